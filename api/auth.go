@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/service"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// Допустимые значения auth.mode
+const (
+	AuthModeNone   = "none"
+	AuthModeAPIKey = "api_key"
+	AuthModeJWT    = "jwt"
+)
+
+// bearerChallenge — значение WWW-Authenticate для ответов 401 в режиме jwt (RFC 6750)
+const bearerChallenge = `Bearer realm="transaction-system"`
+
+// withAPIKey аутентифицирует запрос по заголовку X-API-Key и кладёт клиента в контекст.
+// Запрос без ключа или с неверным ключом получает 401.
+func (h *Handler) withAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := h.clientService.Authenticate(r.Context(), r.Header.Get(apiKeyHeader))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", apiKeyHeader)
+			writeError(w, r, "Authenticate", err)
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("client.id", principal.ClientId))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withBearerToken аутентифицирует запрос по JWT из заголовка Authorization: Bearer.
+// Субъект токена сопоставляется с клиентом, scope токена проверяет requireScope.
+func (h *Handler) withBearerToken(verifier *auth.JWTVerifier) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				w.Header().Set("WWW-Authenticate", bearerChallenge)
+				writeError(w, r, "Authenticate", service.ErrUnauthenticated)
+				return
+			}
+
+			claims, err := verifier.Verify(strings.TrimSpace(token))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", bearerChallenge+`, error="invalid_token"`)
+				writeError(w, r, "Authenticate", fmt.Errorf("%w: %w", service.ErrUnauthenticated, err))
+				return
+			}
+
+			principal, err := h.clientService.AuthenticateToken(r.Context(), claims)
+			if err != nil {
+				writeError(w, r, "Authenticate", err)
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.Int64("client.id", principal.ClientId),
+				attribute.String("client.subject", principal.Subject),
+			)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requireScope пропускает запрос, только если вызывающему выдан scope.
+// Без аутентификации (auth.mode=none) scope не проверяются.
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := auth.FromContext(r.Context()); ok && !p.HasScope(scope) {
+			if p.Subject != "" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`%s, error="insufficient_scope", scope=%q`, bearerChallenge, scope))
+			}
+			writeError(w, r, "RequireScope", &service.ValidationError{Err: service.ErrInsufficientScope, Field: "scope", Reason: scope + " is required"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Admin bool   `json:"admin"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+		slog.WarnContext(r.Context(), "failed to decode request", "op", "CreateClient", "error", err)
+		return
+	}
+
+	client, key, err := h.clientService.CreateClient(r.Context(), req.Name, req.Admin)
+	if err != nil {
+		writeError(w, r, "CreateClient", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"client": client, "api_key": key})
+}
+
+func (h *Handler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid client ID", map[string]string{"id": idStr})
+		return
+	}
+
+	key, err := h.clientService.IssueKey(r.Context(), id)
+	if err != nil {
+		writeError(w, r, "IssueAPIKey", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid key ID", map[string]string{"id": idStr})
+		return
+	}
+
+	if err := h.clientService.RevokeKey(r.Context(), id); err != nil {
+		writeError(w, r, "RevokeAPIKey", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
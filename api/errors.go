@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"TransactionSystem/internal/logging"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/service"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// errorResponse — единый формат тела ответа с ошибкой
+type errorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details"`
+	RequestID string            `json:"request_id"`
+}
+
+// errorMapping сопоставляет доменные ошибки сервисов с HTTP-статусами и кодами
+var errorMapping = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{service.ErrWalletNotFound, http.StatusNotFound, "wallet_not_found"},
+	{service.ErrTransactionNotFound, http.StatusNotFound, "transaction_not_found"},
+	{service.ErrInsufficientFunds, http.StatusConflict, "insufficient_funds"},
+	{service.ErrSameWallet, http.StatusUnprocessableEntity, "same_wallet"},
+	{service.ErrInvalidAmount, http.StatusUnprocessableEntity, "invalid_amount"},
+	{service.ErrNegativeBalance, http.StatusUnprocessableEntity, "negative_balance"},
+	{service.ErrUnknownCurrency, http.StatusUnprocessableEntity, "unknown_currency"},
+	{service.ErrUnsupportedCurrency, http.StatusUnprocessableEntity, "unsupported_currency"},
+	{service.ErrAmountScale, http.StatusUnprocessableEntity, "invalid_amount_scale"},
+	{service.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "currency_mismatch"},
+	{service.ErrSameCurrency, http.StatusUnprocessableEntity, "same_currency"},
+	{service.ErrInvalidRate, http.StatusUnprocessableEntity, "invalid_rate"},
+	{service.ErrFXRateNotFound, http.StatusUnprocessableEntity, "fx_rate_not_found"},
+	{service.ErrFXQuoteNotFound, http.StatusNotFound, "fx_quote_not_found"},
+	{service.ErrFXQuoteExpired, http.StatusConflict, "fx_quote_expired"},
+	{service.ErrFXQuoteMismatch, http.StatusUnprocessableEntity, "fx_quote_mismatch"},
+	{service.ErrFXRatesReadOnly, http.StatusConflict, "fx_rates_read_only"},
+	{service.ErrConversionNotReversible, http.StatusUnprocessableEntity, "conversion_not_reversible"},
+	{service.ErrHoldNotFound, http.StatusNotFound, "hold_not_found"},
+	{service.ErrHoldNotActive, http.StatusConflict, "hold_not_active"},
+	{service.ErrHoldExpired, http.StatusConflict, "hold_expired"},
+	{service.ErrCaptureExceedsHold, http.StatusUnprocessableEntity, "capture_exceeds_hold"},
+	{service.ErrInvalidHoldTTL, http.StatusUnprocessableEntity, "invalid_hold_ttl"},
+	{service.ErrInvalidLimit, http.StatusUnprocessableEntity, "invalid_limit"},
+	{service.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+	{service.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{service.ErrWalletFrozen, http.StatusConflict, "wallet_frozen"},
+	{service.ErrWalletClosed, http.StatusConflict, "wallet_closed"},
+	{service.ErrWalletStatusTransition, http.StatusConflict, "wallet_status_transition"},
+	{service.ErrWalletBalanceNotZero, http.StatusConflict, "wallet_balance_not_zero"},
+	{service.ErrTransactionAlreadyReversed, http.StatusConflict, "transaction_already_reversed"},
+	{service.ErrTransactionNotReversible, http.StatusUnprocessableEntity, "transaction_not_reversible"},
+	{service.ErrReversalReasonRequired, http.StatusUnprocessableEntity, "reversal_reason_required"},
+	{service.ErrIdempotencyKeyInvalid, http.StatusBadRequest, "invalid_idempotency_key"},
+	{service.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{service.ErrIdempotencyKeyInProgress, http.StatusConflict, "idempotency_key_in_progress"},
+	{service.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+	{service.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{service.ErrInsufficientScope, http.StatusForbidden, "insufficient_scope"},
+	{service.ErrClientNotFound, http.StatusNotFound, "client_not_found"},
+	{service.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{service.ErrInvalidClient, http.StatusUnprocessableEntity, "invalid_client"},
+}
+
+// writeError отвечает клиенту ошибкой сервиса. Неизвестные ошибки считаются
+// внутренними: клиент получает 500 без подробностей, а сама ошибка пишется в лог.
+func writeError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	reqID := requestID(w, r)
+
+	for _, m := range errorMapping {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+
+		var details map[string]string
+		var nf *repository.NotFoundError
+		var ve *service.ValidationError
+		var se *service.WalletStatusError
+		var ce *service.CurrencyMismatchError
+		var he *service.HoldStatusError
+		switch {
+		case errors.As(err, &nf):
+			details = map[string]string{nf.Field: fmt.Sprint(nf.Key)}
+		case errors.As(err, &ve):
+			details = map[string]string{"field": ve.Field, "reason": ve.Reason}
+		case errors.As(err, &se):
+			details = map[string]string{"address": se.Address, "status": string(se.Status)}
+		case errors.As(err, &ce):
+			details = map[string]string{"from_currency": string(ce.From), "to_currency": string(ce.To)}
+		case errors.As(err, &he):
+			details = map[string]string{"hold_id": strconv.FormatInt(he.Id, 10), "status": string(he.Status)}
+		}
+
+		slog.WarnContext(r.Context(), "request failed", "op", op, "code", m.code, "error", err)
+		writeJSONError(w, m.status, errorResponse{
+			Code:      m.code,
+			Message:   m.err.Error(),
+			Details:   details,
+			RequestID: reqID,
+		})
+		return
+	}
+
+	slog.ErrorContext(r.Context(), "internal error", "op", op, "error", err)
+	writeJSONError(w, http.StatusInternalServerError, errorResponse{
+		Code:      "internal_error",
+		Message:   "internal server error",
+		RequestID: reqID,
+	})
+}
+
+// writeBadRequest отвечает 400 на некорректный запрос клиента
+func writeBadRequest(w http.ResponseWriter, r *http.Request, message string, details map[string]string) {
+	writeJSONError(w, http.StatusBadRequest, errorResponse{
+		Code:      "invalid_request",
+		Message:   message,
+		Details:   details,
+		RequestID: requestID(w, r),
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, status int, body errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// requestID возвращает идентификатор запроса, назначенный withRequestID.
+// Если обработчик вызван в обход middleware, генерирует новый и добавляет его в ответ.
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := logging.RequestID(r.Context()); id != "" {
+		return id
+	}
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		return id
+	}
+
+	id := uuid.New().String()
+	w.Header().Set(requestIDHeader, id)
+	return id
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateFXQuote фиксирует курс пары валют на время fx.quote_ttl.
+// Ожидает на вход - { "from_currency": "USD", "to_currency": "EUR" }
+func (h *Handler) CreateFXQuote(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		From models.Currency `json:"from_currency"`
+		To   models.Currency `json:"to_currency"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+		slog.WarnContext(r.Context(), "failed to decode request", "op", "CreateFXQuote", "error", err)
+		return
+	}
+
+	quote, err := h.fxService.CreateQuote(r.Context(), req.From, req.To)
+	if err != nil {
+		writeError(w, r, "CreateFXQuote", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
+
+// SetFXRate устанавливает курс пары {from}/{to}.
+// Ожидает на вход - { "rate": 0.92 }
+func (h *Handler) SetFXRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	from, to := models.Currency(vars["from"]), models.Currency(vars["to"])
+
+	var req struct {
+		Rate models.Rate `json:"rate"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+		slog.WarnContext(r.Context(), "failed to decode request", "op", "SetFXRate", "error", err)
+		return
+	}
+
+	if err := h.fxService.SetRate(r.Context(), from, to, req.Rate); err != nil {
+		writeError(w, r, "SetFXRate", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"from_currency": from, "to_currency": to, "rate": req.Rate})
+}
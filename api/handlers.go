@@ -1,8 +1,10 @@
 package api
 
 import (
+    "context"
     "encoding/json"
-    "log"
+    "fmt"
+    "log/slog"
     "net/http"
     "strconv"
     "time"
@@ -14,19 +16,20 @@ import (
 
 func (h *Handler) CreateWallet(w http.ResponseWriter, r *http.Request) {
     var req struct {
-        Balance float64 `json:"balance"`
+        Balance  models.Amount   `json:"balance"`
+        // Currency — код валюты; если не указан, кошелёк создаётся в models.DefaultCurrency
+        Currency models.Currency `json:"currency"`
     }
 
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        log.Printf("CreateWallet: failed to parse request body: %v", err)
+        writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+        slog.WarnContext(r.Context(), "failed to parse request body", "op", "CreateWallet", "error", err)
         return
     }
 
-    address, err := h.walletService.CreateWallet(r.Context(), req.Balance)
+    address, err := h.walletService.CreateWallet(r.Context(), req.Currency, req.Balance)
     if err != nil {
-        http.Error(w, "Failed to create wallet", http.StatusInternalServerError)
-        log.Printf("CreateWallet: failed to create wallet: %v", err)
+        writeError(w, r, "CreateWallet", err)
         return
     }
 
@@ -39,25 +42,55 @@ func (h *Handler) RemoveWallet(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     address, ok := vars["address"]
     if !ok {
-        http.Error(w, "Address is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Address is required", nil)
         return
     }
 
     err := h.walletService.RemoveWallet(r.Context(), address)
     if err != nil {
-        http.Error(w, "Failed to remove wallet", http.StatusInternalServerError)
-        log.Printf("RemoveWallet: failed to remove wallet: %v", err)
+        writeError(w, r, "RemoveWallet", err)
         return
     }
 
     w.WriteHeader(http.StatusOK)
 }
 
+func (h *Handler) FreezeWallet(w http.ResponseWriter, r *http.Request) {
+    h.changeWalletStatus(w, r, "FreezeWallet", h.walletService.FreezeWallet)
+}
+
+func (h *Handler) UnfreezeWallet(w http.ResponseWriter, r *http.Request) {
+    h.changeWalletStatus(w, r, "UnfreezeWallet", h.walletService.UnfreezeWallet)
+}
+
+func (h *Handler) CloseWallet(w http.ResponseWriter, r *http.Request) {
+    h.changeWalletStatus(w, r, "CloseWallet", h.walletService.CloseWallet)
+}
+
+// changeWalletStatus выполняет смену состояния кошелька и возвращает кошелёк в новом состоянии
+func (h *Handler) changeWalletStatus(w http.ResponseWriter, r *http.Request, op string, change func(ctx context.Context, address string) (*models.Wallet, error)) {
+    vars := mux.Vars(r)
+    address, ok := vars["address"]
+    if !ok {
+        writeBadRequest(w, r, "Address is required", nil)
+        return
+    }
+
+    wallet, err := change(r.Context(), address)
+    if err != nil {
+        writeError(w, r, op, err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(wallet)
+}
+
 func (h *Handler) GetWallet(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     address, ok := vars["address"]
     if !ok {
-        http.Error(w, "Address is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Address is required", nil)
         return
     }
 
@@ -65,8 +98,7 @@ func (h *Handler) GetWallet(w http.ResponseWriter, r *http.Request) {
 
     wallet, err := h.walletService.GetWallet(r.Context(), address)
     if err != nil {
-        http.Error(w, "Failed to get wallet", http.StatusInternalServerError)
-        log.Printf("GetWalletInfo: failed to get wallet: %v", err)
+        writeError(w, r, "GetWallet", err)
         return
     }
 
@@ -78,13 +110,13 @@ func (h *Handler) GetTransactionById(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     idStr, ok := vars["id"]
     if !ok {
-        http.Error(w, "ID is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "ID is required", nil)
         return
     }
 
     id, err := strconv.ParseInt(idStr, 10, 64)
     if err != nil {
-        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        writeBadRequest(w, r, "Invalid ID", map[string]string{"id": idStr})
         return
     }
 
@@ -92,8 +124,7 @@ func (h *Handler) GetTransactionById(w http.ResponseWriter, r *http.Request) {
 
     transaction, err = h.transactionService.GetTransactionById(r.Context(), id)
     if err != nil {
-        http.Error(w, "Transaction not found", http.StatusNotFound)
-        log.Printf("GetTransactionById: transaction not found: %v", err)
+        writeError(w, r, "GetTransactionById", err)
         return
     }
 
@@ -101,24 +132,58 @@ func (h *Handler) GetTransactionById(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(transaction)
 }
 
+func (h *Handler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    idStr, ok := vars["id"]
+    if !ok {
+        writeBadRequest(w, r, "ID is required", nil)
+        return
+    }
+
+    id, err := strconv.ParseInt(idStr, 10, 64)
+    if err != nil {
+        writeBadRequest(w, r, "Invalid ID", map[string]string{"id": idStr})
+        return
+    }
+
+    var req struct {
+        Reason string `json:"reason"`
+    }
+
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+        return
+    }
+
+    reversal, err := h.transactionService.ReverseTransaction(r.Context(), id, req.Reason)
+    if err != nil {
+        writeError(w, r, "ReverseTransaction", err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Location", fmt.Sprintf("/api/transaction/%d", reversal.Id))
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(reversal)
+}
+
 func (h *Handler) RemoveTransaction(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     idStr, ok := vars["id"]
     if !ok {
-        http.Error(w, "ID is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "ID is required", nil)
         return
     }
 
     id, err := strconv.ParseInt(idStr, 10, 64)
     if err != nil {
-        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        writeBadRequest(w, r, "Invalid ID", map[string]string{"id": idStr})
         return
     }
 
     err = h.transactionService.RemoveTransaction(r.Context(), id)
     if err != nil {
-        http.Error(w, "Failed to remove transaction", http.StatusInternalServerError)
-        log.Printf("RemoveTransaction: failed to remove transaction: %v", err)
+        writeError(w, r, "RemoveTransaction", err)
         return
     }
 
@@ -129,35 +194,69 @@ func (h *Handler) GetTransactionByInfo(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     from, ok := vars["from"]
     if !ok {
-        http.Error(w, "Sender address (from) is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Sender address (from) is required", nil)
         return
     }
 
     to, ok := vars["to"]
     if !ok {
-        http.Error(w, "Receiver address (to) is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Receiver address (to) is required", nil)
         return
     }
 
     createdAtStr, ok := vars["createdAt"]
     if !ok {
-        http.Error(w, "Transaction timestamp (createdAt) is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Transaction timestamp (createdAt) is required", nil)
         return
     }
 
     createdAt, err := time.Parse(time.RFC3339, createdAtStr)
     if err != nil {
-        http.Error(w, "Invalid timestamp format. Use RFC3339 format (e.g., 2024-02-10T15:04:05Z)", http.StatusBadRequest)
+        writeBadRequest(w, r, "Invalid timestamp format. Use RFC3339 format (e.g., 2024-02-10T15:04:05Z)", map[string]string{"createdAt": createdAtStr})
         return
     }
 
     transaction, err := h.transactionService.GetTransactionByInfo(r.Context(), from, to, createdAt)
     if err != nil {
-        http.Error(w, "Transaction not found", http.StatusNotFound)
-        log.Printf("GetTransactionByInfo: transaction not found: %v", err)
+        writeError(w, r, "GetTransactionByInfo", err)
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(transaction)
+}
+
+func (h *Handler) GetWalletHistory(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    address, ok := vars["address"]
+    if !ok {
+        writeBadRequest(w, r, "Address is required", nil)
+        return
+    }
+
+    query := r.URL.Query()
+    var filter models.WalletHistoryFilter
+
+    var err error
+    if filter.Limit, err = parseLimitParam(query, "limit"); err != nil {
+        writeBadRequest(w, r, err.Error(), nil)
+        return
+    }
+    if filter.Since, err = parseTimeParam(query, "since"); err != nil {
+        writeBadRequest(w, r, err.Error(), nil)
+        return
+    }
+    if filter.Until, err = parseTimeParam(query, "until"); err != nil {
+        writeBadRequest(w, r, err.Error(), nil)
+        return
+    }
+
+    page, err := h.transactionService.GetWalletHistory(r.Context(), address, filter, query.Get("cursor"))
+    if err != nil {
+        writeError(w, r, "GetWalletHistory", err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(page)
 }
\ No newline at end of file
@@ -2,10 +2,14 @@ package api
 
 import (
     "encoding/json"
-    "log"
+    "fmt"
+    "log/slog"
     "net/http"
+    "net/url"
     "strconv"
+    "time"
 
+    "TransactionSystem/internal/models"
     "TransactionSystem/internal/service"
 
     "github.com/gorilla/mux"
@@ -14,73 +18,186 @@ import (
 type Handler struct {
     transactionService *service.TransactionService
     walletService      *service.WalletService
+    idempotencyService *service.IdempotencyService
+    healthService      *service.HealthService
+    clientService      *service.ClientService
+    // fxService задаётся через Options.FX; без него пути /fx не регистрируются
+    fxService          *service.FXService
+    // holdService задаётся через Options.Holds; без него пути /holds не регистрируются
+    holdService        *service.HoldService
+    // maxIdempotentBody задаётся через Options.MaxIdempotentBodyBytes
+    maxIdempotentBody  int64
 }
 
-func NewHandler(ts *service.TransactionService, ws *service.WalletService) *Handler {
+func NewHandler(ts *service.TransactionService, ws *service.WalletService, is *service.IdempotencyService, hs *service.HealthService, cs *service.ClientService) *Handler {
     return &Handler{
         transactionService: ts,
         walletService:      ws,
+        idempotencyService: is,
+        healthService:      hs,
+        clientService:      cs,
     }
 }
 
 func (h *Handler) SendMoney(w http.ResponseWriter, r *http.Request) {
     var req struct {
-        From   string  `json:"from"`
-        To     string  `json:"to"`
-        Amount float64 `json:"amount"`
+        From   string        `json:"from"`
+        To     string        `json:"to"`
+        Amount models.Amount `json:"amount"`
+        // Convert разрешает перевод между кошельками в разных валютах; quote_id подразумевает его
+        Convert bool         `json:"convert"`
+        QuoteId string       `json:"quote_id"`
     }
 
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        log.Printf("SendMoney: failed to decode request: %v", err)
+        writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+        slog.WarnContext(r.Context(), "failed to decode request", "op", "SendMoney", "error", err)
         return
     }
 
-    err := h.transactionService.SendMoney(r.Context(), req.From, req.To, req.Amount)
+    var transaction *models.Transaction
+    var err error
+    if req.Convert || req.QuoteId != "" {
+        transaction, err = h.transactionService.ConvertMoney(r.Context(), req.From, req.To, req.Amount, req.QuoteId)
+    } else {
+        transaction, err = h.transactionService.SendMoney(r.Context(), req.From, req.To, req.Amount)
+    }
     if err != nil {
-        http.Error(w, "Transaction failed", http.StatusBadRequest)
-        log.Printf("SendMoney: transaction failed: %v", err)
+        writeError(w, r, "SendMoney", err)
         return
     }
 
-    w.WriteHeader(http.StatusOK)
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Location", fmt.Sprintf("/api/transaction/%d", transaction.Id))
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(transaction)
 }
 
+// GetLastTransactions обслуживает GET /api/transactions.
+// Запрос только с параметром count возвращает массив последних транзакций, как раньше.
+// Любой другой параметр (limit, cursor, фильтры) переключает ответ на постраничный формат.
 func (h *Handler) GetLastTransactions(w http.ResponseWriter, r *http.Request) {
-    countStr := r.URL.Query().Get("count")
-    count, err := strconv.Atoi(countStr)
-    if err != nil || count <= 0 {
-        http.Error(w, "Invalid count parameter", http.StatusBadRequest)
-        log.Printf("GetLastTransactions: invalid count parameter: %v", err)
+    query := r.URL.Query()
+
+    if len(query) == 1 && query.Has("count") {
+        countStr := query.Get("count")
+        count, err := strconv.Atoi(countStr)
+        if err != nil || count <= 0 {
+            writeBadRequest(w, r, "Invalid count parameter", map[string]string{"count": countStr})
+            return
+        }
+
+        transactions, err := h.transactionService.GetLastTransactions(r.Context(), count)
+        if err != nil {
+            writeError(w, r, "GetLastTransactions", err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(transactions)
+        return
+    }
+
+    filter, err := parseTransactionFilter(query)
+    if err != nil {
+        writeBadRequest(w, r, err.Error(), nil)
         return
     }
 
-    transactions, err := h.transactionService.GetLastTransactions(r.Context(), count)
+    page, err := h.transactionService.ListTransactions(r.Context(), *filter, query.Get("cursor"))
     if err != nil {
-        http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
-        log.Printf("GetLastTransactions: failed to fetch transactions: %v", err)
+        writeError(w, r, "ListTransactions", err)
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(transactions)
+    json.NewEncoder(w).Encode(page)
+}
+
+// parseTransactionFilter разбирает параметры фильтрации списка транзакций.
+// count принимается как синоним limit для обратной совместимости.
+func parseTransactionFilter(query url.Values) (*models.TransactionFilter, error) {
+    filter := models.TransactionFilter{
+        Wallet:     query.Get("wallet"),
+        WalletRole: models.WalletRole(query.Get("role")),
+    }
+
+    var err error
+    if filter.Limit, err = parseLimitParam(query, "count", "limit"); err != nil {
+        return nil, err
+    }
+    if filter.MinAmount, err = parseAmountParam(query, "min_amount"); err != nil {
+        return nil, err
+    }
+    if filter.MaxAmount, err = parseAmountParam(query, "max_amount"); err != nil {
+        return nil, err
+    }
+    if filter.Since, err = parseTimeParam(query, "since"); err != nil {
+        return nil, err
+    }
+    if filter.Until, err = parseTimeParam(query, "until"); err != nil {
+        return nil, err
+    }
+
+    return &filter, nil
+}
+
+// parseLimitParam возвращает размер страницы из первого заданного параметра names,
+// 0 означает значение по умолчанию
+func parseLimitParam(query url.Values, names ...string) (int, error) {
+    limit := 0
+    for _, name := range names {
+        if value := query.Get(name); value != "" {
+            parsed, err := strconv.Atoi(value)
+            if err != nil || parsed <= 0 {
+                return 0, fmt.Errorf("Invalid %s parameter", name)
+            }
+            limit = parsed
+        }
+    }
+    return limit, nil
+}
+
+func parseAmountParam(query url.Values, name string) (*models.Amount, error) {
+    value := query.Get(name)
+    if value == "" {
+        return nil, nil
+    }
+
+    amount, err := models.ParseAmount(value)
+    if err != nil {
+        return nil, fmt.Errorf("Invalid %s parameter", name)
+    }
+    return &amount, nil
+}
+
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+    value := query.Get(name)
+    if value == "" {
+        return nil, nil
+    }
+
+    parsed, err := time.Parse(time.RFC3339Nano, value)
+    if err != nil {
+        return nil, fmt.Errorf("Invalid %s parameter. Use RFC3339 format (e.g., 2024-02-10T15:04:05Z)", name)
+    }
+    return &parsed, nil
 }
 
 func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     address, ok := vars["address"]
     if !ok {
-        http.Error(w, "Wallet address is required", http.StatusBadRequest)
+        writeBadRequest(w, r, "Wallet address is required", nil)
         return
     }
 
     balance, err := h.walletService.GetBalance(r.Context(), address)
     if err != nil {
-        http.Error(w, "Failed to fetch balance", http.StatusInternalServerError)
-        log.Printf("GetBalance: failed to fetch balance: %v", err)
+        writeError(w, r, "GetBalance", err)
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]float64{"balance": balance})
+    json.NewEncoder(w).Encode(map[string]models.Amount{"balance": balance})
 }
\ No newline at end of file
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateHold резервирует средства кошелька-отправителя до списания или снятия холда.
+// Ожидает на вход - { "from": "...", "to": "...", "amount": x.x, "ttl_seconds": N }; без ttl_seconds действует holds.default_ttl
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		From       string        `json:"from"`
+		To         string        `json:"to"`
+		Amount     models.Amount `json:"amount"`
+		TTLSeconds int64         `json:"ttl_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+		slog.WarnContext(r.Context(), "failed to decode request", "op", "CreateHold", "error", err)
+		return
+	}
+
+	ttl, err := h.holdService.TTLFromSeconds(req.TTLSeconds)
+	if err != nil {
+		writeError(w, r, "CreateHold", err)
+		return
+	}
+
+	hold, err := h.holdService.CreateHold(r.Context(), req.From, req.To, req.Amount, ttl)
+	if err != nil {
+		writeError(w, r, "CreateHold", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/holds/%d", hold.Id))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdId(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.holdService.GetHold(r.Context(), id)
+	if err != nil {
+		writeError(w, r, "GetHold", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// CaptureHold списывает холд переводом на кошелёк получателя.
+// Ожидает на вход - { "amount": x.x } или пустое тело; без amount списывается вся сумма холда
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdId(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount models.Amount `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeBadRequest(w, r, "Invalid request body", map[string]string{"reason": err.Error()})
+		slog.WarnContext(r.Context(), "failed to decode request", "op", "CaptureHold", "error", err)
+		return
+	}
+
+	hold, transaction, err := h.holdService.CaptureHold(r.Context(), id, req.Amount)
+	if err != nil {
+		writeError(w, r, "CaptureHold", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/transaction/%d", transaction.Id))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"hold": hold, "transaction": transaction})
+}
+
+func (h *Handler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdId(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.holdService.VoidHold(r.Context(), id)
+	if err != nil {
+		writeError(w, r, "VoidHold", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// holdId разбирает {id} пути; при ошибке уже ответил 400
+func holdId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid ID", map[string]string{"id": idStr})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"TransactionSystem/internal/auth"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultMaxIdempotentBodyBytes — предел тела запроса с Idempotency-Key по умолчанию.
+// Тело читается в память целиком, чтобы вычислить отпечаток запроса.
+const DefaultMaxIdempotentBodyBytes = 1 << 20
+
+// Заголовки ответа, которые сохраняются вместе с телом и отдаются при повторе
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// responseRecorder пропускает ответ клиенту и одновременно запоминает его
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+// withIdempotency гарантирует, что запрос с одним и тем же Idempotency-Key
+// выполняется не более одного раза, а повторы получают исходный ответ.
+// Запросы без заголовка выполняются как обычно.
+func (h *Handler) withIdempotency(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		// Ключи разных клиентов не должны пересекаться, иначе один клиент
+		// получил бы сохранённый ответ другого
+		scope := scope
+		if p, ok := auth.FromContext(r.Context()); ok {
+			scope = fmt.Sprintf("%s:client:%d", scope, p.ClientId)
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxIdempotentBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			slog.WarnContext(r.Context(), "request body too large", "scope", scope, "limit", tooLarge.Limit)
+			writeJSONError(w, http.StatusRequestEntityTooLarge, errorResponse{
+				Code:      "request_too_large",
+				Message:   fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+				RequestID: requestID(w, r),
+			})
+			return
+		}
+		if err != nil {
+			writeBadRequest(w, r, "Invalid request body", nil)
+			slog.WarnContext(r.Context(), "failed to read request body", "scope", scope, "error", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := h.idempotencyService.Begin(r.Context(), scope, key, fingerprint(r, body))
+		if err != nil {
+			writeError(w, r, scope, err)
+			return
+		}
+
+		if record != nil {
+			for name, value := range record.Headers {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Body)
+			return
+		}
+
+		// Клиент мог уже отключиться, но результат всё равно нужно сохранить
+		ctx := context.WithoutCancel(r.Context())
+
+		// Ключ освобождается, если ответ не сохранён: запрос завершился ошибкой 5xx,
+		// сохранить ответ не удалось или обработчик запаниковал (паника не перехватывается
+		// и доходит до net/http). Иначе ключ остался бы «в работе» до истечения срока.
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.idempotencyService.Release(ctx, scope, key); err != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", "scope", scope, "error", err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+
+		err = h.idempotencyService.Complete(ctx, scope, key, recorder.status, headers, recorder.body.Bytes())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", "scope", scope, "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// fingerprint однозначно описывает запрос, чтобы отличить повтор от повторного использования ключа
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(canonicalJSON(body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// canonicalJSON приводит JSON-тело к виду, не зависящему от порядка полей и пробелов:
+// повтор того же запроса от другого клиента (или после пересериализации) получает тот же отпечаток.
+// Числа сохраняют исходную запись, чтобы разные суммы не совпали после округления float64.
+// Тело, которое не является одним JSON-значением, используется как есть.
+func canonicalJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return body
+	}
+
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
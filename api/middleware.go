@@ -0,0 +1,67 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"TransactionSystem/internal/logging"
+	"TransactionSystem/internal/metrics"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength ограничивает идентификатор, пришедший от клиента
+const maxRequestIDLength = 128
+
+// withRequestID берёт идентификатор запроса из X-Request-ID или генерирует новый,
+// кладёт его в контекст для логов нижележащих слоёв и возвращает клиенту в ответе.
+// По завершении запроса пишет запись с маршрутом, статусом и длительностью.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := logging.WithRequestID(r.Context(), id)
+		r = r.WithContext(ctx)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+
+		start := time.Now()
+		sw := metrics.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		slog.InfoContext(ctx, "request completed",
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", sw.Status(),
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// validRequestID допускает только печатные ASCII-символы, чтобы идентификатор
+// клиента нельзя было использовать для подделки строк лога
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
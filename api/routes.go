@@ -4,30 +4,136 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/metrics"
 	"TransactionSystem/internal/service"
 )
 
-func NewRouter(transactionService *service.TransactionService, walletService *service.WalletService) *mux.Router {
+// tracingServerName — имя сервера в атрибутах HTTP-спанов
+const tracingServerName = "transaction-system"
+
+var untracedPaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// Options — настройки роутера, не связанные с сервисами
+type Options struct {
+	// AdminMode открывает административные пути, например физическое удаление транзакций
+	AdminMode bool
+	// Metrics, если задан, учитывает запросы и отдаёт метрики на /metrics
+	Metrics *metrics.Metrics
+	// AuthMode — значение auth.mode; пустое значение равносильно AuthModeNone
+	AuthMode string
+	// JWTVerifier проверяет bearer-токены; обязателен при AuthModeJWT
+	JWTVerifier *auth.JWTVerifier
+	// FX, если задан, открывает котировки и установку курсов для переводов с конвертацией
+	FX *service.FXService
+	// Holds, если задан, открывает холды: резервирование средств с последующим списанием или снятием
+	Holds *service.HoldService
+	// MaxIdempotentBodyBytes — наибольший размер тела запроса с Idempotency-Key, больше отвечается 413;
+	// ноль означает DefaultMaxIdempotentBodyBytes
+	MaxIdempotentBodyBytes int64
+}
+
+func NewRouter(
+	transactionService *service.TransactionService,
+	walletService *service.WalletService,
+	idempotencyService *service.IdempotencyService,
+	healthService *service.HealthService,
+	clientService *service.ClientService,
+	opts Options,
+) *mux.Router {
 	r := mux.NewRouter()
-	h := NewHandler(transactionService, walletService)
+	h := NewHandler(transactionService, walletService, idempotencyService, healthService, clientService)
+	h.fxService = opts.FX
+	h.holdService = opts.Holds
+	h.maxIdempotentBody = opts.MaxIdempotentBodyBytes
+	if h.maxIdempotentBody <= 0 {
+		h.maxIdempotentBody = DefaultMaxIdempotentBodyBytes
+	}
+
+	// Спан на каждый запрос с именем по шаблону маршрута; контекст трассы берётся из
+	// заголовков traceparent/tracestate. Пробы и /metrics не трассируются.
+	r.Use(otelmux.Middleware(tracingServerName, otelmux.WithFilter(func(r *http.Request) bool {
+		return !untracedPaths[r.URL.Path]
+	})))
+	// X-Request-ID и журнал запросов; идентификатор нужен всем остальным обработчикам
+	r.Use(withRequestID)
+
+	// Пробы оркестратора: процесс жив и экземпляр готов принимать запросы
+	r.HandleFunc("/healthz", h.Healthz).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", h.Readyz).Methods(http.MethodGet)
+
+	if opts.Metrics != nil {
+		r.Handle("/metrics", opts.Metrics.Handler()).Methods(http.MethodGet)
+		r.Use(opts.Metrics.Middleware)
+	}
 
 	api := r.PathPrefix("/api").Subrouter()
 
+	// Пробы и /metrics остаются открытыми, всё под /api требует ключ клиента или токен.
+	// Каждый путь требует свой scope, без аутентификации scope не проверяются.
+	switch opts.AuthMode {
+	case AuthModeAPIKey:
+		api.Use(h.withAPIKey)
+	case AuthModeJWT:
+		api.Use(h.withBearerToken(opts.JWTVerifier))
+	}
+
 	// Пути указанные в ТЗ
-	api.HandleFunc("/send", h.SendMoney).Methods(http.MethodPost)
-	api.HandleFunc("/transactions", h.GetLastTransactions).Methods(http.MethodGet).Queries("count", "{count}")
-	api.HandleFunc("/wallet/{address}/balance", h.GetBalance).Methods(http.MethodGet)
+	// Поддерживает заголовок Idempotency-Key
+	api.HandleFunc("/send", requireScope(auth.ScopeTransferCreate, h.withIdempotency("send", h.SendMoney))).Methods(http.MethodPost)
+	// ?count=N — последние N транзакций; limit, cursor и фильтры — постраничный вывод
+	api.HandleFunc("/transactions", requireScope(auth.ScopeWalletRead, h.GetLastTransactions)).Methods(http.MethodGet)
+	api.HandleFunc("/wallet/{address}/balance", requireScope(auth.ScopeWalletRead, h.GetBalance)).Methods(http.MethodGet)
 
 	// Дополнительные пути, необходимые 
-	api.HandleFunc("/transaction/{id}", h.GetTransactionById).Methods(http.MethodGet)
-	api.HandleFunc("/transaction/{id}", h.RemoveTransaction).Methods(http.MethodDelete)
-	api.HandleFunc("/transaction/{from}/{to}/{createdAt}", h.GetTransactionByInfo).Methods(http.MethodGet)
+	api.HandleFunc("/transaction/{id}", requireScope(auth.ScopeWalletRead, h.GetTransactionById)).Methods(http.MethodGet)
+	// Ожидает на вход - { "reason": "..." }
+	api.HandleFunc("/transaction/{id}/reverse", requireScope(auth.ScopeTransferCreate, h.withIdempotency("transaction_reverse", h.ReverseTransaction))).Methods(http.MethodPost)
+	api.HandleFunc("/transaction/{from}/{to}/{createdAt}", requireScope(auth.ScopeWalletRead, h.GetTransactionByInfo)).Methods(http.MethodGet)
 	
 	// Ожидает на вход - { "balance": x.x }
-	api.HandleFunc("/wallet/create", h.CreateWallet).Methods(http.MethodPost)
+	api.HandleFunc("/wallet/create", requireScope(auth.ScopeWalletWrite, h.withIdempotency("wallet_create", h.CreateWallet))).Methods(http.MethodPost)
+
+	api.HandleFunc("/wallet/{address}", requireScope(auth.ScopeWalletRead, h.GetWallet)).Methods(http.MethodGet)
+	api.HandleFunc("/wallet/{address}/transactions", requireScope(auth.ScopeWalletRead, h.GetWalletHistory)).Methods(http.MethodGet)
+	api.HandleFunc("/wallet/{address}/freeze", requireScope(auth.ScopeWalletWrite, h.FreezeWallet)).Methods(http.MethodPost)
+	api.HandleFunc("/wallet/{address}/unfreeze", requireScope(auth.ScopeWalletWrite, h.UnfreezeWallet)).Methods(http.MethodPost)
+	api.HandleFunc("/wallet/{address}/close", requireScope(auth.ScopeWalletWrite, h.CloseWallet)).Methods(http.MethodPost)
+
+	if opts.FX != nil {
+		// Ожидает на вход - { "from_currency": "USD", "to_currency": "EUR" }
+		api.HandleFunc("/fx/quotes", requireScope(auth.ScopeTransferCreate, h.CreateFXQuote)).Methods(http.MethodPost)
+		// Ожидает на вход - { "rate": x.x }; работает только с fx.provider: manual
+		api.HandleFunc("/admin/fx/rates/{from}/{to}", requireScope(auth.ScopeAdmin, h.SetFXRate)).Methods(http.MethodPut)
+	}
+
+	if opts.Holds != nil {
+		// Ожидает на вход - { "from": "...", "to": "...", "amount": x.x, "ttl_seconds": N }
+		api.HandleFunc("/holds", requireScope(auth.ScopeTransferCreate, h.withIdempotency("hold_create", h.CreateHold))).Methods(http.MethodPost)
+		api.HandleFunc("/holds/{id}", requireScope(auth.ScopeWalletRead, h.GetHold)).Methods(http.MethodGet)
+		// Ожидает на вход - { "amount": x.x } или пустое тело для списания всей суммы
+		api.HandleFunc("/holds/{id}/capture", requireScope(auth.ScopeTransferCreate, h.withIdempotency("hold_capture", h.CaptureHold))).Methods(http.MethodPost)
+		api.HandleFunc("/holds/{id}/void", requireScope(auth.ScopeTransferCreate, h.VoidHold)).Methods(http.MethodPost)
+	}
+
+	// Пул соединений, версии сборки и схемы, время работы
+	api.HandleFunc("/admin/status", requireScope(auth.ScopeAdmin, h.AdminStatus)).Methods(http.MethodGet)
+
+	// Физическое удаление транзакций и кошельков ломает историю,
+	// поэтому доступно только в административном режиме
+	if opts.AdminMode {
+		api.HandleFunc("/transaction/{id}", requireScope(auth.ScopeAdmin, h.RemoveTransaction)).Methods(http.MethodDelete)
+		api.HandleFunc("/wallet/{address}", requireScope(auth.ScopeAdmin, h.RemoveWallet)).Methods(http.MethodDelete)
+	}
 
-	api.HandleFunc("/wallet/{address}", h.GetWallet).Methods(http.MethodGet)
-	api.HandleFunc("/wallet/{address}", h.RemoveWallet).Methods(http.MethodDelete)
+	// Управление клиентами и ключами имеет смысл только при аутентификации ключами;
+	// первый администратор создаётся командой "client create --admin"
+	if opts.AuthMode == AuthModeAPIKey {
+		api.HandleFunc("/admin/clients", requireScope(auth.ScopeAdmin, h.CreateClient)).Methods(http.MethodPost)
+		api.HandleFunc("/admin/clients/{id}/keys", requireScope(auth.ScopeAdmin, h.IssueAPIKey)).Methods(http.MethodPost)
+		api.HandleFunc("/admin/keys/{id}", requireScope(auth.ScopeAdmin, h.RevokeAPIKey)).Methods(http.MethodDelete)
+	}
 
 	return r
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"TransactionSystem/internal/service"
+)
+
+const clientUsage = "usage: client create <name> [--admin] | issue-key <client-id> | revoke-key <key-id>"
+
+// runClient выполняет подкоманду client: create регистрирует клиента и печатает его первый
+// ключ, issue-key выдаёт клиенту новый ключ, revoke-key отзывает ключ. Команда работает
+// без аутентификации, поэтому через неё создаётся первый администратор.
+func runClient(ctx context.Context, clients *service.ClientService, args []string) error {
+	if len(args) == 0 {
+		return errors.New(clientUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		// --admin допускается как до, так и после имени
+		var name string
+		var admin bool
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--admin" || arg == "-admin":
+				admin = true
+			case name == "" && !strings.HasPrefix(arg, "-"):
+				name = arg
+			default:
+				return errors.New(clientUsage)
+			}
+		}
+		if name == "" {
+			return errors.New(clientUsage)
+		}
+
+		client, key, err := clients.CreateClient(ctx, name, admin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "client %d (%s) created, admin: %t\n", client.Id, client.Name, client.Admin)
+		printKey(key.Id, key.Key)
+		return nil
+	case "issue-key":
+		id, err := parseIDArg(args)
+		if err != nil {
+			return err
+		}
+		key, err := clients.IssueKey(ctx, id)
+		if err != nil {
+			return err
+		}
+		printKey(key.Id, key.Key)
+		return nil
+	case "revoke-key":
+		id, err := parseIDArg(args)
+		if err != nil {
+			return err
+		}
+		if err := clients.RevokeKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "api key %d revoked\n", id)
+		return nil
+	default:
+		return fmt.Errorf("unknown client command %q; %s", args[0], clientUsage)
+	}
+}
+
+func parseIDArg(args []string) (int64, error) {
+	if len(args) != 2 {
+		return 0, errors.New(clientUsage)
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", args[1])
+	}
+	return id, nil
+}
+
+// printKey печатает ключ: он показывается один раз, в базе хранится только хэш
+func printKey(id int64, key string) {
+	fmt.Fprintf(os.Stdout, "api key %d: %s\n", id, key)
+	fmt.Fprintln(os.Stdout, "store it now, it cannot be shown again")
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"TransactionSystem/config"
+	"TransactionSystem/internal/fx"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/service"
+)
+
+// newFXProvider создаёт поставщика курсов по fx.provider
+func newFXProvider(cfg config.FXConfig) (service.FXRateProvider, error) {
+	if cfg.Provider == config.FXProviderStatic {
+		rates, err := fx.LoadStaticRates(cfg.RatesFile)
+		if err != nil {
+			return nil, err
+		}
+		return rates, nil
+	}
+
+	var initial map[fx.Pair]models.Rate
+	if cfg.RatesFile != "" {
+		rates, err := fx.LoadRatesFile(cfg.RatesFile)
+		if err != nil {
+			return nil, err
+		}
+		initial = rates
+	}
+	return fx.NewManualRates(initial), nil
+}
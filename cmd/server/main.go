@@ -2,68 +2,205 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
 
 	"TransactionSystem/api"
 	"TransactionSystem/config"
-	"TransactionSystem/internal/database"
-	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/logging"
+	"TransactionSystem/internal/metrics"
+	"TransactionSystem/internal/models"
 	"TransactionSystem/internal/service"
+	"TransactionSystem/internal/tracing"
 )
 
+// version — версия сборки, задаётся через -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
 	// 1. Загружаем конфиг
-	cfg, err := config.LoadConfig("config/config.yml")
+	// Значения по умолчанию, затем файл (--config или TS_CONFIG), окружение TS_* и флаги
+	cfg, args, err := config.LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
-	// 2. Подключаемся к БД
-	dbPool, err := database.InitDB(cfg)
+	// Структурированный лог; стандартный пакет log после SetDefault тоже пишет через него
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		log.Fatalf("Database connection failed: %v", err)
+		log.Fatalf("Error configuring logger: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// Трассировка; экспортёр stdout печатает спаны рядом с логом
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, version, os.Stdout)
+	if err != nil {
+		fatal("failed to configure tracing", err)
+	}
+
+	// 2. Подключаемся к БД драйвера database.driver. Хранилище закрывается явно:
+	// подкомандами сразу после работы, сервером — после остановки запросов и фоновых задач
+	store, err := openStorage(cfg)
+	if err != nil {
+		fatal("database connection failed", err)
 	}
-	defer dbPool.Close()
 
-	// 3. Запускаем миграции
 	ctx := context.Background()
-	err = database.RunMigrations(ctx, dbPool)
+
+	// Подкоманды migrate и client выполняются и завершают работу, не запуская сервер
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "", "client":
+	case "migrate":
+		if err := runMigrate(ctx, store.migrator, args[1:]); err != nil {
+			fatal("migration failed", err)
+		}
+		store.close()
+		return
+	default:
+		fatal("unknown command, expected migrate or client", fmt.Errorf("command %q", command))
+	}
+
+	// 3. Запускаем миграции
+	if err := store.migrator.Up(ctx); err != nil {
+		fatal("migration failed", err)
+	}
+
+	// 4. Инициализируем сервисы поверх репозиториев выбранной базы
+	transactionService := service.NewTransactionService(store.transactions, store.wallets)
+	walletService := service.NewWalletService(store.wallets)
+	idempotencyService := service.NewIdempotencyService(store.idempotency, cfg.Idempotency.TTL)
+	ledgerService := service.NewLedgerService(store.ledger)
+	healthService := service.NewHealthService(store.db, store.migrator, version)
+	clientService := service.NewClientService(store.clients)
+
+	// Реестр валют проверен при загрузке конфигурации
+	currencies, err := models.NewCurrencies(cfg.Currencies.Custom)
 	if err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		fatal("invalid currencies", err)
 	}
+	walletService.SetCurrencies(currencies)
+	transactionService.SetCurrencies(currencies)
 
-	// 4. Инициализируем репозитории
-	transactionRepo := repository.NewTransactionRepository(dbPool)
-	walletRepo := repository.NewWalletRepository(dbPool)
+	// Курсы обмена для переводов с конвертацией
+	fxProvider, err := newFXProvider(cfg.FX)
+	if err != nil {
+		fatal("failed to load fx rates", err)
+	}
+	fxService := service.NewFXService(fxProvider, currencies, cfg.FX.QuoteTTL)
+	transactionService.SetFX(fxService)
+
+	// Холды резервируют средства до списания обычным переводом
+	holdService := service.NewHoldService(store.holds, store.wallets, cfg.Holds.DefaultTTL, cfg.Holds.MaxTTL)
+	holdService.SetCurrencies(currencies)
 
-	// 5. Инициализируем сервисы
-	transactionService := service.NewTransactionService(transactionRepo, walletRepo)
-	walletService := service.NewWalletService(walletRepo)
+	// Подкоманда client управляет клиентами и их API-ключами
+	if command == "client" {
+		if cfg.Database.Driver == config.DriverMemory {
+			fatal("client command failed", errors.New("database.driver memory does not keep clients after exit"))
+		}
+		if err := runClient(ctx, clientService, args[1:]); err != nil {
+			fatal("client command failed", err)
+		}
+		store.close()
+		return
+	}
+
+	// Метрики Prometheus: запросы, переводы, кошельки и пул соединений
+	appMetrics := metrics.New(store.db, walletService)
+	transactionService.SetMetrics(appMetrics)
+	holdService.SetMetrics(appMetrics)
+
+	// Сверяем балансы кошельков с журналом проводок; расхождение не мешает запуску, но требует разбора
+	if report, err := ledgerService.CheckInvariants(ctx); err != nil {
+		slog.Warn("ledger check failed", "error", err)
+		if report != nil {
+			for _, m := range report.Mismatches {
+				slog.Warn("wallet balance differs from ledger", "address", m.Address, "balance", m.Balance, "ledger_balance", m.LedgerBalance)
+			}
+		}
+	}
 
 	// 5.5. Создаем, при необходимости, начальные 10 кошельков
+	// Они создаются без владельца, поэтому при аутентификации доступны только администратору
 	if flagEmpty, err := walletService.IsEmpty(ctx); err != nil {
-		log.Fatalf("Failed to check if wallets table is empty: %v", err)
+		fatal("failed to check if wallets table is empty", err)
 	} else if flagEmpty {
 		for i := 0; i < 10; i++ {
-			address, err := walletService.CreateWallet(ctx, 100)
-			if err != nil {
-				log.Fatalf("Failed to create initial wallets: %v", err)
+			if _, err := walletService.CreateWallet(ctx, models.DefaultCurrency, models.AmountFromUnits(100)); err != nil {
+				fatal("failed to create initial wallets", err)
 			}
-			log.Printf("Created wallet %s\n", address)
+		}
+	}
+
+	// Ключи проверки bearer-токенов читаются один раз при запуске
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Auth.Mode == api.AuthModeJWT {
+		if jwtVerifier, err = auth.NewJWTVerifier(cfg.Auth.JWT); err != nil {
+			fatal("failed to load jwt keys", err)
 		}
 	}
 
 	// 6. Создаём роутер
-	router := api.NewRouter(transactionService, walletService)
+	router := api.NewRouter(transactionService, walletService, idempotencyService, healthService, clientService, api.Options{
+		AdminMode:   cfg.Server.AdminMode,
+		Metrics:     appMetrics,
+		AuthMode:    cfg.Auth.Mode,
+		JWTVerifier: jwtVerifier,
+		FX:          fxService,
+		Holds:       holdService,
+		// Тело запроса с Idempotency-Key читается в память целиком
+		MaxIdempotentBodyBytes: cfg.Idempotency.MaxBodyBytes,
+	})
 
-	// 7. Запускаем сервер
-	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	log.Printf("Starting server on %s...", addr)
+	// Фоновое снятие истёкших холдов и удаление истёкших ключей идемпотентности;
+	// останавливаются вместе с сервером до закрытия пула
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		holdService.RunExpiry(workersCtx, cfg.Holds.ExpiryInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		idempotencyService.RunPurge(workersCtx, cfg.Idempotency.PurgeInterval)
+	}()
 
-	err = http.ListenAndServe(addr, router)
+	// 7. Запускаем сервер; после его остановки закрываем пул, когда все запросы уже завершены
+	// С началом остановки /readyz отвечает 503, чтобы балансировщик перестал слать запросы
+	err = runServer(ctx, cfg.Server, router, healthService.BeginShutdown)
+	stopWorkers()
+	workers.Wait()
+	store.close()
+	slog.Info("database connections closed")
+
+	// Отправляем спаны, накопленные к моменту остановки
+	tracingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	cancel()
 	if err != nil {
-		log.Fatalf("Server failed: %v", err)
+		fatal("server stopped with error", err)
 	}
 }
+
+// fatal пишет ошибку в лог и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
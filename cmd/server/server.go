@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"TransactionSystem/config"
+)
+
+// runServer обслуживает запросы до SIGINT или SIGTERM, после чего перестаёт принимать
+// новые соединения и ждёт завершения текущих запросов не дольше cfg.ShutdownTimeout.
+// onShutdown вызывается сразу после получения сигнала.
+func runServer(ctx context.Context, cfg config.ServerConfig, handler http.Handler, onShutdown func()) error {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLS.Enabled() {
+			slog.Info("starting server", "addr", srv.Addr, "tls", true)
+			serveErr <- srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			slog.Info("starting server", "addr", srv.Addr, "tls", false)
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Повторный сигнал после этого момента завершит процесс сразу
+	stop()
+	onShutdown()
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+
+	slog.Info("server stopped")
+	return nil
+}
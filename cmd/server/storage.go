@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"TransactionSystem/config"
+	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/repository/sqlite"
+	"TransactionSystem/internal/service"
+)
+
+// schemaMigrator — мигратор выбранной базы: *database.Migrator, *database.SQLiteMigrator
+// или noMigrations для хранилища в памяти
+type schemaMigrator interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context) error
+	To(ctx context.Context, version int64) error
+	Status(ctx context.Context) ([]database.MigrationStatus, error)
+	CurrentVersion(ctx context.Context) (int64, error)
+	LatestVersion() int64
+}
+
+// storage — хранилища драйвера database.driver
+type storage struct {
+	db           service.Database
+	migrator     schemaMigrator
+	wallets      repository.WalletStore
+	transactions repository.TransactionStore
+	holds        repository.HoldStore
+	ledger       repository.LedgerStore
+	idempotency  repository.IdempotencyStore
+	clients      repository.ClientStore
+	close        func()
+}
+
+// openStorage подключается к базе из cfg.Database. Миграции не применяются.
+func openStorage(cfg *config.Config) (*storage, error) {
+	switch cfg.Database.Driver {
+	case config.DriverSQLite:
+		return openSQLite(cfg)
+	case config.DriverMemory:
+		return openMemory(), nil
+	}
+
+	pool, err := database.InitDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := database.NewMigrator(pool, cfg.Database.Schema)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	db := repository.NewDB(pool, cfg.Database.Schema)
+	return &storage{
+		db:           db,
+		migrator:     migrator,
+		wallets:      repository.NewWalletRepository(db),
+		transactions: repository.NewTransactionRepository(db),
+		holds:        repository.NewHoldRepository(db),
+		ledger:       repository.NewLedgerRepository(db),
+		idempotency:  repository.NewIdempotencyRepository(db),
+		clients:      repository.NewClientRepository(db),
+		close:        pool.Close,
+	}, nil
+}
+
+func openSQLite(cfg *config.Config) (*storage, error) {
+	sqlDB, err := database.InitSQLite(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := database.NewSQLiteMigrator(sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	db := sqlite.NewDB(sqlDB)
+	return &storage{
+		db:           db,
+		migrator:     migrator,
+		wallets:      sqlite.NewWalletRepository(db),
+		transactions: sqlite.NewTransactionRepository(db),
+		holds:        sqlite.NewHoldRepository(db),
+		ledger:       sqlite.NewLedgerRepository(db),
+		idempotency:  sqlite.NewIdempotencyRepository(db),
+		clients:      sqlite.NewClientRepository(db),
+		close:        func() { sqlDB.Close() },
+	}, nil
+}
+
+// openMemory возвращает хранилище в памяти процесса; схемы и соединений у него нет
+func openMemory() *storage {
+	store := memory.NewStore()
+	return &storage{
+		db:           memoryDB{},
+		migrator:     noMigrations{},
+		wallets:      store,
+		transactions: store,
+		holds:        store,
+		ledger:       store,
+		idempotency:  store,
+		clients:      store,
+		close:        func() {},
+	}
+}
+
+// memoryDB всегда доступна и не держит соединений
+type memoryDB struct{}
+
+func (memoryDB) Ping(ctx context.Context) error { return nil }
+func (memoryDB) Stats() models.PoolStats        { return models.PoolStats{} }
+
+// noMigrations — мигратор хранилища без схемы: применять нечего, версия всегда 0
+type noMigrations struct{}
+
+func (noMigrations) Up(ctx context.Context) error                      { return nil }
+func (noMigrations) Down(ctx context.Context) error                    { return nil }
+func (noMigrations) CurrentVersion(ctx context.Context) (int64, error) { return 0, nil }
+func (noMigrations) LatestVersion() int64                              { return 0 }
+
+func (noMigrations) To(ctx context.Context, version int64) error {
+	if version != 0 {
+		return fmt.Errorf("database.driver %s has no migrations", config.DriverMemory)
+	}
+	return nil
+}
+
+func (noMigrations) Status(ctx context.Context) ([]database.MigrationStatus, error) {
+	return nil, nil
+}
@@ -1,44 +1,498 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"TransactionSystem/internal/models"
 
 	"gopkg.in/yaml.v2"
-	"os"
 )
 
+// DatabaseConfig — хранилище данных. Параметры подключения host…schema относятся
+// к PostgreSQL, path — к SQLite.
 type DatabaseConfig struct {
-	Host          string `yaml:"host"`
-	Port          int    `yaml:"port"`
-	User          string `yaml:"user"`
-	Password      string `yaml:"password"`
-	Dbname        string `yaml:"dbname"`
-	Sslmode       string `yaml:"sslmode"`
-	Schema		  string `yaml:"schema"`
+	// postgres, sqlite (встроенная база в одном файле, для одного экземпляра сервиса)
+	// или memory (данные в памяти процесса теряются при остановке; для разработки и демонстраций)
+	Driver   string `yaml:"driver"`
+	Path     string `yaml:"path"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Dbname   string `yaml:"dbname"`
+	Sslmode  string `yaml:"sslmode"`
+	Schema   string `yaml:"schema"`
 }
 
 type ServerConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+	// Включает административные пути API, например удаление транзакций и кошельков
+	AdminMode bool `yaml:"admin_mode"`
+
+	// Таймауты http.Server; ReadHeaderTimeout защищает от медленных клиентов (slowloris)
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// Сколько после SIGINT/SIGTERM ждать завершения запросов, которые уже выполняются
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig — сертификат и ключ в формате PEM. Если оба пути пусты, сервер работает по HTTP.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+type IdempotencyConfig struct {
+	// Время, в течение которого повтор запроса с тем же Idempotency-Key возвращает исходный ответ
+	TTL time.Duration `yaml:"ttl"`
+	// Как часто фоновая задача удаляет ключи с истёкшим сроком
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+	// Наибольший размер тела запроса с Idempotency-Key в байтах: тело читается в память
+	// целиком ради отпечатка запроса, запросы больше отклоняются с 413
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+}
+
+type LogConfig struct {
+	// debug, info, warn или error
+	Level string `yaml:"level"`
+	// text или json
+	Format string `yaml:"format"`
+}
+
+type TracingConfig struct {
+	// none, stdout или otlp
+	Exporter string `yaml:"exporter"`
+	// Адрес OTLP/HTTP-коллектора host:port; пусто — localhost:4318 или OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string `yaml:"endpoint"`
+	// Отправлять трассы коллектору по HTTP без TLS
+	Insecure bool `yaml:"insecure"`
+	// Доля трасс, начатых этим сервисом, которые записываются: от 0 до 1.
+	// Для входящих запросов с traceparent решение принимает вызывающая сторона.
+	SampleRatio float64 `yaml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name"`
+}
+
+type AuthConfig struct {
+	// none — запросы не аутентифицируются; api_key — каждый запрос к /api должен
+	// содержать ключ клиента в заголовке X-API-Key; jwt — токен в заголовке Authorization: Bearer
+	Mode string    `yaml:"mode"`
+	JWT  JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig — проверка bearer-токенов. Ключ подписи задаётся ровно одним из файлов:
+// JWKS (набор ключей, выбор по kid) или открытый ключ PEM.
+type JWTConfig struct {
+	JWKSFile      string `yaml:"jwks_file"`
+	PublicKeyFile string `yaml:"public_key_file"`
+	// Ожидаемые iss и aud; пустое значение не проверяется
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// Claim с идентификатором клиента и claim со списком scope (строка через пробел или массив)
+	SubjectClaim string `yaml:"subject_claim"`
+	ScopeClaim   string `yaml:"scope_claim"`
+	// Допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration `yaml:"leeway"`
+}
+
+// CurrencyConfig — собственные валюты в дополнение к ISO 4217
+type CurrencyConfig struct {
+	// Код валюты и число знаков после запятой (от 0 до 2), например PTS: 0.
+	// Валюты ISO 4217 с тремя и более знаками (BHD, KWD и другие) не поддерживаются
+	// и не могут быть объявлены здесь: суммы хранятся с двумя знаками.
+	// Задаётся только в файле конфигурации.
+	Custom map[string]int `yaml:"custom"`
+}
+
+// FXConfig — курсы обмена для переводов с конвертацией
+type FXConfig struct {
+	// static — неизменная таблица из rates_file; manual — курсы задаёт администратор,
+	// rates_file (если указан) задаёт начальные значения
+	Provider  string `yaml:"provider"`
+	RatesFile string `yaml:"rates_file"`
+	// Сколько действует котировка, зафиксировавшая курс
+	QuoteTTL time.Duration `yaml:"quote_ttl"`
+}
+
+// HoldConfig — холды, резервирующие средства до списания или снятия
+type HoldConfig struct {
+	// Срок холда, если в запросе не указан ttl_seconds, и наибольший допустимый срок
+	DefaultTTL time.Duration `yaml:"default_ttl"`
+	MaxTTL     time.Duration `yaml:"max_ttl"`
+	// Как часто фоновая задача снимает истёкшие холды
+	ExpiryInterval time.Duration `yaml:"expiry_interval"`
 }
 
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
-	Server   ServerConfig   `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Server      ServerConfig      `yaml:"server"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Currencies  CurrencyConfig    `yaml:"currencies"`
+	FX          FXConfig          `yaml:"fx"`
+	Holds       HoldConfig        `yaml:"holds"`
+}
+
+const (
+	// EnvPrefix — префикс переменных окружения: database.host задаётся через TS_DATABASE_HOST
+	EnvPrefix = "TS_"
+	// DefaultPath — файл конфигурации, который читается, если путь не указан явно
+	DefaultPath = "config/config.yml"
+	// pathEnv задаёт путь к файлу конфигурации, флаг --config имеет приоритет
+	pathEnv = EnvPrefix + "CONFIG"
+)
+
+// Значения database.driver
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Значения fx.provider
+const (
+	FXProviderStatic = "static"
+	FXProviderManual = "manual"
+)
+
+// Default возвращает конфигурацию по умолчанию — нижний слой LoadConfig
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Driver:  DriverPostgres,
+			Path:    "transaction_system.db",
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Dbname:  "transaction_system",
+			Sslmode: "disable",
+			Schema:  "TransactionSystem",
+		},
+		Server: ServerConfig{
+			Host:              "0.0.0.0",
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           24 * time.Hour,
+			PurgeInterval: time.Hour,
+			MaxBodyBytes:  1 << 20,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "transaction-system",
+		},
+		Auth: AuthConfig{
+			Mode: "none",
+			JWT: JWTConfig{
+				SubjectClaim: "sub",
+				ScopeClaim:   "scope",
+				Leeway:       30 * time.Second,
+			},
+		},
+		FX: FXConfig{
+			Provider: FXProviderManual,
+			QuoteTTL: 30 * time.Second,
+		},
+		Holds: HoldConfig{
+			DefaultTTL:     15 * time.Minute,
+			MaxTTL:         7 * 24 * time.Hour,
+			ExpiryInterval: time.Minute,
+		},
+	}
+}
+
+// LoadConfig собирает конфигурацию из слоёв, каждый следующий переопределяет предыдущий:
+// значения по умолчанию, YAML-файл, переменные окружения с префиксом EnvPrefix, флаги args.
+// Путь к файлу задаётся флагом --config или переменной TS_CONFIG; файл по умолчанию
+// (DefaultPath) может отсутствовать. Ошибка *ValidationError перечисляет все некорректные параметры.
+// Вторым значением возвращаются позиционные аргументы после флагов, например подкоманда.
+func LoadConfig(args []string) (*Config, []string, error) {
+	return load(args, os.LookupEnv)
 }
 
-func LoadConfig(filename string) (*Config, error) {
-	file, err := os.Open(filename)
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	cfg := Default()
+	params := parameters(cfg)
+
+	fs := flag.NewFlagSet("transaction-system", flag.ContinueOnError)
+	path := fs.String("config", "", "path to the YAML config file (env "+pathEnv+")")
+
+	// Флаги только запоминаются: применяются они после файла и окружения
+	var flagValues []flagValue
+	for _, p := range params {
+		p := p
+		usage := fmt.Sprintf("overrides %s (env %s)", p.key, p.env)
+		set := func(value string) error {
+			flagValues = append(flagValues, flagValue{p, value})
+			return nil
+		}
+		if p.isBool() {
+			fs.BoolFunc(p.key, usage, set)
+		} else {
+			fs.Func(p.key, usage, set)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path == "" {
+		*path, _ = lookupEnv(pathEnv)
+	}
+	var verr ValidationError
+
+	if err := decodeFile(cfg, *path, &verr); err != nil {
+		return nil, nil, err
+	}
+
+	for _, p := range params {
+		if value, ok := lookupEnv(p.env); ok {
+			if err := p.set(value); err != nil {
+				verr.add(p.key, fmt.Sprintf("env %s: %v", p.env, err))
+			}
+		}
+	}
+
+	for _, f := range flagValues {
+		if err := f.param.set(f.value); err != nil {
+			verr.add(f.param.key, fmt.Sprintf("flag --%s: %v", f.param.key, err))
+		}
+	}
+
+	cfg.validate(&verr)
+	if len(verr.Errors) > 0 {
+		return nil, nil, &verr
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// decodeFile накладывает на cfg значения из YAML-файла. Пустой path означает
+// DefaultPath, отсутствие которого не считается ошибкой. Неизвестные ключи
+// (обычно опечатки) попадают в verr вместе с остальными некорректными параметрами.
+func decodeFile(cfg *Config, path string, verr *ValidationError) error {
+	optional := path == ""
+	if optional {
+		path = DefaultPath
+	}
+
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("could not open config file: %v", err)
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not open config file: %w", err)
 	}
 	defer file.Close()
 
-	var config Config
-	decoder := yaml.NewDecoder(file)
-	err = decoder.Decode(&config)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode config file: %v", err)
+		return fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.SetStrict(true)
+	err = dec.Decode(cfg)
+	// Пустой файл или файл из одних комментариев не задаёт ни одного параметра
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		if unknown := unknownKeys(data); len(unknown) > 0 {
+			for _, key := range unknown {
+				verr.add(key, fmt.Sprintf("unknown key in config file %s", path))
+			}
+			var rest []string
+			for _, msg := range typeErr.Errors {
+				if !strings.Contains(msg, " not found in type ") {
+					rest = append(rest, msg)
+				}
+			}
+			if len(rest) == 0 {
+				return nil
+			}
+			err = &yaml.TypeError{Errors: rest}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not decode config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// schemaName — допустимое имя схемы; оно подставляется в SQL, поэтому ограничено простыми идентификаторами
+var schemaName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+var (
+	logLevels  = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	logFormats = map[string]bool{"text": true, "json": true}
+	exporters  = map[string]bool{"none": true, "stdout": true, "otlp": true}
+	authModes  = map[string]bool{"none": true, "api_key": true, "jwt": true}
+)
+
+func (c *Config) validate(verr *ValidationError) {
+	required := func(key, value string) {
+		if value == "" {
+			verr.add(key, "must not be empty")
+		}
+	}
+	port := func(key string, value int) {
+		if value < 1 || value > 65535 {
+			verr.add(key, fmt.Sprintf("must be between 1 and 65535, got %d", value))
+		}
+	}
+
+	switch c.Database.Driver {
+	case DriverPostgres:
+		required("database.host", c.Database.Host)
+		port("database.port", c.Database.Port)
+		required("database.user", c.Database.User)
+		required("database.dbname", c.Database.Dbname)
+		if !schemaName.MatchString(c.Database.Schema) {
+			verr.add("database.schema", fmt.Sprintf("must be a plain identifier (letters, digits, underscore), got %q", c.Database.Schema))
+		}
+		if !sslModes[c.Database.Sslmode] {
+			verr.add("database.sslmode", fmt.Sprintf("unknown mode %q", c.Database.Sslmode))
+		}
+	case DriverSQLite:
+		required("database.path", c.Database.Path)
+	case DriverMemory:
+	default:
+		verr.add("database.driver", fmt.Sprintf("unknown driver %q, expected postgres, sqlite or memory", c.Database.Driver))
+	}
+
+	port("server.port", c.Server.Port)
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			verr.add(key, "must be positive")
+		}
+	}
+	positive("server.read_timeout", c.Server.ReadTimeout)
+	positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	positive("server.write_timeout", c.Server.WriteTimeout)
+	positive("server.idle_timeout", c.Server.IdleTimeout)
+	positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.TLS.Enabled() {
+		required("server.tls.cert_file", c.Server.TLS.CertFile)
+		required("server.tls.key_file", c.Server.TLS.KeyFile)
+	}
+
+	positive("idempotency.ttl", c.Idempotency.TTL)
+	positive("idempotency.purge_interval", c.Idempotency.PurgeInterval)
+	if c.Idempotency.MaxBodyBytes <= 0 {
+		verr.add("idempotency.max_body_bytes", fmt.Sprintf("must be positive, got %d", c.Idempotency.MaxBodyBytes))
+	}
+
+	if !logLevels[c.Log.Level] {
+		verr.add("log.level", fmt.Sprintf("unknown level %q, expected debug, info, warn or error", c.Log.Level))
+	}
+	if !logFormats[c.Log.Format] {
+		verr.add("log.format", fmt.Sprintf("unknown format %q, expected text or json", c.Log.Format))
+	}
+
+	if !exporters[c.Tracing.Exporter] {
+		verr.add("tracing.exporter", fmt.Sprintf("unknown exporter %q, expected none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		verr.add("tracing.sample_ratio", fmt.Sprintf("must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+	required("tracing.service_name", c.Tracing.ServiceName)
+
+	if !authModes[c.Auth.Mode] {
+		verr.add("auth.mode", fmt.Sprintf("unknown mode %q, expected none, api_key or jwt", c.Auth.Mode))
+	}
+	// Первый ключ выдаёт команда "client create", а её результат в памяти не переживает выход
+	if c.Auth.Mode == "api_key" && c.Database.Driver == DriverMemory {
+		verr.add("auth.mode", "api_key requires a persistent database.driver, memory loses clients on exit")
+	}
+	if c.Auth.Mode == "jwt" {
+		jwt := c.Auth.JWT
+		if (jwt.JWKSFile == "") == (jwt.PublicKeyFile == "") {
+			verr.add("auth.jwt.jwks_file", "exactly one of auth.jwt.jwks_file and auth.jwt.public_key_file must be set")
+		}
+		required("auth.jwt.subject_claim", jwt.SubjectClaim)
+		required("auth.jwt.scope_claim", jwt.ScopeClaim)
+		if jwt.Leeway < 0 {
+			verr.add("auth.jwt.leeway", "must not be negative")
+		}
+	}
+
+	if _, err := models.NewCurrencies(c.Currencies.Custom); err != nil {
+		verr.add("currencies.custom", err.Error())
+	}
+
+	switch c.FX.Provider {
+	case FXProviderStatic:
+		required("fx.rates_file", c.FX.RatesFile)
+	case FXProviderManual:
+	default:
+		verr.add("fx.provider", fmt.Sprintf("unknown provider %q, expected static or manual", c.FX.Provider))
+	}
+	positive("fx.quote_ttl", c.FX.QuoteTTL)
+
+	positive("holds.default_ttl", c.Holds.DefaultTTL)
+	positive("holds.max_ttl", c.Holds.MaxTTL)
+	positive("holds.expiry_interval", c.Holds.ExpiryInterval)
+	if c.Holds.DefaultTTL > c.Holds.MaxTTL {
+		verr.add("holds.default_ttl", "must not exceed holds.max_ttl")
+	}
+}
+
+// FieldError — некорректное значение одного параметра конфигурации
+type FieldError struct {
+	Key    string
+	Reason string
+}
+
+// ValidationError перечисляет все некорректные параметры конфигурации
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) add(key, reason string) {
+	e.Errors = append(e.Errors, FieldError{Key: key, Reason: reason})
+}
+
+func (e *ValidationError) Error() string {
+	msg := "invalid configuration:"
+	for _, fe := range e.Errors {
+		msg += fmt.Sprintf("\n  %s: %s", fe.Key, fe.Reason)
 	}
-	return &config, nil
+	return msg
 }
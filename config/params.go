@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// param — скалярное поле конфигурации, которое можно переопределить
+// переменной окружения и флагом
+type param struct {
+	// key — путь по yaml-тегам, например database.host; он же имя флага
+	key   string
+	env   string
+	value reflect.Value
+}
+
+type flagValue struct {
+	param param
+	value string
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parameters перечисляет скалярные поля cfg по yaml-тегам. Новые поля структур
+// конфигурации автоматически получают переменную окружения и флаг.
+func parameters(cfg *Config) []param {
+	var params []param
+	collect(reflect.ValueOf(cfg).Elem(), "", &params)
+	return params
+}
+
+func collect(v reflect.Value, prefix string, params *[]param) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := prefix + name
+		field := v.Field(i)
+
+		switch field.Kind() {
+		case reflect.Struct:
+			collect(field, key+".", params)
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			env := EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+			*params = append(*params, param{key: key, env: env, value: field})
+		}
+	}
+}
+
+// unknownKeys возвращает ключи YAML-документа data, которым не соответствует ни одно поле Config,
+// в виде путей по yaml-тегам, например database.hots
+func unknownKeys(data []byte) []string {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	var keys []string
+	walkKeys(doc, reflect.TypeOf(Config{}), "", &keys)
+	return keys
+}
+
+func walkKeys(doc yaml.MapSlice, t reflect.Type, prefix string, keys *[]string) {
+	for _, item := range doc {
+		name := fmt.Sprint(item.Key)
+		field, ok := fieldByTag(t, name)
+		if !ok {
+			*keys = append(*keys, prefix+name)
+			continue
+		}
+		// Вложенные разделы проверяются рекурсивно, содержимое полей-map (currencies.custom) — нет
+		if nested, ok := item.Value.(yaml.MapSlice); ok && field.Type.Kind() == reflect.Struct {
+			walkKeys(nested, field.Type, prefix+name+".", keys)
+		}
+	}
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; tag == name && tag != "-" {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func (p param) isBool() bool {
+	return p.value.Kind() == reflect.Bool
+}
+
+// set разбирает строковое значение по типу поля и записывает его в конфигурацию
+func (p param) set(s string) error {
+	if p.value.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		p.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch p.value.Kind() {
+	case reflect.String:
+		p.value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		p.value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		p.value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		p.value.SetFloat(f)
+	}
+	return nil
+}
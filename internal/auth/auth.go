@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope — право, которое требуется для вызова пути API
+const (
+	ScopeWalletRead     = "wallet:read"
+	ScopeWalletWrite    = "wallet:write"
+	ScopeTransferCreate = "transfer:create"
+	// ScopeAdmin даёт доступ ко всем кошелькам и включает все остальные scope
+	ScopeAdmin = "admin"
+)
+
+// ClientScopes — scope обычного клиента, аутентифицированного API-ключом
+var ClientScopes = []string{ScopeWalletRead, ScopeWalletWrite, ScopeTransferCreate}
+
+// Principal — аутентифицированный вызывающий. Запрос без Principal в контексте
+// выполняется без проверок доступа: так работают режим auth.mode=none и внутренние вызовы.
+type Principal struct {
+	ClientId int64
+	// Subject — субъект JWT; пуст для API-ключей
+	Subject string
+	Admin   bool
+	Scopes  []string
+}
+
+// HasScope сообщает, выдан ли вызывающему scope; администратору доступны все
+func (p *Principal) HasScope(scope string) bool {
+	return p.Admin || slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает Principal запроса, если он был аутентифицирован
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Ключ имеет вид tsk_<prefix>_<secret>: prefix хранится открыто и служит для поиска
+// записи, secret — 32 случайных байта. В базе хранится только SHA-256 всего ключа.
+const (
+	keyScheme    = "tsk"
+	prefixBytes  = 6
+	secretBytes  = 32
+	keySeparator = "_"
+)
+
+// GenerateKey создаёт новый API-ключ и возвращает его вместе с префиксом и хэшем для хранения
+func GenerateKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	prefix = hex.EncodeToString(buf[:prefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(buf[prefixBytes:])
+	key = keyScheme + keySeparator + prefix + keySeparator + secret
+
+	return key, prefix, HashKey(key), nil
+}
+
+// ParseKey возвращает префикс ключа или false, если строка не похожа на API-ключ
+func ParseKey(key string) (string, bool) {
+	scheme, rest, ok := strings.Cut(key, keySeparator)
+	if !ok || scheme != keyScheme {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, keySeparator)
+	if !ok || len(prefix) != 2*prefixBytes || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// HashKey возвращает SHA-256 ключа в hex. Ключи случайны и длинны, поэтому медленный
+// хэш паролей здесь не нужен.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyKey сравнивает ключ с сохранённым хэшем за постоянное время
+func VerifyKey(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashKey(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"TransactionSystem/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken — токен не прошёл проверку: подпись, срок действия, iss/aud или claims
+var ErrInvalidToken = errors.New("invalid token")
+
+// validMethods — только асимметричные алгоритмы. HS* и none запрещены, иначе открытый
+// ключ можно было бы использовать как HMAC-секрет и подписать токен самому.
+var validMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Claims — данные проверенного токена, нужные сервису
+type Claims struct {
+	Subject string
+	Scopes  []string
+}
+
+// JWTVerifier проверяет bearer-токены ключами из JWKS-файла или открытым ключом PEM
+type JWTVerifier struct {
+	// keys — ключи JWKS по kid; key — единственный ключ из PEM-файла
+	keys         map[string]crypto.PublicKey
+	key          crypto.PublicKey
+	parser       *jwt.Parser
+	subjectClaim string
+	scopeClaim   string
+}
+
+// NewJWTVerifier читает ключи подписи из файла, указанного в cfg
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{subjectClaim: cfg.SubjectClaim, scopeClaim: cfg.ScopeClaim}
+
+	var err error
+	switch {
+	case cfg.JWKSFile != "":
+		v.keys, err = LoadJWKS(cfg.JWKSFile)
+	case cfg.PublicKeyFile != "":
+		v.key, err = LoadPublicKey(cfg.PublicKeyFile)
+	default:
+		err = errors.New("no jwks file or public key configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает его субъект и scope
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims[v.subjectClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: claim %q is missing or not a string", ErrInvalidToken, v.subjectClaim)
+	}
+
+	scopes, err := parseScopes(claims[v.scopeClaim])
+	if err != nil {
+		return nil, fmt.Errorf("%w: claim %q: %v", ErrInvalidToken, v.scopeClaim, err)
+	}
+
+	return &Claims{Subject: subject, Scopes: scopes}, nil
+}
+
+// keyFunc выбирает ключ проверки по заголовку kid. Токен без kid допускается,
+// если ключ один.
+func (v *JWTVerifier) keyFunc(t *jwt.Token) (interface{}, error) {
+	if v.key != nil {
+		return v.key, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// parseScopes принимает scope в виде строки через пробел (OAuth 2.0) или массива строк.
+// Отсутствующий claim означает пустой набор.
+func parseScopes(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []interface{}:
+		scopes := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected element %v", item)
+			}
+			scopes = append(scopes, s)
+		}
+		return scopes, nil
+	default:
+		return nil, fmt.Errorf("unexpected type %T", value)
+	}
+}
+
+// LoadPublicKey читает открытый ключ RSA, ECDSA или Ed25519 в формате PEM (PUBLIC KEY)
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s is not PEM encoded", path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key %s: %w", path, err)
+	}
+	return key, nil
+}
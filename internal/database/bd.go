@@ -3,7 +3,7 @@ package database
 import (
     "context"
     "fmt"
-    "log"
+    "log/slog"
 
     "github.com/jackc/pgx/v4/pgxpool"
     "TransactionSystem/config"
@@ -27,6 +27,6 @@ func InitDB(cfg *config.Config) (*pgxpool.Pool, error) {
         return nil, fmt.Errorf("unable to connect to database: %w", err)
     }
 
-    log.Println("Connected to database")
+    slog.Info("connected to database", "host", cfg.Database.Host, "dbname", cfg.Database.Dbname, "schema", cfg.Database.Schema)
     return pool, nil
 }
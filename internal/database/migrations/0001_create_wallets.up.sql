@@ -1,6 +1,6 @@
-CREATE SCHEMA IF NOT EXISTS "TransactionSystem";
+CREATE SCHEMA IF NOT EXISTS {schema};
 
-CREATE TABLE IF NOT EXISTS "TransactionSystem".wallets (
+CREATE TABLE IF NOT EXISTS {schema}.wallets (
     address TEXT PRIMARY KEY,
     balance DECIMAL(18, 2) NOT NULL,
     created_at TIMESTAMP DEFAULT now()
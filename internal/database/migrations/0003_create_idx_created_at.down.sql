@@ -0,0 +1 @@
+DROP INDEX IF EXISTS {schema}.idx_created_at;
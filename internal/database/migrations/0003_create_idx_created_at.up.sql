@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_created_at ON {schema}.transactions (created_at DESC);
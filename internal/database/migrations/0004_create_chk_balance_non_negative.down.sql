@@ -0,0 +1 @@
+ALTER TABLE {schema}.wallets DROP CONSTRAINT IF EXISTS chk_balance_non_negative;
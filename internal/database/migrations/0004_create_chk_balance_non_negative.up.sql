@@ -0,0 +1,9 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'chk_balance_non_negative'
+    ) THEN
+        ALTER TABLE {schema}.wallets
+            ADD CONSTRAINT chk_balance_non_negative CHECK (balance >= 0);
+    END IF;
+END $$;
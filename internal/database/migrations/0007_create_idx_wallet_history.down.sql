@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS {schema}.idx_from_wallet_created_at;
+DROP INDEX IF EXISTS {schema}.idx_to_wallet_created_at;
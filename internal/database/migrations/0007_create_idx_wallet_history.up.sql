@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_from_wallet_created_at ON {schema}.transactions (from_wallet, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_to_wallet_created_at ON {schema}.transactions (to_wallet, created_at DESC, id DESC);
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS {schema}.ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT,
+    account TEXT NOT NULL,
+    amount DECIMAL(18, 2) NOT NULL,
+    kind TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CONSTRAINT fk_transaction FOREIGN KEY (transaction_id) REFERENCES {schema}.transactions(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON {schema}.ledger_entries (account, id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON {schema}.ledger_entries (transaction_id);
+
+-- Перенос существующих данных в пустой журнал: начальный баланс каждого кошелька
+-- восстанавливается из текущего баланса и истории переводов
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM {schema}.ledger_entries) THEN
+        CREATE TEMPORARY TABLE ledger_opening ON COMMIT DROP AS
+        SELECT w.address, w.created_at,
+               w.balance
+                   - COALESCE((SELECT SUM(t.amount) FROM {schema}.transactions t WHERE t.to_wallet = w.address), 0)
+                   + COALESCE((SELECT SUM(t.amount) FROM {schema}.transactions t WHERE t.from_wallet = w.address), 0)
+                   AS amount
+        FROM {schema}.wallets w;
+
+        INSERT INTO {schema}.ledger_entries (transaction_id, account, amount, kind, created_at)
+        SELECT NULL, o.account, o.amount, 'opening', o.created_at
+        FROM (
+            SELECT address AS account, amount, created_at FROM ledger_opening
+            UNION ALL
+            SELECT '@issuance', -amount, created_at FROM ledger_opening
+        ) o
+        WHERE o.amount <> 0;
+
+        INSERT INTO {schema}.ledger_entries (transaction_id, account, amount, kind, created_at)
+        SELECT e.transaction_id, e.account, e.amount, 'transfer', e.created_at
+        FROM (
+            SELECT id AS transaction_id, from_wallet AS account, -amount AS amount, created_at FROM {schema}.transactions
+            UNION ALL
+            SELECT id, to_wallet, amount, created_at FROM {schema}.transactions
+        ) e
+        ORDER BY e.transaction_id;
+    END IF;
+END $$;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS {schema}.idx_transactions_reversal_of;
+
+ALTER TABLE {schema}.transactions
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS reversed_by,
+    DROP COLUMN IF EXISTS reversal_of;
@@ -0,0 +1,10 @@
+-- Сторнирование: компенсирующая транзакция ссылается на исходную,
+-- исходная помечается ссылкой на сторнирующую
+ALTER TABLE {schema}.transactions
+    ADD COLUMN IF NOT EXISTS reversal_of BIGINT REFERENCES {schema}.transactions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reversed_by BIGINT REFERENCES {schema}.transactions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reason TEXT;
+
+-- Транзакцию можно сторнировать не более одного раза
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversal_of
+    ON {schema}.transactions (reversal_of) WHERE reversal_of IS NOT NULL;
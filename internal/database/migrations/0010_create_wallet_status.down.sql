@@ -0,0 +1,3 @@
+ALTER TABLE {schema}.wallets
+    DROP CONSTRAINT IF EXISTS chk_wallet_status,
+    DROP COLUMN IF EXISTS status;
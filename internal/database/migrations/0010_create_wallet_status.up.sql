@@ -0,0 +1,12 @@
+ALTER TABLE {schema}.wallets
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'chk_wallet_status'
+    ) THEN
+        ALTER TABLE {schema}.wallets
+            ADD CONSTRAINT chk_wallet_status CHECK (status IN ('active', 'frozen', 'closed'));
+    END IF;
+END $$;
@@ -0,0 +1 @@
+-- Ограничения принадлежат миграциям 0004 и 0010 и удаляются при их откате
@@ -0,0 +1,20 @@
+-- Имена ограничений уникальны только в пределах таблицы, поэтому проверки в 0004 и 0010
+-- могли пропустить создание ограничений, если их уже создала другая схема той же базы
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'chk_balance_non_negative' AND conrelid = '{schema}.wallets'::regclass
+    ) THEN
+        ALTER TABLE {schema}.wallets
+            ADD CONSTRAINT chk_balance_non_negative CHECK (balance >= 0);
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'chk_wallet_status' AND conrelid = '{schema}.wallets'::regclass
+    ) THEN
+        ALTER TABLE {schema}.wallets
+            ADD CONSTRAINT chk_wallet_status CHECK (status IN ('active', 'frozen', 'closed'));
+    END IF;
+END $$;
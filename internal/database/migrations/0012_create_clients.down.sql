@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS {schema}.idx_wallets_owner_id;
+
+ALTER TABLE {schema}.wallets
+    DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS {schema}.api_keys;
+DROP TABLE IF EXISTS {schema}.clients;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS {schema}.clients (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    admin BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Хранится только SHA-256 ключа; prefix — открытая часть ключа для поиска записи
+CREATE TABLE IF NOT EXISTS {schema}.api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    client_id BIGINT NOT NULL REFERENCES {schema}.clients (id) ON DELETE CASCADE,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_client_id ON {schema}.api_keys (client_id);
+
+-- Кошельки, созданные до появления клиентов, остаются без владельца и доступны только администратору
+ALTER TABLE {schema}.wallets
+    ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES {schema}.clients (id);
+
+CREATE INDEX IF NOT EXISTS idx_wallets_owner_id ON {schema}.wallets (owner_id);
@@ -0,0 +1,2 @@
+ALTER TABLE {schema}.clients
+    DROP COLUMN IF EXISTS subject;
@@ -0,0 +1,3 @@
+-- Клиенты, аутентифицированные JWT, находятся по субъекту токена и создаются при первом запросе
+ALTER TABLE {schema}.clients
+    ADD COLUMN IF NOT EXISTS subject TEXT UNIQUE;
@@ -0,0 +1,5 @@
+ALTER TABLE {schema}.transactions
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE {schema}.wallets
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,7 @@
+-- Кошелёк хранит средства в одной валюте; существующие кошельки считаются долларовыми (models.DefaultCurrency).
+-- Перевод возможен только между кошельками одной валюты, она же записывается в транзакцию.
+ALTER TABLE {schema}.wallets
+    ADD COLUMN IF NOT EXISTS currency VARCHAR(8) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE {schema}.transactions
+    ADD COLUMN IF NOT EXISTS currency VARCHAR(8) NOT NULL DEFAULT 'USD';
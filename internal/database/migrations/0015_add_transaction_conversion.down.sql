@@ -0,0 +1,9 @@
+ALTER TABLE {schema}.transactions
+    DROP CONSTRAINT IF EXISTS chk_transaction_conversion;
+
+ALTER TABLE {schema}.transactions
+    DROP COLUMN IF EXISTS quote_id,
+    DROP COLUMN IF EXISTS residue,
+    DROP COLUMN IF EXISTS to_currency,
+    DROP COLUMN IF EXISTS to_amount,
+    DROP COLUMN IF EXISTS rate;
@@ -0,0 +1,17 @@
+-- Перевод с конвертацией: списание в валюте отправителя (amount, currency), зачисление
+-- в валюте получателя (to_amount, to_currency) по курсу rate. residue — остаток округления
+-- amount * rate до точности валюты получателя. У обычных переводов все столбцы пусты.
+ALTER TABLE {schema}.transactions
+    ADD COLUMN IF NOT EXISTS rate NUMERIC(20, 8),
+    ADD COLUMN IF NOT EXISTS to_amount DECIMAL(18, 2),
+    ADD COLUMN IF NOT EXISTS to_currency VARCHAR(8),
+    ADD COLUMN IF NOT EXISTS residue NUMERIC(20, 10),
+    ADD COLUMN IF NOT EXISTS quote_id TEXT;
+
+ALTER TABLE {schema}.transactions
+    DROP CONSTRAINT IF EXISTS chk_transaction_conversion;
+ALTER TABLE {schema}.transactions
+    ADD CONSTRAINT chk_transaction_conversion CHECK (
+        (rate IS NULL AND to_amount IS NULL AND to_currency IS NULL AND residue IS NULL)
+        OR (rate > 0 AND to_amount > 0 AND to_currency IS NOT NULL AND residue >= 0)
+    );
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS {schema}.holds;
+
+ALTER TABLE {schema}.wallets
+    DROP CONSTRAINT IF EXISTS chk_wallet_held,
+    DROP COLUMN IF EXISTS held;
@@ -0,0 +1,36 @@
+-- Холды резервируют средства кошелька до списания или отмены. Сумма активных холдов
+-- хранится в кошельке (held), чтобы проверка доступного баланса не суммировала холды
+ALTER TABLE {schema}.wallets
+    ADD COLUMN IF NOT EXISTS held DECIMAL(18, 2) NOT NULL DEFAULT 0;
+
+-- Имя ограничения проверяется вместе с таблицей, как в 0011: другая схема той же базы
+-- может уже иметь ограничение с таким именем
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'chk_wallet_held' AND conrelid = '{schema}.wallets'::regclass
+    ) THEN
+        ALTER TABLE {schema}.wallets
+            ADD CONSTRAINT chk_wallet_held CHECK (held >= 0 AND held <= balance);
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS {schema}.holds (
+    id BIGSERIAL PRIMARY KEY,
+    from_wallet TEXT NOT NULL REFERENCES {schema}.wallets (address),
+    to_wallet TEXT NOT NULL REFERENCES {schema}.wallets (address),
+    amount DECIMAL(18, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(8) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT chk_hold_status CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    closed_at TIMESTAMP,
+    captured_amount DECIMAL(18, 2),
+    transaction_id BIGINT REFERENCES {schema}.transactions (id) ON DELETE SET NULL
+);
+
+-- Поиск истёкших холдов фоновой задачей
+CREATE INDEX IF NOT EXISTS idx_holds_active_expires_at
+    ON {schema}.holds (expires_at) WHERE status = 'active';
@@ -0,0 +1,2 @@
+ALTER TABLE {schema}.ledger_entries
+    DROP COLUMN IF EXISTS balance_after;
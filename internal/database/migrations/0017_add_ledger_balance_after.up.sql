@@ -0,0 +1,14 @@
+-- Баланс кошелька после каждой проводки. История кошелька берёт его из журнала, поэтому
+-- корректировки баланса, начальный баланс и удалённые транзакции в ней учтены.
+-- У системных счетов (начинаются с '@') значение не ведётся.
+ALTER TABLE {schema}.ledger_entries
+    ADD COLUMN IF NOT EXISTS balance_after DECIMAL(18, 2);
+
+UPDATE {schema}.ledger_entries l
+SET balance_after = r.balance_after
+FROM (
+    SELECT id, SUM(amount) OVER (PARTITION BY account ORDER BY id) AS balance_after
+    FROM {schema}.ledger_entries
+    WHERE account NOT LIKE '@%'
+) r
+WHERE l.id = r.id AND l.balance_after IS NULL;
@@ -0,0 +1,36 @@
+-- Валюта проводки. Системные счета (начинаются с '@') общие для всех валют,
+-- поэтому журнал должен сходиться к нулю по каждой валюте отдельно.
+ALTER TABLE {schema}.ledger_entries
+    ADD COLUMN IF NOT EXISTS currency VARCHAR(8);
+
+-- Проводки кошельков — в валюте кошелька
+UPDATE {schema}.ledger_entries l
+SET currency = w.currency
+FROM {schema}.wallets w
+WHERE l.currency IS NULL AND w.address = l.account;
+
+-- Пара проводок записывается одним запросом, поэтому идёт подряд и взаимно погашается:
+-- проводка системного счёта получает валюту кошелька из своей пары
+UPDATE {schema}.ledger_entries l
+SET currency = p.currency
+FROM {schema}.ledger_entries p
+WHERE l.currency IS NULL
+  AND p.currency IS NOT NULL
+  AND p.account NOT LIKE '@%'
+  AND p.id IN (l.id - 1, l.id + 1)
+  AND p.amount = -l.amount
+  AND p.kind = l.kind
+  AND p.transaction_id IS NOT DISTINCT FROM l.transaction_id;
+
+-- Проводки удалённых кошельков — в валюте их транзакции, остальные — в валюте по умолчанию
+UPDATE {schema}.ledger_entries l
+SET currency = t.currency
+FROM {schema}.transactions t
+WHERE l.currency IS NULL AND t.id = l.transaction_id;
+
+UPDATE {schema}.ledger_entries
+SET currency = 'USD'
+WHERE currency IS NULL;
+
+ALTER TABLE {schema}.ledger_entries
+    ALTER COLUMN currency SET NOT NULL;
@@ -1 +0,0 @@
-CREATE INDEX IF NOT EXISTS idx_created_at ON "TransactionSystem".transactions (created_at DESC);
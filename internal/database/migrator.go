@@ -0,0 +1,424 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Файлы миграций называются NNNN_name.up.sql и NNNN_name.down.sql,
+// где NNNN — номер версии. Для каждой версии обязательны оба файла.
+// {schema} в тексте миграции заменяется экранированным именем схемы.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLockKey — ключ pg_advisory_lock, под которым выполняются миграции,
+// чтобы несколько реплик не мигрировали базу одновременно
+const migrationLockKey int64 = 7_305_118_274_366_101
+
+var ErrChecksumMismatch = errors.New("applied migration differs from the embedded one")
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus — состояние миграции в базе данных
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Dirty — применённая миграция отличается от встроенной или неизвестна бинарному файлу
+	Dirty bool
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator применяет и откатывает встроенные миграции, записывая применённые
+// версии в таблицу schema_migrations
+type Migrator struct {
+	db         *pgxpool.Pool
+	schema     string
+	migrations []Migration
+}
+
+// NewMigrator создаёт Migrator для таблиц в схеме schema
+func NewMigrator(db *pgxpool.Pool, schema string) (*Migrator, error) {
+	migrations, err := LoadMigrations(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, schema: pgx.Identifier{schema}.Sanitize(), migrations: migrations}, nil
+}
+
+// LoadMigrations читает встроенные миграции для схемы schema, упорядоченные по версии.
+// Контрольная сумма считается по тексту с подставленной схемой.
+func LoadMigrations(schema string) ([]Migration, error) {
+	replacer := strings.NewReplacer("{schema}", pgx.Identifier{schema}.Sanitize())
+	return readMigrations(migrationFiles, "migrations", replacer.Replace)
+}
+
+// readMigrations читает пары файлов миграций из каталога dir; render подготавливает текст миграции
+func readMigrations(files fs.ReadFileFS, dir string, render func(string) string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		raw, err := files.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		content := render(string(raw))
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has different names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = content
+			sum := sha256.Sum256([]byte(content))
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = content
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up применяет все ещё не применённые миграции
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.LatestVersion())
+}
+
+// Down откатывает последнюю применённую миграцию
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		current := latestApplied(applied)
+		if current == 0 {
+			slog.InfoContext(ctx, "no migrations to roll back")
+			return nil
+		}
+
+		migration := findMigration(m.migrations, current)
+		if migration == nil {
+			return fmt.Errorf("applied migration %d is unknown to this binary", current)
+		}
+		return m.rollback(ctx, conn, *migration)
+	})
+}
+
+// To применяет или откатывает миграции так, чтобы последней применённой была version.
+// version 0 откатывает все миграции.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && findMigration(m.migrations, version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		rollbacks, applies, err := planMigrations(m.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range rollbacks {
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+		}
+		for _, migration := range applies {
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status возвращает состояние всех известных и применённых миграций
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error {
+		statuses = migrationStatuses(m.migrations, applied)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// CurrentVersion возвращает последнюю применённую версию. В отличие от Status она
+// не берёт блокировку и не создаёт schema_migrations, поэтому годится для частых проверок.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := m.db.QueryRow(ctx, m.render(`SELECT COALESCE(MAX(version), 0) FROM {schema}.schema_migrations`)).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return version, nil
+}
+
+// withLock выполняет fn под advisory-блокировкой на выделенном соединении
+// и передаёт ей уже применённые миграции
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]appliedMigration) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	_, err = conn.Exec(ctx, m.render(`
+		CREATE SCHEMA IF NOT EXISTS {schema};
+		CREATE TABLE IF NOT EXISTS {schema}.schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		)`))
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := m.readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+func (m *Migrator) readApplied(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, m.render(`SELECT version, name, checksum, applied_at FROM {schema}.schema_migrations`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	err := m.inTx(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			m.render(`INSERT INTO {schema}.schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`),
+			migration.Version, migration.Name, migration.Checksum,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	slog.InfoContext(ctx, "applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	err := m.inTx(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, m.render(`DELETE FROM {schema}.schema_migrations WHERE version = $1`), migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	slog.InfoContext(ctx, "rolled back migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *pgxpool.Conn, fn func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) render(sql string) string {
+	return strings.ReplaceAll(sql, "{schema}", m.schema)
+}
+
+// LatestVersion возвращает версию последней встроенной миграции — ту, которую ожидает бинарный файл
+func (m *Migrator) LatestVersion() int64 {
+	return latestVersion(m.migrations)
+}
+
+func findMigration(migrations []Migration, version int64) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+func latestVersion(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func latestApplied(applied map[int64]appliedMigration) int64 {
+	var current int64
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
+
+// verifyApplied проверяет, что применённые миграции совпадают со встроенными
+func verifyApplied(migrations []Migration, applied map[int64]appliedMigration) error {
+	for version, a := range applied {
+		migration := findMigration(migrations, version)
+		if migration == nil {
+			return fmt.Errorf("applied migration %d (%s) is unknown to this binary", version, a.name)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// planMigrations возвращает миграции, которые нужно откатить (от новых к старым) и применить,
+// чтобы последней применённой стала version
+func planMigrations(migrations []Migration, applied map[int64]appliedMigration, version int64) (rollbacks, applies []Migration, err error) {
+	if err := verifyApplied(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; ok && migrations[i].Version > version {
+			rollbacks = append(rollbacks, migrations[i])
+		}
+	}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			applies = append(applies, migration)
+		}
+	}
+
+	return rollbacks, applies, nil
+}
+
+// migrationStatuses сопоставляет встроенные миграции с применёнными
+func migrationStatuses(migrations []Migration, applied map[int64]appliedMigration) []MigrationStatus {
+	var statuses []MigrationStatus
+
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &a.appliedAt
+			status.Dirty = a.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Версии, применённые более новым бинарным файлом
+	for version, a := range applied {
+		if findMigration(migrations, version) != nil {
+			continue
+		}
+		a := a
+		statuses = append(statuses, MigrationStatus{
+			Version: version, Name: a.name, Applied: true, AppliedAt: &a.appliedAt, Dirty: true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses
+}
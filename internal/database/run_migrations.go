@@ -2,35 +2,15 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
-	"log"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-func RunMigrations(ctx context.Context, conn *pgxpool.Pool) error {
-	// Читаем SQL файлы миграций
-	files := []string{
-		"internal/database/migrations/create_wallets.sql",
-		"internal/database/migrations/create_transactions.sql",
-		"internal/database/migrations/create_idx_created_at.sql",
+// RunMigrations применяет в схеме schema все ещё не применённые миграции, встроенные в бинарный файл
+func RunMigrations(ctx context.Context, conn *pgxpool.Pool, schema string) error {
+	migrator, err := NewMigrator(conn, schema)
+	if err != nil {
+		return err
 	}
-	for _, file := range files {
-		// Читаем содержимое файла
-		sqlContent, err := ioutil.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read SQL file %s: %w", file, err)
-		}
-
-		// Выполняем SQL запрос
-		_, err = conn.Exec(ctx, string(sqlContent)) // Передаем ctx
-		if err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", file, err)
-		}
-
-		log.Printf("Successfully executed migration: %s", file)
-	}
-
-	return nil
+	return migrator.Up(ctx)
 }
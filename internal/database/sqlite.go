@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"TransactionSystem/config"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteBusyTimeout — сколько миллисекунд соединение ждёт блокировку базы, занятую другой транзакцией
+const sqliteBusyTimeout = 5000
+
+// InitSQLite открывает файл базы SQLite из database.path. Транзакции начинаются
+// с BEGIN IMMEDIATE: SQLite допускает одного писателя, и блокировка на запись берётся
+// сразу, а не при первом изменении, когда её уже нельзя дождаться без взаимной блокировки.
+func InitSQLite(cfg *config.Config) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Database.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+
+	// Писатель в SQLite один, поэтому соединение тоже одно: запросы ждут его в очереди пула,
+	// а не опрашивают занятую базу. busy_timeout остаётся для других процессов, например migrate.
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+
+	slog.Info("opened sqlite database", "path", cfg.Database.Path)
+	return db, nil
+}
@@ -0,0 +1,6 @@
+-- Суммы хранятся целым числом минимальных единиц: точного DECIMAL в SQLite нет
+CREATE TABLE IF NOT EXISTS wallets (
+    address TEXT PRIMARY KEY,
+    balance INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
@@ -0,0 +1,10 @@
+-- AUTOINCREMENT, как и SERIAL, не выдаёт повторно идентификаторы удалённых транзакций
+CREATE TABLE IF NOT EXISTS transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_wallet TEXT NOT NULL,
+    to_wallet TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    CONSTRAINT fk_from FOREIGN KEY (from_wallet) REFERENCES wallets(address),
+    CONSTRAINT fk_to FOREIGN KEY (to_wallet) REFERENCES wallets(address)
+);
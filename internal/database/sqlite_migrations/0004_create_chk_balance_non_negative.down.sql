@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS chk_balance_non_negative_update;
+DROP TRIGGER IF EXISTS chk_balance_non_negative_insert;
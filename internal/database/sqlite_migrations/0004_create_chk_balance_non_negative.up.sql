@@ -0,0 +1,12 @@
+-- SQLite не добавляет CHECK к существующей таблице, поэтому ограничение реализовано триггерами
+CREATE TRIGGER IF NOT EXISTS chk_balance_non_negative_insert
+BEFORE INSERT ON wallets WHEN NEW.balance < 0
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: chk_balance_non_negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_balance_non_negative_update
+BEFORE UPDATE OF balance ON wallets WHEN NEW.balance < 0
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: chk_balance_non_negative');
+END;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_from_wallet_created_at;
+DROP INDEX IF EXISTS idx_to_wallet_created_at;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_from_wallet_created_at ON transactions (from_wallet, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_to_wallet_created_at ON transactions (to_wallet, created_at DESC, id DESC);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS transactions_reversal_set_null;
+DROP INDEX IF EXISTS idx_transactions_reversal_of;
+
+ALTER TABLE transactions DROP COLUMN reason;
+ALTER TABLE transactions DROP COLUMN reversed_by;
+ALTER TABLE transactions DROP COLUMN reversal_of;
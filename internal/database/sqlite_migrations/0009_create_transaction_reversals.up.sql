@@ -0,0 +1,16 @@
+-- Столбец с внешним ключом нельзя удалить через DROP COLUMN, поэтому ссылки между транзакциями
+-- не объявлены внешними ключами, а ON DELETE SET NULL выполняет триггер
+ALTER TABLE transactions ADD COLUMN reversal_of INTEGER;
+ALTER TABLE transactions ADD COLUMN reversed_by INTEGER;
+ALTER TABLE transactions ADD COLUMN reason TEXT;
+
+-- Транзакцию можно сторнировать не более одного раза
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversal_of
+    ON transactions (reversal_of) WHERE reversal_of IS NOT NULL;
+
+CREATE TRIGGER IF NOT EXISTS transactions_reversal_set_null
+AFTER DELETE ON transactions
+BEGIN
+    UPDATE transactions SET reversal_of = NULL WHERE reversal_of = OLD.id;
+    UPDATE transactions SET reversed_by = NULL WHERE reversed_by = OLD.id;
+END;
@@ -0,0 +1,3 @@
+ALTER TABLE wallets
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CONSTRAINT chk_wallet_status CHECK (status IN ('active', 'frozen', 'closed'));
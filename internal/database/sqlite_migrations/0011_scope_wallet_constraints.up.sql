@@ -0,0 +1,3 @@
+-- В PostgreSQL миграция восстанавливает ограничения, пропущенные из-за других схем той же базы.
+-- В SQLite схем нет, версия сохранена, чтобы номера миграций совпадали.
+SELECT 1;
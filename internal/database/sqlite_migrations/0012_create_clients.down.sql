@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_wallets_owner_id;
+
+ALTER TABLE wallets DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS clients;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS clients (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    admin BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+-- Хранится только SHA-256 ключа; prefix — открытая часть ключа для поиска записи
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_id INTEGER NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_client_id ON api_keys (client_id);
+
+-- Как и в 0009, внешний ключ не объявлен, чтобы столбец можно было удалить при откате
+ALTER TABLE wallets ADD COLUMN owner_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_wallets_owner_id ON wallets (owner_id);
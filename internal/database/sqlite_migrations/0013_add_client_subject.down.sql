@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_clients_subject;
+
+ALTER TABLE clients DROP COLUMN subject;
@@ -0,0 +1,5 @@
+-- Клиенты, аутентифицированные JWT, находятся по субъекту токена и создаются при первом запросе.
+-- ALTER TABLE в SQLite не добавляет UNIQUE, поэтому уникальность обеспечивает индекс.
+ALTER TABLE clients ADD COLUMN subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clients_subject ON clients (subject);
@@ -0,0 +1,4 @@
+-- Кошелёк хранит средства в одной валюте; существующие кошельки считаются долларовыми (models.DefaultCurrency)
+ALTER TABLE wallets ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE transactions ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
@@ -0,0 +1,5 @@
+ALTER TABLE transactions DROP COLUMN quote_id;
+ALTER TABLE transactions DROP COLUMN residue;
+ALTER TABLE transactions DROP COLUMN to_currency;
+ALTER TABLE transactions DROP COLUMN to_amount;
+ALTER TABLE transactions DROP COLUMN rate;
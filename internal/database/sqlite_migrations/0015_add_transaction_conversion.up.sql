@@ -0,0 +1,7 @@
+-- Перевод с конвертацией: to_amount хранится в минимальных единицах, как amount,
+-- курс и остаток округления — десятичной строкой
+ALTER TABLE transactions ADD COLUMN rate TEXT;
+ALTER TABLE transactions ADD COLUMN to_amount INTEGER;
+ALTER TABLE transactions ADD COLUMN to_currency TEXT;
+ALTER TABLE transactions ADD COLUMN residue TEXT;
+ALTER TABLE transactions ADD COLUMN quote_id TEXT;
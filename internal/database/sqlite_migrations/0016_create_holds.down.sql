@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS holds;
+
+DROP TRIGGER IF EXISTS chk_wallet_held_update;
+ALTER TABLE wallets DROP COLUMN held;
@@ -0,0 +1,29 @@
+-- Холды резервируют средства кошелька до списания или отмены. Сумма активных холдов
+-- хранится в кошельке (held), чтобы проверка доступного баланса не суммировала холды
+ALTER TABLE wallets ADD COLUMN held INTEGER NOT NULL DEFAULT 0;
+
+-- Как и в 0004, ограничение chk_wallet_held реализовано триггером
+CREATE TRIGGER IF NOT EXISTS chk_wallet_held_update
+BEFORE UPDATE OF balance, held ON wallets WHEN NEW.held < 0 OR NEW.held > NEW.balance
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: chk_wallet_held');
+END;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_wallet TEXT NOT NULL REFERENCES wallets (address),
+    to_wallet TEXT NOT NULL REFERENCES wallets (address),
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT chk_hold_status CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    closed_at TIMESTAMP,
+    captured_amount INTEGER,
+    transaction_id INTEGER REFERENCES transactions (id) ON DELETE SET NULL
+);
+
+-- Поиск истёкших холдов фоновой задачей
+CREATE INDEX IF NOT EXISTS idx_holds_active_expires_at
+    ON holds (expires_at) WHERE status = 'active';
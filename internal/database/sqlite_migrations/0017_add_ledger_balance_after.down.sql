@@ -0,0 +1 @@
+ALTER TABLE ledger_entries DROP COLUMN balance_after;
@@ -0,0 +1,9 @@
+-- Баланс кошелька после каждой проводки; у системных счетов (начинаются с '@') не ведётся
+ALTER TABLE ledger_entries ADD COLUMN balance_after INTEGER;
+
+UPDATE ledger_entries
+SET balance_after = (
+    SELECT SUM(p.amount) FROM ledger_entries p
+    WHERE p.account = ledger_entries.account AND p.id <= ledger_entries.id
+)
+WHERE account NOT LIKE '@%';
@@ -0,0 +1,29 @@
+-- Валюта проводки; журнал должен сходиться к нулю по каждой валюте отдельно.
+-- Заполняется так же, как в PostgreSQL: валюта кошелька, затем валюта кошелька из пары
+-- проводок, затем валюта транзакции, остальное — валюта по умолчанию.
+ALTER TABLE ledger_entries ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+
+UPDATE ledger_entries
+SET currency = COALESCE((
+    SELECT w.currency FROM wallets w WHERE w.address = ledger_entries.account
+), '')
+WHERE currency = '';
+
+UPDATE ledger_entries
+SET currency = COALESCE((
+    SELECT p.currency FROM ledger_entries p
+    WHERE p.currency <> ''
+      AND p.account NOT LIKE '@%'
+      AND p.id IN (ledger_entries.id - 1, ledger_entries.id + 1)
+      AND p.amount = -ledger_entries.amount
+      AND p.kind = ledger_entries.kind
+      AND p.transaction_id IS ledger_entries.transaction_id
+    LIMIT 1
+), '')
+WHERE currency = '';
+
+UPDATE ledger_entries
+SET currency = COALESCE((
+    SELECT t.currency FROM transactions t WHERE t.id = ledger_entries.transaction_id
+), 'USD')
+WHERE currency = '';
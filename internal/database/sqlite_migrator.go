@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Миграции SQLite повторяют миграции PostgreSQL под теми же номерами и именами,
+// поэтому обе базы сообщают одну и ту же версию схемы
+//
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrationFiles embed.FS
+
+// sqliteTimeFormat — формат времени в SQLite; фиксированная ширина сохраняет порядок при сравнении строк
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// SQLiteMigrator применяет и откатывает встроенные миграции SQLite. Каждая операция
+// выполняется в одной транзакции: DDL в SQLite транзакционен, а блокировка базы на запись
+// не даёт нескольким процессам мигрировать одновременно.
+type SQLiteMigrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewSQLiteMigrator(db *sql.DB) (*SQLiteMigrator, error) {
+	migrations, err := LoadSQLiteMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteMigrator{db: db, migrations: migrations}, nil
+}
+
+// LoadSQLiteMigrations читает встроенные миграции SQLite, упорядоченные по версии
+func LoadSQLiteMigrations() ([]Migration, error) {
+	return readMigrations(sqliteMigrationFiles, "sqlite_migrations", func(s string) string { return s })
+}
+
+// Up применяет все ещё не применённые миграции
+func (m *SQLiteMigrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.LatestVersion())
+}
+
+// Down откатывает последнюю применённую миграцию
+func (m *SQLiteMigrator) Down(ctx context.Context) error {
+	return m.inTx(ctx, func(tx *sql.Tx, applied map[int64]appliedMigration) error {
+		current := latestApplied(applied)
+		if current == 0 {
+			slog.InfoContext(ctx, "no migrations to roll back")
+			return nil
+		}
+
+		migration := findMigration(m.migrations, current)
+		if migration == nil {
+			return fmt.Errorf("applied migration %d is unknown to this binary", current)
+		}
+		return m.rollback(ctx, tx, *migration)
+	})
+}
+
+// To применяет или откатывает миграции так, чтобы последней применённой была version.
+// version 0 откатывает все миграции.
+func (m *SQLiteMigrator) To(ctx context.Context, version int64) error {
+	if version != 0 && findMigration(m.migrations, version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.inTx(ctx, func(tx *sql.Tx, applied map[int64]appliedMigration) error {
+		rollbacks, applies, err := planMigrations(m.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range rollbacks {
+			if err := m.rollback(ctx, tx, migration); err != nil {
+				return err
+			}
+		}
+		for _, migration := range applies {
+			if err := m.apply(ctx, tx, migration); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status возвращает состояние всех известных и применённых миграций
+func (m *SQLiteMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.inTx(ctx, func(tx *sql.Tx, applied map[int64]appliedMigration) error {
+		statuses = migrationStatuses(m.migrations, applied)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// CurrentVersion возвращает последнюю применённую версию, не блокируя базу
+func (m *SQLiteMigrator) CurrentVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return version, nil
+}
+
+// LatestVersion возвращает версию последней встроенной миграции — ту, которую ожидает бинарный файл
+func (m *SQLiteMigrator) LatestVersion() int64 {
+	return latestVersion(m.migrations)
+}
+
+// inTx выполняет fn в транзакции и передаёт ей уже применённые миграции
+func (m *SQLiteMigrator) inTx(ctx context.Context, fn func(tx *sql.Tx, applied map[int64]appliedMigration) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := m.readApplied(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx, applied); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SQLiteMigrator) readApplied(ctx context.Context, tx *sql.Tx) (map[int64]appliedMigration, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (m *SQLiteMigrator) apply(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		migration.Version, migration.Name, migration.Checksum, time.Now().UTC().Format(sqliteTimeFormat),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	slog.InfoContext(ctx, "applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+func (m *SQLiteMigrator) rollback(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to roll back migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	slog.InfoContext(ctx, "rolled back migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package fx содержит поставщиков курсов обмена для переводов с конвертацией:
+// StaticRates с таблицей курсов из файла и ManualRates с курсами, которые задаёт администратор.
+package fx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"TransactionSystem/internal/models"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Pair — направление обмена: курс Pair{From, To} — цена единицы From в единицах To
+type Pair struct {
+	From models.Currency
+	To   models.Currency
+}
+
+func (p Pair) String() string {
+	return string(p.From) + "/" + string(p.To)
+}
+
+// LoadRatesFile читает таблицу курсов из YAML-файла вида
+//
+//	USD:
+//	  EUR: 0.92
+//	  JPY: 151.30
+//
+// Обратный курс не вычисляется: EUR→USD должен быть указан отдельно.
+func LoadRatesFile(path string) (map[Pair]models.Rate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	// Курсы читаются строками, чтобы не терять точность на float64
+	var table map[string]map[string]string
+	if err := yaml.UnmarshalStrict(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file %s: %w", path, err)
+	}
+
+	rates := make(map[Pair]models.Rate)
+	for from, row := range table {
+		for to, value := range row {
+			pair := Pair{models.Currency(from), models.Currency(to)}
+			if pair.From == pair.To {
+				return nil, fmt.Errorf("rates file %s: %s: currencies must differ", path, pair)
+			}
+			rate, err := models.ParseRate(value)
+			if err != nil {
+				return nil, fmt.Errorf("rates file %s: %s: %w", path, pair, err)
+			}
+			rates[pair] = rate
+		}
+	}
+
+	return rates, nil
+}
+
+func rateNotFound(from, to models.Currency) error {
+	return fmt.Errorf("%w: %s", models.ErrRateNotFound, Pair{from, to})
+}
+
+// StaticRates — неизменная таблица курсов
+type StaticRates struct {
+	rates map[Pair]models.Rate
+}
+
+func NewStaticRates(rates map[Pair]models.Rate) *StaticRates {
+	copied := make(map[Pair]models.Rate, len(rates))
+	for pair, rate := range rates {
+		copied[pair] = rate
+	}
+	return &StaticRates{rates: copied}
+}
+
+// LoadStaticRates создаёт таблицу курсов из файла в формате LoadRatesFile
+func LoadStaticRates(path string) (*StaticRates, error) {
+	rates, err := LoadRatesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticRates{rates: rates}, nil
+}
+
+func (s *StaticRates) Rate(ctx context.Context, from, to models.Currency) (models.Rate, error) {
+	rate, ok := s.rates[Pair{from, to}]
+	if !ok {
+		return models.Rate{}, rateNotFound(from, to)
+	}
+	return rate, nil
+}
+
+// ManualRates — курсы, которые задаёт администратор. Хранятся в памяти процесса
+// и после перезапуска возвращаются к начальной таблице.
+type ManualRates struct {
+	mu    sync.RWMutex
+	rates map[Pair]models.Rate
+}
+
+// NewManualRates создаёт курсы с начальной таблицей initial (может быть nil)
+func NewManualRates(initial map[Pair]models.Rate) *ManualRates {
+	return &ManualRates{rates: NewStaticRates(initial).rates}
+}
+
+func (m *ManualRates) Rate(ctx context.Context, from, to models.Currency) (models.Rate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rate, ok := m.rates[Pair{from, to}]
+	if !ok {
+		return models.Rate{}, rateNotFound(from, to)
+	}
+	return rate, nil
+}
+
+// SetRate устанавливает курс from→to; обратный курс не меняется
+func (m *ManualRates) SetRate(ctx context.Context, from, to models.Currency, rate models.Rate) error {
+	if rate.IsZero() {
+		return fmt.Errorf("%w: rate must be positive", models.ErrInvalidRate)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rates[Pair{from, to}] = rate
+	return nil
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "transaction_system"
+
+// collectTimeout ограничивает запросы к базе при сборе метрик
+const collectTimeout = 2 * time.Second
+
+// PoolStatsSource — источник статистики пула соединений, например *repository.DB
+type PoolStatsSource interface {
+	Stats() models.PoolStats
+}
+
+// WalletCounter считает кошельки по состояниям, например *service.WalletService
+type WalletCounter interface {
+	CountWallets(ctx context.Context) (map[models.WalletStatus]int64, error)
+}
+
+// Metrics хранит собственный реестр Prometheus, поэтому несколько экземпляров
+// (например, в тестах) не конфликтуют друг с другом
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	transfersSucceeded prometheus.Counter
+	transfersFailed    *prometheus.CounterVec
+	transferredVolume  *prometheus.CounterVec
+}
+
+// New регистрирует метрики сервиса. pool и wallets опрашиваются при каждом сборе метрик,
+// любой из них может быть nil.
+func New(pool PoolStatsSource, wallets WalletCounter) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		transfersSucceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_succeeded_total",
+			Help:      "Transfers completed by SendMoney.",
+		}),
+		transfersFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_failed_total",
+			Help:      "Transfers rejected or failed in SendMoney by reason.",
+		}, []string{"reason"}),
+		transferredVolume: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transferred_amount_total",
+			Help:      "Total amount debited by successful transfers, in units of the sender's currency.",
+		}, []string{"currency"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.transfersSucceeded,
+		m.transfersFailed,
+		m.transferredVolume,
+	)
+	if pool != nil {
+		m.registry.MustRegister(newPoolCollector(pool))
+	}
+	if wallets != nil {
+		m.registry.MustRegister(newWalletCollector(wallets))
+	}
+
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware учитывает запросы по шаблону маршрута mux, а не по фактическому пути,
+// чтобы адреса кошельков и id транзакций не раздували число рядов
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := NewStatusWriter(w)
+
+		next.ServeHTTP(sw, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(sw.Status())}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// TransferSucceeded реализует service.TransferMetrics. Суммы в разных валютах
+// не складываются: каждая валюта — отдельный ряд.
+func (m *Metrics) TransferSucceeded(amount models.Amount, currency models.Currency) {
+	m.transfersSucceeded.Inc()
+	m.transferredVolume.WithLabelValues(string(currency)).Add(float64(amount.Minor()) / math.Pow10(models.AmountScale))
+}
+
+// TransferFailed реализует service.TransferMetrics
+func (m *Metrics) TransferFailed(reason string) {
+	m.transfersFailed.WithLabelValues(reason).Inc()
+}
+
+// StatusWriter запоминает код ответа обработчика; им пользуются и Middleware,
+// и журнал запросов в пакете api
+type StatusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status возвращает отправленный код ответа; 200, если обработчик не вызвал WriteHeader
+func (sw *StatusWriter) Status() int {
+	return sw.status
+}
+
+func (sw *StatusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *StatusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// poolCollector снимает состояние пула соединений в момент сбора метрик
+type poolCollector struct {
+	source   PoolStatsSource
+	acquired *prometheus.Desc
+	idle     *prometheus.Desc
+	total    *prometheus.Desc
+	max      *prometheus.Desc
+}
+
+func newPoolCollector(source PoolStatsSource) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		source:   source,
+		acquired: desc("acquired_conns", "Connections currently acquired from the pool."),
+		idle:     desc("idle_conns", "Idle connections in the pool."),
+		total:    desc("total_conns", "Total connections in the pool."),
+		max:      desc("max_conns", "Maximum size of the pool."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stats.AcquiredConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stats.MaxConns))
+}
+
+// walletCollector считает кошельки по состояниям в момент сбора метрик.
+// Если база недоступна, метрика пропускается, а не роняет весь ответ /metrics.
+type walletCollector struct {
+	counter WalletCounter
+	wallets *prometheus.Desc
+}
+
+func newWalletCollector(counter WalletCounter) *walletCollector {
+	return &walletCollector{
+		counter: counter,
+		wallets: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "wallets"),
+			"Wallets by status.", []string{"status"}, nil),
+	}
+}
+
+func (c *walletCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.wallets
+}
+
+func (c *walletCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountWallets(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "metrics: failed to count wallets", "error", err)
+		return
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.wallets, prometheus.GaugeValue, float64(count), string(status))
+	}
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AmountScale — количество знаков после запятой, совпадает с DECIMAL(18, 2) в БД
+const AmountScale = 2
+
+// amountPrecision — общее количество значащих цифр DECIMAL(18, 2)
+const amountPrecision = 18
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount хранит денежную сумму в минимальных единицах (сотых долях),
+// поэтому сложение и вычитание выполняются точно, без ошибок float64.
+type Amount struct {
+	minor int64
+}
+
+// NewAmount создаёт сумму из минимальных единиц: NewAmount(150) == 1.50
+func NewAmount(minor int64) Amount {
+	return Amount{minor: minor}
+}
+
+// AmountFromUnits создаёт сумму из целых единиц: AmountFromUnits(100) == 100.00
+func AmountFromUnits(units int64) Amount {
+	return Amount{minor: units * pow10(AmountScale)}
+}
+
+// ParseAmount разбирает десятичную запись суммы ("100", "100.5", "-0.25").
+// Суммы с количеством знаков после запятой больше AmountScale отклоняются.
+func ParseAmount(s string) (Amount, error) {
+	return parseAmount(s, true)
+}
+
+// MustParseAmount аналогичен ParseAmount, но паникует при ошибке
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func parseAmount(s string, strict bool) (Amount, error) {
+	str := s
+	negative := false
+	if strings.HasPrefix(str, "-") {
+		negative = true
+		str = str[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(str, ".")
+	if intPart == "" || (hasDot && fracPart == "") {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	if len(fracPart) > AmountScale {
+		// Значения из БД могут приходить с незначащими нулями, их допускаем
+		extra := fracPart[AmountScale:]
+		if strict || strings.Trim(extra, "0") != "" {
+			return Amount{}, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, s, AmountScale)
+		}
+		fracPart = fracPart[:AmountScale]
+	}
+	fracPart += strings.Repeat("0", AmountScale-len(fracPart))
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > amountPrecision-AmountScale {
+		return Amount{}, fmt.Errorf("%w: %q exceeds %d digits", ErrInvalidAmount, s, amountPrecision)
+	}
+
+	minor, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Amount{minor: minor}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// Minor возвращает сумму в минимальных единицах
+func (a Amount) Minor() int64 {
+	return a.minor
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{minor: a.minor + b.minor}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{minor: a.minor - b.minor}
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{minor: -a.minor}
+}
+
+// Cmp возвращает -1, 0 или 1, если a меньше, равна или больше b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.minor < b.minor:
+		return -1
+	case a.minor > b.minor:
+		return 1
+	}
+	return 0
+}
+
+// Sign возвращает -1, 0 или 1 в зависимости от знака суммы
+func (a Amount) Sign() int {
+	return a.Cmp(Amount{})
+}
+
+func (a Amount) IsZero() bool {
+	return a.minor == 0
+}
+
+func (a Amount) String() string {
+	minor := a.minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	scale := pow10(AmountScale)
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, AmountScale, minor%scale)
+}
+
+// MarshalJSON кодирует сумму как JSON-число с фиксированным числом знаков
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает JSON-число или строку с десятичной записью суммы
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan реализует sql.Scanner, pgx передаёт NUMERIC в текстовом виде
+func (a *Amount) Scan(src interface{}) error {
+	var (
+		parsed Amount
+		err    error
+	)
+
+	switch v := src.(type) {
+	case string:
+		parsed, err = parseAmount(v, false)
+	case []byte:
+		parsed, err = parseAmount(string(v), false)
+	case int64:
+		parsed = AmountFromUnits(v)
+	case nil:
+		return fmt.Errorf("%w: cannot scan NULL", ErrInvalidAmount)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+	return nil
+}
+
+// Value реализует driver.Valuer, сумма передаётся в БД как десятичная строка
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Client — владелец кошельков и API-ключей. Admin-клиент имеет доступ ко всем кошелькам.
+type Client struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+	// Subject — субъект JWT, если клиент создан при аутентификации токеном
+	Subject   *string   `json:"subject,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKey — выданный клиенту ключ. Сам ключ не хранится, только его хэш.
+type APIKey struct {
+	Id        int64      `json:"id"`
+	ClientId  int64      `json:"client_id"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssuedAPIKey — только что выданный ключ; Key показывается клиенту один раз
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
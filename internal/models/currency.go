@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Currency — код валюты: ISO 4217 или собственный код, зарегистрированный в конфигурации
+type Currency string
+
+// DefaultCurrency — валюта кошельков, созданных без указания валюты, и кошельков,
+// существовавших до появления валют
+const DefaultCurrency Currency = "USD"
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// ErrUnsupportedCurrency — валюта ISO 4217 с большим числом знаков после запятой, чем AmountScale
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// ErrAmountScale — у суммы больше знаков после запятой, чем допускает валюта
+var ErrAmountScale = errors.New("amount has more decimal places than the currency allows")
+
+// currencyCode — допустимый код валюты: заглавные латинские буквы и цифры, от 3 до 8 символов
+var currencyCode = regexp.MustCompile(`^[A-Z][A-Z0-9]{2,7}$`)
+
+// isoCurrencies — действующие валюты ISO 4217 и число знаков после запятой
+var isoCurrencies = map[Currency]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2, "ARS": 2, "AUD": 2, "AWG": 2,
+	"AZN": 2, "BAM": 2, "BBD": 2, "BDT": 2, "BGN": 2, "BMD": 2, "BND": 2, "BOB": 2, "BOV": 2,
+	"BRL": 2, "BSD": 2, "BTN": 2, "BWP": 2, "BYN": 2, "BZD": 2, "CAD": 2, "CDF": 2, "CHE": 2,
+	"CHF": 2, "CHW": 2, "CNY": 2, "COP": 2, "COU": 2, "CRC": 2, "CUP": 2, "CVE": 2, "CZK": 2,
+	"DKK": 2, "DOP": 2, "DZD": 2, "EGP": 2, "ERN": 2, "ETB": 2, "EUR": 2, "FJD": 2, "FKP": 2,
+	"GBP": 2, "GEL": 2, "GHS": 2, "GIP": 2, "GMD": 2, "GTQ": 2, "GYD": 2, "HKD": 2, "HNL": 2,
+	"HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IRR": 2, "JMD": 2, "KES": 2, "KGS": 2,
+	"KHR": 2, "KPW": 2, "KYD": 2, "KZT": 2, "LAK": 2, "LBP": 2, "LKR": 2, "LRD": 2, "LSL": 2,
+	"MAD": 2, "MDL": 2, "MGA": 2, "MKD": 2, "MMK": 2, "MNT": 2, "MOP": 2, "MRU": 2, "MUR": 2,
+	"MVR": 2, "MWK": 2, "MXN": 2, "MXV": 2, "MYR": 2, "MZN": 2, "NAD": 2, "NGN": 2, "NIO": 2,
+	"NOK": 2, "NPR": 2, "NZD": 2, "PAB": 2, "PEN": 2, "PGK": 2, "PHP": 2, "PKR": 2, "PLN": 2,
+	"QAR": 2, "RON": 2, "RSD": 2, "RUB": 2, "SAR": 2, "SBD": 2, "SCR": 2, "SDG": 2, "SEK": 2,
+	"SGD": 2, "SHP": 2, "SLE": 2, "SOS": 2, "SRD": 2, "SSP": 2, "STN": 2, "SVC": 2, "SYP": 2,
+	"SZL": 2, "THB": 2, "TJS": 2, "TMT": 2, "TOP": 2, "TRY": 2, "TTD": 2, "TWD": 2, "TZS": 2,
+	"UAH": 2, "USD": 2, "USN": 2, "UYU": 2, "UZS": 2, "VED": 2, "VES": 2, "WST": 2, "XCD": 2,
+	"YER": 2, "ZAR": 2, "ZMW": 2, "ZWL": 2,
+}
+
+// unsupportedISOCurrencies — валюты ISO 4217, минимальная единица которых меньше 10^-AmountScale.
+// Суммы хранятся с точностью AmountScale (DECIMAL(18, 2) в БД), поэтому кошельки и переводы
+// в этих валютах отклоняются с ErrUnsupportedCurrency, а не округляются.
+var unsupportedISOCurrencies = map[Currency]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Currencies — реестр валют, с которыми работает сервис: ISO 4217 и собственные коды
+type Currencies struct {
+	scales map[Currency]int
+}
+
+// ISOCurrencies возвращает реестр только из валют ISO 4217
+func ISOCurrencies() *Currencies {
+	c, _ := NewCurrencies(nil)
+	return c
+}
+
+// NewCurrencies возвращает реестр из валют ISO 4217 и собственных кодов custom
+// с числом знаков после запятой. Собственный код не может совпадать с кодом ISO 4217.
+func NewCurrencies(custom map[string]int) (*Currencies, error) {
+	scales := make(map[Currency]int, len(isoCurrencies)+len(custom))
+	for code, scale := range isoCurrencies {
+		scales[code] = scale
+	}
+
+	// Коды перебираются по порядку, чтобы ошибка не зависела от порядка обхода map
+	codes := make([]string, 0, len(custom))
+	for code := range custom {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		scale := custom[code]
+		if !currencyCode.MatchString(code) {
+			return nil, fmt.Errorf("currency code %q must be 3 to 8 uppercase letters or digits starting with a letter", code)
+		}
+		if _, ok := isoCurrencies[Currency(code)]; ok {
+			return nil, fmt.Errorf("currency code %q is already defined by ISO 4217", code)
+		}
+		if isoScale, ok := unsupportedISOCurrencies[Currency(code)]; ok {
+			return nil, fmt.Errorf("currency code %q is defined by ISO 4217 with %d decimal places, amounts are stored with at most %d", code, isoScale, AmountScale)
+		}
+		if scale < 0 || scale > AmountScale {
+			return nil, fmt.Errorf("currency %s: scale must be between 0 and %d (amounts are stored with %d decimal places), got %d", code, AmountScale, AmountScale, scale)
+		}
+		scales[Currency(code)] = scale
+	}
+
+	return &Currencies{scales: scales}, nil
+}
+
+// Scale возвращает число знаков после запятой валюты code
+func (c *Currencies) Scale(code Currency) (int, error) {
+	scale, ok := c.scales[code]
+	if !ok {
+		if isoScale, ok := unsupportedISOCurrencies[code]; ok {
+			return 0, fmt.Errorf("%w: %s has %d decimal places, amounts are stored with %d", ErrUnsupportedCurrency, code, isoScale, AmountScale)
+		}
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return scale, nil
+}
+
+// CheckAmount проверяет, что сумма a записывается в валюте code без дробных минимальных единиц:
+// для JPY допустимо 100, но не 100.50
+func (c *Currencies) CheckAmount(code Currency, a Amount) error {
+	scale, err := c.Scale(code)
+	if err != nil {
+		return err
+	}
+	if a.Minor()%pow10(AmountScale-scale) != 0 {
+		return fmt.Errorf("%w: %s %s allows %d", ErrAmountScale, a, code, scale)
+	}
+	return nil
+}
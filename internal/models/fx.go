@@ -0,0 +1,226 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FXAccount — системный счёт журнала, через который проходит перевод с конвертацией:
+// он получает списанное с отправителя и выдаёт зачисленное получателю. Каждая часть перевода
+// проводится в своей валюте, поэтому счёт сходится с остальным журналом по каждой валюте отдельно.
+const FXAccount = "@fx"
+
+// RateScale — количество знаков после запятой у курса, совпадает с NUMERIC(20, 8) в БД
+const RateScale = 8
+
+// ResidueScale — количество знаков после запятой у остатка округления:
+// произведение суммы на курс точно записывается с AmountScale+RateScale знаками
+const ResidueScale = AmountScale + RateScale
+
+var (
+	ErrInvalidRate  = errors.New("invalid exchange rate")
+	ErrRateNotFound = errors.New("exchange rate not found")
+)
+
+// Rate — курс обмена: сколько единиц валюты назначения стоит единица исходной валюты.
+// Хранится целым числом 10^-RateScale долей, как Amount.
+type Rate struct {
+	v int64
+}
+
+// ParseRate разбирает положительный курс в десятичной записи ("0.92", "150.5")
+func ParseRate(s string) (Rate, error) {
+	v, err := parseDecimal(s, RateScale, true)
+	if err != nil || v <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q must be a positive number with at most %d decimal places", ErrInvalidRate, s, RateScale)
+	}
+	return Rate{v: v}, nil
+}
+
+// MustParseRate аналогичен ParseRate, но паникует при ошибке
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r Rate) IsZero() bool {
+	return r.v == 0
+}
+
+func (r Rate) String() string {
+	return formatDecimal(r.v, RateScale)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON принимает JSON-число или строку с десятичной записью курса
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan реализует sql.Scanner: NUMERIC из PostgreSQL и TEXT из SQLite приходят строкой
+func (r *Rate) Scan(src interface{}) error {
+	v, err := scanDecimal(src, RateScale)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRate, err)
+	}
+	*r = Rate{v: v}
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Residue — остаток округления конвертации в валюте назначения с точностью ResidueScale
+type Residue struct {
+	v int64
+}
+
+func (r Residue) IsZero() bool {
+	return r.v == 0
+}
+
+func (r Residue) String() string {
+	return formatDecimal(r.v, ResidueScale)
+}
+
+func (r Residue) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Residue) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	v, err := parseDecimal(s, ResidueScale, true)
+	if err != nil {
+		return err
+	}
+	*r = Residue{v: v}
+	return nil
+}
+
+func (r *Residue) Scan(src interface{}) error {
+	v, err := scanDecimal(src, ResidueScale)
+	if err != nil {
+		return err
+	}
+	*r = Residue{v: v}
+	return nil
+}
+
+func (r Residue) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Convert переводит сумму a по курсу rate в валюту с scale знаками после запятой.
+// Результат округляется вниз до минимальной единицы валюты, отброшенная часть
+// возвращается остатком: a * rate == converted + residue.
+func Convert(a Amount, rate Rate, scale int) (Amount, Residue, error) {
+	if scale < 0 || scale > AmountScale {
+		return Amount{}, Residue{}, fmt.Errorf("unsupported currency scale %d", scale)
+	}
+	if a.Sign() < 0 {
+		return Amount{}, Residue{}, fmt.Errorf("%w: cannot convert negative amount %s", ErrInvalidAmount, a)
+	}
+
+	// Точное произведение в единицах 10^-ResidueScale валюты назначения
+	exact := new(big.Int).Mul(big.NewInt(a.minor), big.NewInt(rate.v))
+	step := big.NewInt(pow10(RateScale + AmountScale - scale))
+
+	units, residue := new(big.Int).QuoRem(exact, step, new(big.Int))
+	minor := units.Mul(units, big.NewInt(pow10(AmountScale-scale)))
+	if !minor.IsInt64() || len(minor.String()) > amountPrecision {
+		return Amount{}, Residue{}, fmt.Errorf("%w: %s converted at %s exceeds %d digits", ErrInvalidAmount, a, rate, amountPrecision)
+	}
+
+	return Amount{minor: minor.Int64()}, Residue{v: residue.Int64()}, nil
+}
+
+// Conversion — сведения о конвертации перевода: Amount и Currency транзакции списаны
+// с отправителя, ToAmount в валюте ToCurrency зачислена получателю
+type Conversion struct {
+	Rate       Rate     `json:"rate"`
+	ToAmount   Amount   `json:"to_amount"`
+	ToCurrency Currency `json:"to_currency"`
+	// Residue — отброшенная при округлении часть Amount*Rate, остаётся на счёте FXAccount
+	Residue Residue `json:"residue"`
+	// QuoteId — котировка, курс которой использован; пусто, если курс взят у поставщика
+	QuoteId string `json:"quote_id,omitempty"`
+}
+
+// FXQuote — котировка: курс From→To, зафиксированный до ExpiresAt
+type FXQuote struct {
+	Id        string    `json:"id"`
+	From      Currency  `json:"from_currency"`
+	To        Currency  `json:"to_currency"`
+	Rate      Rate      `json:"rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Owner — id клиента, запросившего котировку; nil у котировок, выданных без аутентификации
+	Owner *int64 `json:"owner_id,omitempty"`
+}
+
+// parseDecimal разбирает неотрицательное десятичное число в целое число 10^-scale долей.
+// strict отклоняет лишние знаки после запятой, иначе допускаются лишние нули.
+func parseDecimal(s string, scale int, strict bool) (int64, error) {
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" || (hasDot && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	if len(fracPart) > scale {
+		if strict || strings.Trim(fracPart[scale:], "0") != "" {
+			return 0, fmt.Errorf("decimal %q has more than %d decimal places", s, scale)
+		}
+		fracPart = fracPart[:scale]
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+
+	v, err := strconv.ParseInt(strings.TrimLeft(intPart, "0")+fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("decimal %q is out of range", s)
+	}
+	return v, nil
+}
+
+func scanDecimal(src interface{}, scale int) (int64, error) {
+	switch v := src.(type) {
+	case string:
+		return parseDecimal(v, scale, false)
+	case []byte:
+		return parseDecimal(string(v), scale, false)
+	default:
+		return 0, fmt.Errorf("cannot scan %T", src)
+	}
+}
+
+func formatDecimal(v int64, scale int) string {
+	p := pow10(scale)
+	return fmt.Sprintf("%d.%0*d", v/p, scale, v%p)
+}
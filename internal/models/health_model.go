@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// PoolStats — снимок состояния пула соединений с базой данных
+type PoolStats struct {
+	TotalConns        int32 `json:"total_conns"`
+	AcquiredConns     int32 `json:"acquired_conns"`
+	IdleConns         int32 `json:"idle_conns"`
+	ConstructingConns int32 `json:"constructing_conns"`
+	MaxConns          int32 `json:"max_conns"`
+	// Число выданных соединений и суммарное время ожидания их выдачи
+	AcquireCount           int64   `json:"acquire_count"`
+	AcquireDurationSeconds float64 `json:"acquire_duration_seconds"`
+	// Сколько раз пришлось ждать освобождения соединения и сколько ожиданий было отменено
+	EmptyAcquireCount    int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+}
+
+// Названия проверок готовности
+const (
+	CheckDatabase   = "database"
+	CheckMigrations = "migrations"
+	CheckShutdown   = "shutdown"
+)
+
+// CheckOK — результат успешной проверки, иначе проверка содержит описание проблемы
+const CheckOK = "ok"
+
+// Readiness — результат проверки готовности сервиса принимать запросы
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// MigrationVersion — применённая и ожидаемая бинарным файлом версии схемы
+type MigrationVersion struct {
+	Current  int64 `json:"current"`
+	Expected int64 `json:"expected"`
+	// Error заполняется, если текущую версию не удалось прочитать
+	Error string `json:"error,omitempty"`
+}
+
+// SystemStatus — подробное состояние экземпляра сервиса для администратора
+type SystemStatus struct {
+	Version       string           `json:"version"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds float64          `json:"uptime_seconds"`
+	ShuttingDown  bool             `json:"shutting_down"`
+	Migrations    MigrationVersion `json:"migrations"`
+	Pool          PoolStats        `json:"pool"`
+	Readiness     Readiness        `json:"readiness"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// HoldStatus — состояние холда. Средства зарезервированы только у активного холда.
+type HoldStatus string
+
+const (
+	HoldActive HoldStatus = "active"
+	// Холд списан переводом на кошелёк получателя
+	HoldCaptured HoldStatus = "captured"
+	// Холд отменён до списания
+	HoldVoided HoldStatus = "voided"
+	// Холд не был списан или отменён до ExpiresAt и снят автоматически
+	HoldExpired HoldStatus = "expired"
+)
+
+// Hold резервирует Amount на кошельке From для перевода на кошелёк To. Пока холд активен,
+// сумма остаётся в балансе кошелька, но не входит в доступный баланс.
+type Hold struct {
+	Id        int64      `json:"id"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Amount    Amount     `json:"amount"`
+	Currency  Currency   `json:"currency"`
+	Status    HoldStatus `json:"status"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	// ClosedAt — время списания, отмены или истечения
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	// CapturedAmount и TransactionId заполнены у списанного холда; остаток Amount
+	// сверх CapturedAmount при частичном списании освобождается
+	CapturedAmount *Amount `json:"captured_amount,omitempty"`
+	TransactionId  *int64  `json:"transaction_id,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// StatusCode равен 0, пока исходный запрос ещё выполняется.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
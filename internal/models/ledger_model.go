@@ -0,0 +1,53 @@
+package models
+
+import "sort"
+
+// IssuanceAccount — системный счёт журнала, за счёт которого зачисляются начальные
+// балансы и ручные корректировки. Благодаря ему сумма проводок в каждой валюте остаётся нулевой.
+const IssuanceAccount = "@issuance"
+
+// LedgerEntryKind — причина появления проводки в журнале
+type LedgerEntryKind string
+
+const (
+	LedgerEntryOpening    LedgerEntryKind = "opening"
+	LedgerEntryTransfer   LedgerEntryKind = "transfer"
+	LedgerEntryReversal   LedgerEntryKind = "reversal"
+	LedgerEntryConversion LedgerEntryKind = "conversion"
+	LedgerEntryAdjustment LedgerEntryKind = "adjustment"
+	LedgerEntryClosing    LedgerEntryKind = "closing"
+)
+
+// LedgerMismatch — кошелёк, баланс которого расходится с суммой его проводок
+type LedgerMismatch struct {
+	Address       string `json:"address"`
+	Balance       Amount `json:"balance"`
+	LedgerBalance Amount `json:"ledger_balance"`
+}
+
+// LedgerReport — результат сверки кошельков с журналом проводок
+type LedgerReport struct {
+	// Сумма проводок по каждой валюте, в сбалансированном журнале все суммы равны нулю.
+	// Суммы разных валют не складываются: ошибка в одной валюте не компенсируется другой.
+	Totals map[Currency]Amount `json:"totals"`
+	// Остатки системных счетов (начинаются с '@') в каждой валюте: например, позиция
+	// счёта FXAccount по валюте отправителя и по валюте получателя видна отдельно
+	SystemBalances map[Currency]map[string]Amount `json:"system_balances"`
+	Mismatches     []LedgerMismatch               `json:"mismatches"`
+}
+
+func (r *LedgerReport) Balanced() bool {
+	return len(r.UnbalancedCurrencies()) == 0 && len(r.Mismatches) == 0
+}
+
+// UnbalancedCurrencies возвращает валюты с ненулевой суммой проводок в порядке кодов
+func (r *LedgerReport) UnbalancedCurrencies() []Currency {
+	var unbalanced []Currency
+	for currency, total := range r.Totals {
+		if !total.IsZero() {
+			unbalanced = append(unbalanced, currency)
+		}
+	}
+	sort.Slice(unbalanced, func(i, j int) bool { return unbalanced[i] < unbalanced[j] })
+	return unbalanced
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// WalletRole задаёт, в какой роли кошелёк из фильтра участвует в транзакции
+type WalletRole string
+
+const (
+	WalletRoleAny      WalletRole = "any"
+	WalletRoleSender   WalletRole = "sender"
+	WalletRoleReceiver WalletRole = "receiver"
+)
+
+func (r WalletRole) Valid() bool {
+	switch r {
+	case WalletRoleAny, WalletRoleSender, WalletRoleReceiver:
+		return true
+	}
+	return false
+}
+
+// TransactionCursor — позиция в списке транзакций, упорядоченном по (created_at, id) по убыванию.
+// Следующая страница начинается с транзакций строго "старше" курсора.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	Id        int64
+}
+
+// Encode возвращает непрозрачное для клиента строковое представление курсора
+func (c TransactionCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseTransactionCursor(s string) (*TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanosStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(nanosStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &TransactionCursor{CreatedAt: time.Unix(0, nanos).UTC(), Id: id}, nil
+}
+
+// TransactionFilter описывает выборку транзакций. Пустые поля не ограничивают выборку.
+type TransactionFilter struct {
+	Wallet     string
+	WalletRole WalletRole
+	// Owner оставляет только транзакции, в которых участвует кошелёк этого клиента
+	Owner *int64
+
+	MinAmount *Amount
+	MaxAmount *Amount
+
+	// Интервал времени создания: [Since, Until)
+	Since *time.Time
+	Until *time.Time
+
+	After *TransactionCursor
+	Limit int
+}
+
+// TransactionPage — одна страница списка транзакций
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
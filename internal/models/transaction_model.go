@@ -8,6 +8,21 @@ type Transaction struct {
 	Id       int64    `json:"id"`
 	From      string   `json:"from"`
 	To        string   `json:"to"`  
-	Amount    float64   `json:"amount"`
+	Amount    Amount    `json:"amount"`
+	// Currency — валюта списания с отправителя; без конвертации — валюта обоих кошельков
+	Currency  Currency  `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Conversion заполнена у переводов между кошельками в разных валютах
+	Conversion *Conversion `json:"conversion,omitempty"`
+
+	// Сторнирование: ReversalOf — исходная транзакция для компенсирующей,
+	// ReversedBy — компенсирующая транзакция для сторнированной
+	ReversalOf *int64 `json:"reversal_of,omitempty"`
+	ReversedBy *int64 `json:"reversed_by,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	// Балансы кошельков сразу после перевода, заполняются только при создании транзакции
+	FromBalance *Amount `json:"from_balance,omitempty"`
+	ToBalance   *Amount `json:"to_balance,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// EntryDirection — направление движения средств относительно кошелька
+type EntryDirection string
+
+const (
+	// DirectionDebit — списание с кошелька
+	DirectionDebit EntryDirection = "debit"
+	// DirectionCredit — зачисление на кошелёк
+	DirectionCredit EntryDirection = "credit"
+)
+
+// WalletHistoryEntry — транзакция с точки зрения одного кошелька
+type WalletHistoryEntry struct {
+	TransactionId int64          `json:"transaction_id"`
+	Direction     EntryDirection `json:"direction"`
+	Counterparty  string         `json:"counterparty"`
+	Amount        Amount         `json:"amount"`
+	Currency      Currency       `json:"currency"`
+	BalanceAfter  Amount         `json:"balance_after"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// WalletHistoryFilter описывает выборку истории кошелька, интервал времени [Since, Until)
+type WalletHistoryFilter struct {
+	Since *time.Time
+	Until *time.Time
+
+	After *TransactionCursor
+	Limit int
+}
+
+type WalletHistoryPage struct {
+	Entries    []WalletHistoryEntry `json:"entries"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
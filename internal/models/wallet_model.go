@@ -1,11 +1,42 @@
 package models 
 
 import (
+	"encoding/json"
 	"time"
 )
 
+// WalletStatus — состояние кошелька. Переводы возможны только между активными кошельками.
+type WalletStatus string
+
+const (
+	WalletActive WalletStatus = "active"
+	WalletFrozen WalletStatus = "frozen"
+	// Закрытый кошелёк не удаляется, чтобы его транзакции оставались доступны
+	WalletClosed WalletStatus = "closed"
+)
+
 type Wallet struct {
-	Address   string    `json:"address"`
-	Balance   float64   `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
-}
\ No newline at end of file
+	Address   string       `json:"address"`
+	Balance   Amount       `json:"balance"`
+	// Held — сумма активных холдов: она входит в Balance, но недоступна для переводов
+	Held      Amount       `json:"held"`
+	Currency  Currency     `json:"currency"`
+	Status    WalletStatus `json:"status"`
+	// Owner — id клиента-владельца; nil у кошельков, созданных без аутентификации
+	Owner     *int64       `json:"owner_id,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Available возвращает баланс, доступный для переводов и новых холдов
+func (w *Wallet) Available() Amount {
+	return w.Balance.Sub(w.Held)
+}
+
+// MarshalJSON дополняет кошелёк доступным балансом, который не хранится отдельно
+func (w Wallet) MarshalJSON() ([]byte, error) {
+	type wallet Wallet
+	return json.Marshal(struct {
+		wallet
+		AvailableBalance Amount `json:"available_balance"`
+	}{wallet(w), w.Available()})
+}
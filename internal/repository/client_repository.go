@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Менеджер для клиентов и их API-ключей
+type ClientRepository struct {
+	db *DB
+}
+
+func NewClientRepository(db *DB) *ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+// CreateClient создаёт клиента вместе с его первым ключом, заданным префиксом и хэшем
+func (cr *ClientRepository) CreateClient(ctx context.Context, name string, admin bool, prefix, hash string) (*models.Client, *models.APIKey, error) {
+	tx, err := cr.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO {schema}.clients (name, admin) VALUES ($1, $2)
+              RETURNING id, name, admin, subject, created_at`
+
+	var c models.Client
+
+	err = tx.QueryRow(ctx, query, name, admin).Scan(&c.Id, &c.Name, &c.Admin, &c.Subject, &c.CreatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	k, err := insertAPIKey(ctx, tx, c.Id, prefix, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	return &c, k, nil
+}
+
+func (cr *ClientRepository) GetClient(ctx context.Context, id int64) (*models.Client, error) {
+	query := `SELECT id, name, admin, subject, created_at FROM {schema}.clients WHERE id = $1`
+
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, id).Scan(&c.Id, &c.Name, &c.Admin, &c.Subject, &c.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "client", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to find client with id %v: %w", id, err)
+	}
+
+	return &c, nil
+}
+
+// EnsureSubjectClient возвращает клиента с субъектом JWT subject, создавая его при первом обращении
+func (cr *ClientRepository) EnsureSubjectClient(ctx context.Context, subject string) (*models.Client, error) {
+	// DO UPDATE без изменений нужен, чтобы RETURNING вернул уже существующую строку
+	query := `INSERT INTO {schema}.clients (name, subject) VALUES ($1, $1)
+              ON CONFLICT (subject) DO UPDATE SET subject = EXCLUDED.subject
+              RETURNING id, name, admin, subject, created_at`
+
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, subject).Scan(&c.Id, &c.Name, &c.Admin, &c.Subject, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure client for subject %v: %w", subject, err)
+	}
+
+	return &c, nil
+}
+
+// CreateAPIKey сохраняет ключ клиента clientId по его префиксу и хэшу
+func (cr *ClientRepository) CreateAPIKey(ctx context.Context, clientId int64, prefix, hash string) (*models.APIKey, error) {
+	return insertAPIKey(ctx, cr.db, clientId, prefix, hash)
+}
+
+// rowQuerier — *DB или открытая на нём транзакция pgx.Tx
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func insertAPIKey(ctx context.Context, q rowQuerier, clientId int64, prefix, hash string) (*models.APIKey, error) {
+	query := `INSERT INTO {schema}.api_keys (client_id, prefix, key_hash) VALUES ($1, $2, $3)
+              RETURNING id, client_id, prefix, key_hash, created_at, revoked_at`
+
+	var k models.APIKey
+
+	err := q.QueryRow(ctx, query, clientId, prefix, hash).Scan(
+		&k.Id, &k.ClientId, &k.Prefix, &k.Hash, &k.CreatedAt, &k.RevokedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key for client %v: %w", clientId, err)
+	}
+
+	return &k, nil
+}
+
+// GetAPIKeyByPrefix возвращает ключ с указанным префиксом вместе с его клиентом
+func (cr *ClientRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, *models.Client, error) {
+	query := `SELECT k.id, k.client_id, k.prefix, k.key_hash, k.created_at, k.revoked_at,
+                     c.id, c.name, c.admin, c.subject, c.created_at
+              FROM {schema}.api_keys k
+              JOIN {schema}.clients c ON c.id = k.client_id
+              WHERE k.prefix = $1`
+
+	var k models.APIKey
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, prefix).Scan(
+		&k.Id, &k.ClientId, &k.Prefix, &k.Hash, &k.CreatedAt, &k.RevokedAt,
+		&c.Id, &c.Name, &c.Admin, &c.Subject, &c.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, &NotFoundError{Entity: "api key", Field: "prefix", Key: prefix}
+		}
+		return nil, nil, fmt.Errorf("failed to find api key with prefix %v: %w", prefix, err)
+	}
+
+	return &k, &c, nil
+}
+
+// RevokeAPIKey отзывает ключ; повторный отзыв не меняет время первого
+func (cr *ClientRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	query := `UPDATE {schema}.api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1`
+
+	result, err := cr.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key with id %v: %w", id, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return &NotFoundError{Entity: "api key", Field: "id", Key: id}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// schemaPlaceholder в тексте запроса заменяется экранированным именем схемы
+const schemaPlaceholder = "{schema}"
+
+// DB — пул соединений с PostgreSQL, привязанный к схеме, в которой лежат таблицы сервиса.
+// Репозитории пишут запросы как {schema}.wallets, поэтому несколько экземпляров сервиса
+// с разными схемами могут работать в одной базе, не видя данных друг друга.
+type DB struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+func NewDB(pool *pgxpool.Pool, schema string) *DB {
+	return &DB{
+		pool:   pool,
+		schema: pgx.Identifier{schema}.Sanitize(),
+	}
+}
+
+func (db *DB) render(sql string) string {
+	return strings.ReplaceAll(sql, schemaPlaceholder, db.schema)
+}
+
+// Exec, Query и QueryRow создают спан на каждый SQL-запрос
+
+func (db *DB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	sql = db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	tag, err := db.pool.Exec(ctx, sql, args...)
+	endQuery(span, err)
+	return tag, err
+}
+
+func (db *DB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	sql = db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	rows, err := db.pool.Query(ctx, sql, args...)
+	if err != nil {
+		endQuery(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	sql = db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	return tracedRow{Row: db.pool.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := db.pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaTx{Tx: tx, db: db}, nil
+}
+
+// Ping проверяет, что база данных отвечает
+func (db *DB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
+// Stats возвращает текущее состояние пула соединений
+func (db *DB) Stats() models.PoolStats {
+	stat := db.pool.Stat()
+	return models.PoolStats{
+		TotalConns:             stat.TotalConns(),
+		AcquiredConns:          stat.AcquiredConns(),
+		IdleConns:              stat.IdleConns(),
+		ConstructingConns:      stat.ConstructingConns(),
+		MaxConns:               stat.MaxConns(),
+		AcquireCount:           stat.AcquireCount(),
+		AcquireDurationSeconds: stat.AcquireDuration().Seconds(),
+		EmptyAcquireCount:      stat.EmptyAcquireCount(),
+		CanceledAcquireCount:   stat.CanceledAcquireCount(),
+	}
+}
+
+// schemaTx — транзакция, подставляющая схему в запросы и трассирующая их так же, как DB
+type schemaTx struct {
+	pgx.Tx
+	db *DB
+}
+
+func (tx *schemaTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	sql = tx.db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	tag, err := tx.Tx.Exec(ctx, sql, args...)
+	endQuery(span, err)
+	return tag, err
+}
+
+func (tx *schemaTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	sql = tx.db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	rows, err := tx.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		endQuery(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (tx *schemaTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	sql = tx.db.render(sql)
+	ctx, span := startQuery(ctx, sql)
+	return tracedRow{Row: tx.Tx.QueryRow(ctx, sql, args...), span: span}
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// NotFoundError возвращается, когда запрошенная запись отсутствует.
+// Оборачивает pgx.ErrNoRows, поэтому errors.Is(err, pgx.ErrNoRows) для неё истинно.
+type NotFoundError struct {
+	Entity string
+	Field  string
+	Key    interface{}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with %s %v not found", e.Entity, e.Field, e.Key)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return pgx.ErrNoRows
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Менеджер для холдов
+type HoldRepository struct {
+	db *DB
+}
+
+func NewHoldRepository(db *DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+// HoldCheck вызывается внутри транзакции БД, когда холд уже заблокирован.
+// Ошибка, возвращённая проверкой, отменяет изменение.
+type HoldCheck func(hold *models.Hold) error
+
+// holdColumns — столбцы холда в порядке, который ожидает scanHold
+const holdColumns = `id, from_wallet, to_wallet, amount, currency, status, expires_at, created_at,
+    closed_at, captured_amount, transaction_id`
+
+func scanHold(row pgx.Row, h *models.Hold) error {
+	return row.Scan(&h.Id, &h.From, &h.To, &h.Amount, &h.Currency, &h.Status, &h.ExpiresAt, &h.CreatedAt,
+		&h.ClosedAt, &h.CapturedAmount, &h.TransactionId)
+}
+
+// CreateHold резервирует amount на кошельке from для перевода на кошелёк to.
+// check получает оба кошелька, заблокированных в том же порядке, что и при переводе.
+func (hr *HoldRepository) CreateHold(ctx context.Context, from, to string, amount models.Amount, expiresAt time.Time, check TransferCheck) (*models.Hold, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	wallets, err := lockWallets(ctx, tx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(wallets[from], wallets[to]); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE {schema}.wallets SET held = held + $2 WHERE address = $1`, from, amount)
+	if err != nil {
+		return nil, fmt.Errorf("sender held amount update failed: %w", err)
+	}
+
+	var hold models.Hold
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO {schema}.holds (from_wallet, to_wallet, amount, currency, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+holdColumns,
+		from, to, amount, string(wallets[from].Currency), expiresAt.UTC(),
+	)
+	if err := scanHold(row, &hold); err != nil {
+		return nil, fmt.Errorf("hold record failed: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold committed", "hold_id", hold.Id)
+
+	return &hold, nil
+}
+
+func (hr *HoldRepository) GetHold(ctx context.Context, id int64) (*models.Hold, error) {
+	var hold models.Hold
+
+	err := scanHold(hr.db.QueryRow(ctx, `SELECT `+holdColumns+` FROM {schema}.holds WHERE id = $1`, id), &hold)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "hold", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to find hold with id %v: %w", id, err)
+	}
+
+	return &hold, nil
+}
+
+// CaptureHold переводит amount по холду id и закрывает холд. Перевод выполняется так же,
+// как ExecuteTransfer: checkHold проверяет заблокированный холд, check — кошельки перевода.
+func (hr *HoldRepository) CaptureHold(ctx context.Context, id int64, amount models.Amount, checkHold HoldCheck, check TransferCheck) (*models.Hold, *models.Transaction, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := lockHold(ctx, tx, id, checkHold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Весь холд освобождается тем же запросом, что списывает amount
+	t, err := transfer(ctx, tx, models.Transaction{From: hold.From, To: hold.To, Amount: amount}, models.LedgerEntryTransfer, hold.Amount, check)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	row := tx.QueryRow(ctx,
+		`UPDATE {schema}.holds SET status = $2, closed_at = now(), captured_amount = $3, transaction_id = $4
+		WHERE id = $1 AND status = $5 RETURNING `+holdColumns,
+		id, string(models.HoldCaptured), amount, t.Id, string(models.HoldActive),
+	)
+	if err := scanHold(row, hold); err != nil {
+		return nil, nil, fmt.Errorf("failed to close hold %v: %w", id, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold capture committed", "hold_id", id, "transaction_id", t.Id)
+
+	return hold, t, nil
+}
+
+// ReleaseHold закрывает холд id в состоянии status и возвращает его сумму в доступный баланс
+func (hr *HoldRepository) ReleaseHold(ctx context.Context, id int64, status models.HoldStatus, checkHold HoldCheck) (*models.Hold, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := lockHold(ctx, tx, id, checkHold)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx,
+		`UPDATE {schema}.holds SET status = $2, closed_at = now()
+		WHERE id = $1 AND status = $3 RETURNING `+holdColumns,
+		id, string(status), string(models.HoldActive),
+	)
+	if err := scanHold(row, hold); err != nil {
+		return nil, fmt.Errorf("failed to close hold %v: %w", id, err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE {schema}.wallets SET held = held - $2 WHERE address = $1`, hold.From, hold.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("sender held amount update failed: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold release committed", "hold_id", id, "status", status)
+
+	return hold, nil
+}
+
+func (hr *HoldRepository) ListExpiredHolds(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	rows, err := hr.db.Query(ctx,
+		`SELECT id FROM {schema}.holds WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at, id LIMIT $3`,
+		string(models.HoldActive), now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan hold id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	return ids, nil
+}
+
+// lockHold читает холд с блокировкой строки до конца транзакции и проверяет его
+func lockHold(ctx context.Context, tx pgx.Tx, id int64, check HoldCheck) (*models.Hold, error) {
+	var hold models.Hold
+
+	err := scanHold(tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM {schema}.holds WHERE id = $1 FOR UPDATE`, id), &hold)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "hold", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to lock hold with id %v: %w", id, err)
+	}
+
+	if check != nil {
+		if err := check(&hold); err != nil {
+			return nil, err
+		}
+	}
+
+	return &hold, nil
+}
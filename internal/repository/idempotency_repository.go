@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Менеджер для ключей идемпотентности
+type IdempotencyRepository struct {
+	db *DB
+}
+
+func NewIdempotencyRepository(db *DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve атомарно занимает ключ под новый запрос. Просроченный ключ занимается заново.
+// Если ключ уже занят, возвращается существующая запись и false.
+func (ir *IdempotencyRepository) Reserve(ctx context.Context, scope, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	query := `INSERT INTO {schema}.idempotency_keys (scope, key, fingerprint, expires_at)
+              VALUES ($1, $2, $3, now() + $4::bigint * interval '1 millisecond')
+              ON CONFLICT (scope, key) DO UPDATE
+              SET fingerprint = EXCLUDED.fingerprint,
+                  status_code = NULL,
+                  response_headers = NULL,
+                  response_body = NULL,
+                  created_at = now(),
+                  expires_at = EXCLUDED.expires_at
+              WHERE idempotency_keys.expires_at <= now()
+              RETURNING created_at, expires_at`
+
+	record := models.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint}
+
+	err := ir.db.QueryRow(ctx, query, scope, key, fingerprint, ttl.Milliseconds()).Scan(
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err == nil {
+		return &record, true, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key %v: %w", key, err)
+	}
+
+	existing, err := ir.Get(ctx, scope, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (ir *IdempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	query := `SELECT fingerprint, status_code, response_headers, response_body, created_at, expires_at
+              FROM {schema}.idempotency_keys WHERE scope = $1 AND key = $2`
+
+	record := models.IdempotencyRecord{Scope: scope, Key: key}
+	var statusCode *int
+
+	err := ir.db.QueryRow(ctx, query, scope, key).Scan(
+		&record.Fingerprint,
+		&statusCode,
+		&record.Headers,
+		&record.Body,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "idempotency key", Field: "value", Key: key}
+		}
+		return nil, fmt.Errorf("failed to get idempotency key %v: %w", key, err)
+	}
+
+	if statusCode != nil {
+		record.StatusCode = *statusCode
+	}
+
+	return &record, nil
+}
+
+// Complete сохраняет ответ на запрос, выполненный под ключом
+func (ir *IdempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	query := `UPDATE {schema}.idempotency_keys
+              SET status_code = $3, response_headers = $4, response_body = $5
+              WHERE scope = $1 AND key = $2`
+
+	_, err := ir.db.Exec(ctx, query, scope, key, statusCode, headers, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key %v: %w", key, err)
+	}
+
+	return nil
+}
+
+// Release освобождает ключ незавершённого запроса, чтобы его можно было повторить
+func (ir *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM {schema}.idempotency_keys
+              WHERE scope = $1 AND key = $2 AND status_code IS NULL`
+
+	_, err := ir.db.Exec(ctx, query, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key %v: %w", key, err)
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком; такие ключи Reserve и так занимает заново,
+// удаление лишь не даёт таблице расти
+func (ir *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM {schema}.idempotency_keys WHERE expires_at <= now()`
+
+	tag, err := ir.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
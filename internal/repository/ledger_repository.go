@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Менеджер для журнала проводок
+type LedgerRepository struct {
+	db *DB
+}
+
+func NewLedgerRepository(db *DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Verify сверяет балансы кошельков с журналом в одном снимке данных
+func (lr *LedgerRepository) Verify(ctx context.Context) (*models.LedgerReport, error) {
+	tx, err := lr.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var report models.LedgerReport
+
+	totals, err := tx.Query(ctx,
+		`SELECT currency, CASE WHEN account LIKE '@%' THEN account ELSE '' END, SUM(amount)
+         FROM {schema}.ledger_entries GROUP BY 1, 2`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+	defer totals.Close()
+
+	report.Totals = make(map[models.Currency]models.Amount)
+	report.SystemBalances = make(map[models.Currency]map[string]models.Amount)
+	for totals.Next() {
+		var currency, system string
+		var total models.Amount
+		if err := totals.Scan(&currency, &system, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger total: %w", err)
+		}
+		c := models.Currency(currency)
+		report.Totals[c] = report.Totals[c].Add(total)
+		if system != "" {
+			if report.SystemBalances[c] == nil {
+				report.SystemBalances[c] = make(map[string]models.Amount)
+			}
+			report.SystemBalances[c][system] = total
+		}
+	}
+	if err := totals.Err(); err != nil {
+		return nil, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+	totals.Close()
+
+	// Счета журнала без кошелька тоже попадают в сверку, кроме системных (начинаются с '@')
+	query := `SELECT COALESCE(w.address, l.account), COALESCE(w.balance, 0), COALESCE(l.total, 0)
+              FROM {schema}.wallets w
+              FULL OUTER JOIN (
+                  SELECT account, SUM(amount) AS total
+                  FROM {schema}.ledger_entries
+                  WHERE account NOT LIKE '@%'
+                  GROUP BY account
+              ) l ON l.account = w.address
+              WHERE COALESCE(w.balance, 0) <> COALESCE(l.total, 0)
+              ORDER BY 1`
+
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare balances with ledger: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.LedgerMismatch
+		if err := rows.Scan(&m.Address, &m.Balance, &m.LedgerBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger mismatch: %w", err)
+		}
+		report.Mismatches = append(report.Mismatches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return &report, nil
+}
+
+// insertLedgerPair записывает сбалансированную пару проводок: списание amount со счёта debit
+// и зачисление на счёт credit, обе в валюте currency. Вызывается внутри той же транзакции БД,
+// что и изменение балансов. balance_after кошелька продолжает сумму его предыдущих проводок;
+// строка кошелька к этому моменту заблокирована, поэтому параллельные записи не перемежаются.
+func insertLedgerPair(ctx context.Context, tx pgx.Tx, transactionId *int64, kind models.LedgerEntryKind, currency models.Currency, debit, credit string, amount models.Amount) error {
+	query := `INSERT INTO {schema}.ledger_entries (transaction_id, account, amount, kind, balance_after, currency)
+              SELECT $1::bigint, e.account, e.amount, $5,
+                     CASE WHEN e.account LIKE '@%' THEN NULL ELSE COALESCE((
+                         SELECT p.balance_after FROM {schema}.ledger_entries p
+                         WHERE p.account = e.account ORDER BY p.id DESC LIMIT 1
+                     ), 0) + e.amount END, $7
+              FROM (VALUES ($2::text, $3::numeric), ($4::text, $6::numeric)) AS e(account, amount)`
+
+	_, err := tx.Exec(ctx, query, transactionId, debit, amount.Neg(), credit, string(kind), amount, string(currency))
+	if err != nil {
+		return fmt.Errorf("failed to write ledger entries: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+var (
+	// errDuplicatePrefix повторяет ограничение UNIQUE (prefix) таблицы api_keys
+	errDuplicatePrefix = errors.New("api key prefix already exists")
+	// errUnknownClient повторяет внешний ключ api_keys.client_id
+	errUnknownClient = errors.New("client does not exist")
+)
+
+func cloneClient(c *models.Client) *models.Client {
+	clone := *c
+	if c.Subject != nil {
+		subject := *c.Subject
+		clone.Subject = &subject
+	}
+	return &clone
+}
+
+func cloneAPIKey(k *models.APIKey) *models.APIKey {
+	clone := *k
+	if k.RevokedAt != nil {
+		revokedAt := *k.RevokedAt
+		clone.RevokedAt = &revokedAt
+	}
+	return &clone
+}
+
+// CreateClient создаёт клиента вместе с его первым ключом, заданным префиксом и хэшем
+func (s *Store) CreateClient(ctx context.Context, name string, admin bool, prefix, hash string) (*models.Client, *models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Ключ проверяется до создания клиента, чтобы ошибка не оставила клиента без ключа
+	if _, ok := s.apiKeyPrefixes[prefix]; ok {
+		return nil, nil, fmt.Errorf("failed to create api key for new client: %w", errDuplicatePrefix)
+	}
+
+	c := s.insertClient(name, admin, nil)
+	k, err := s.insertAPIKey(c.Id, prefix, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cloneClient(c), cloneAPIKey(k), nil
+}
+
+func (s *Store) GetClient(ctx context.Context, id int64) (*models.Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.clients[id]
+	if !ok {
+		return nil, &repository.NotFoundError{Entity: "client", Field: "id", Key: id}
+	}
+	return cloneClient(c), nil
+}
+
+// EnsureSubjectClient возвращает клиента с субъектом JWT subject, создавая его при первом обращении
+func (s *Store) EnsureSubjectClient(ctx context.Context, subject string) (*models.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.clients {
+		if c.Subject != nil && *c.Subject == subject {
+			return cloneClient(c), nil
+		}
+	}
+	return cloneClient(s.insertClient(subject, false, &subject)), nil
+}
+
+// CreateAPIKey сохраняет ключ клиента clientId по его префиксу и хэшу
+func (s *Store) CreateAPIKey(ctx context.Context, clientId int64, prefix, hash string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, err := s.insertAPIKey(clientId, prefix, hash)
+	if err != nil {
+		return nil, err
+	}
+	return cloneAPIKey(k), nil
+}
+
+// GetAPIKeyByPrefix возвращает ключ с указанным префиксом вместе с его клиентом
+func (s *Store) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, *models.Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.apiKeyPrefixes[prefix]
+	if !ok {
+		return nil, nil, &repository.NotFoundError{Entity: "api key", Field: "prefix", Key: prefix}
+	}
+	k := s.apiKeys[id]
+	return cloneAPIKey(k), cloneClient(s.clients[k.ClientId]), nil
+}
+
+// RevokeAPIKey отзывает ключ; повторный отзыв не меняет время первого
+func (s *Store) RevokeAPIKey(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return &repository.NotFoundError{Entity: "api key", Field: "id", Key: id}
+	}
+	if k.RevokedAt == nil {
+		revokedAt := now()
+		k.RevokedAt = &revokedAt
+	}
+	return nil
+}
+
+func (s *Store) insertClient(name string, admin bool, subject *string) *models.Client {
+	s.lastClientId++
+	c := &models.Client{Id: s.lastClientId, Name: name, Admin: admin, Subject: subject, CreatedAt: now()}
+	s.clients[c.Id] = c
+	return c
+}
+
+func (s *Store) insertAPIKey(clientId int64, prefix, hash string) (*models.APIKey, error) {
+	if _, ok := s.clients[clientId]; !ok {
+		return nil, fmt.Errorf("failed to create api key for client %v: %w", clientId, errUnknownClient)
+	}
+	if _, ok := s.apiKeyPrefixes[prefix]; ok {
+		return nil, fmt.Errorf("failed to create api key for client %v: %w", clientId, errDuplicatePrefix)
+	}
+
+	s.lastAPIKeyId++
+	k := &models.APIKey{Id: s.lastAPIKeyId, ClientId: clientId, Prefix: prefix, Hash: hash, CreatedAt: now()}
+	s.apiKeys[k.Id] = k
+	s.apiKeyPrefixes[prefix] = k.Id
+	return k, nil
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+func holdNotFound(id int64) error {
+	return &repository.NotFoundError{Entity: "hold", Field: "id", Key: id}
+}
+
+func cloneHold(h *models.Hold) *models.Hold {
+	c := *h
+	if h.ClosedAt != nil {
+		closedAt := *h.ClosedAt
+		c.ClosedAt = &closedAt
+	}
+	if h.CapturedAmount != nil {
+		captured := *h.CapturedAmount
+		c.CapturedAmount = &captured
+	}
+	if h.TransactionId != nil {
+		id := *h.TransactionId
+		c.TransactionId = &id
+	}
+	return &c
+}
+
+func (s *Store) CreateHold(ctx context.Context, from, to string, amount models.Amount, expiresAt time.Time, check repository.TransferCheck) (*models.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first, second := from, to
+	if second < first {
+		first, second = second, first
+	}
+	for _, address := range []string{first, second} {
+		if _, ok := s.wallets[address]; !ok {
+			return nil, walletNotFound(address)
+		}
+	}
+	fromWallet := s.wallets[from]
+
+	if check != nil {
+		if err := check(cloneWallet(fromWallet), cloneWallet(s.wallets[to])); err != nil {
+			return nil, err
+		}
+	}
+
+	if fromWallet.Held.Add(amount).Cmp(fromWallet.Balance) > 0 {
+		return nil, fmt.Errorf("sender held amount update failed: %w", errHeldExceedsBalance)
+	}
+	fromWallet.Held = fromWallet.Held.Add(amount)
+
+	s.lastHoldId++
+	hold := &models.Hold{
+		Id:        s.lastHoldId,
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Currency:  fromWallet.Currency,
+		Status:    models.HoldActive,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Microsecond),
+		CreatedAt: now(),
+	}
+	s.holds[hold.Id] = hold
+
+	return cloneHold(hold), nil
+}
+
+func (s *Store) GetHold(ctx context.Context, id int64) (*models.Hold, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.holds[id]
+	if !ok {
+		return nil, holdNotFound(id)
+	}
+	return cloneHold(h), nil
+}
+
+func (s *Store) CaptureHold(ctx context.Context, id int64, amount models.Amount, checkHold repository.HoldCheck, check repository.TransferCheck) (*models.Hold, *models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.activeHold(id, checkHold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t, err := s.transfer(ctx, models.Transaction{From: h.From, To: h.To, Amount: amount}, models.LedgerEntryTransfer, h.Amount, check)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closedAt, captured, transactionId := now(), amount, t.Id
+	h.Status = models.HoldCaptured
+	h.ClosedAt, h.CapturedAmount, h.TransactionId = &closedAt, &captured, &transactionId
+	return cloneHold(h), t, nil
+}
+
+func (s *Store) ReleaseHold(ctx context.Context, id int64, status models.HoldStatus, checkHold repository.HoldCheck) (*models.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.activeHold(id, checkHold)
+	if err != nil {
+		return nil, err
+	}
+
+	if w, ok := s.wallets[h.From]; ok {
+		w.Held = w.Held.Sub(h.Amount)
+	}
+	closedAt := now()
+	h.Status = status
+	h.ClosedAt = &closedAt
+	return cloneHold(h), nil
+}
+
+func (s *Store) ListExpiredHolds(ctx context.Context, at time.Time, limit int) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var expired []*models.Hold
+	for _, h := range s.holds {
+		if h.Status == models.HoldActive && !h.ExpiresAt.After(at) {
+			expired = append(expired, h)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if !expired[i].ExpiresAt.Equal(expired[j].ExpiresAt) {
+			return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+		}
+		return expired[i].Id < expired[j].Id
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	ids := make([]int64, 0, len(expired))
+	for _, h := range expired {
+		ids = append(ids, h.Id)
+	}
+	return ids, nil
+}
+
+// activeHold находит холд id и проверяет его; как и условие status = 'active' в запросах
+// PostgreSQL, не даёт закрыть холд повторно. Вызывается под блокировкой на запись.
+func (s *Store) activeHold(id int64, check repository.HoldCheck) (*models.Hold, error) {
+	h, ok := s.holds[id]
+	if !ok {
+		return nil, holdNotFound(id)
+	}
+	if check != nil {
+		if err := check(cloneHold(h)); err != nil {
+			return nil, err
+		}
+	}
+	if h.Status != models.HoldActive {
+		return nil, fmt.Errorf("failed to close hold %v: hold is %s", id, h.Status)
+	}
+	return h, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// idempotencyKey — первичный ключ (scope, key) таблицы idempotency_keys
+type idempotencyKey struct {
+	scope, key string
+}
+
+func cloneHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	c := make(map[string]string, len(headers))
+	for name, value := range headers {
+		c[name] = value
+	}
+	return c
+}
+
+func cloneIdempotencyRecord(r *models.IdempotencyRecord) *models.IdempotencyRecord {
+	c := *r
+	c.Headers = cloneHeaders(r.Headers)
+	c.Body = bytes.Clone(r.Body)
+	return &c
+}
+
+// Reserve атомарно занимает ключ под новый запрос. Просроченный ключ занимается заново.
+// Если ключ уже занят, возвращается существующая запись и false.
+func (s *Store) Reserve(ctx context.Context, scope, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	createdAt := now()
+	id := idempotencyKey{scope, key}
+	if existing, ok := s.idempotency[id]; ok && existing.ExpiresAt.After(createdAt) {
+		return cloneIdempotencyRecord(existing), false, nil
+	}
+
+	record := &models.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(ttl).Truncate(time.Microsecond),
+	}
+	s.idempotency[id] = record
+	return cloneIdempotencyRecord(record), true, nil
+}
+
+func (s *Store) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.idempotency[idempotencyKey{scope, key}]
+	if !ok {
+		return nil, &repository.NotFoundError{Entity: "idempotency key", Field: "value", Key: key}
+	}
+	return cloneIdempotencyRecord(record), nil
+}
+
+// Complete сохраняет ответ на запрос, выполненный под ключом
+func (s *Store) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.idempotency[idempotencyKey{scope, key}]
+	if !ok {
+		return nil
+	}
+	record.StatusCode = statusCode
+	record.Headers = cloneHeaders(headers)
+	record.Body = bytes.Clone(body)
+	return nil
+}
+
+// Release освобождает ключ незавершённого запроса, чтобы его можно было повторить
+func (s *Store) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKey{scope, key}
+	if record, ok := s.idempotency[id]; ok && !record.Completed() {
+		delete(s.idempotency, id)
+	}
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком; такие ключи Reserve и так занимает заново,
+// удаление лишь не даёт хранилищу расти
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at := now()
+	var deleted int64
+	for id, record := range s.idempotency {
+		if !record.ExpiresAt.After(at) {
+			delete(s.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,635 @@
+// Package memory содержит хранилище всех данных сервиса в памяти процесса.
+// Используется для запуска сервисов без базы данных (database.driver: memory), во встроенном режиме и в тестах.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+var (
+	// errNegativeBalance повторяет ограничение chk_balance_non_negative схемы PostgreSQL
+	errNegativeBalance = errors.New("balance must not be negative")
+	// errHeldExceedsBalance повторяет ограничение chk_wallet_held
+	errHeldExceedsBalance = errors.New("held amount must not exceed balance")
+)
+
+type ledgerEntry struct {
+	transactionId *int64
+	account       string
+	amount        models.Amount
+	kind          models.LedgerEntryKind
+	currency      models.Currency
+	// balanceAfter — сумма проводок счёта кошелька после этой; у системных счетов не ведётся
+	balanceAfter models.Amount
+}
+
+// Store реализует все хранилища пакета repository с той же семантикой, что и репозитории PostgreSQL.
+// Все изменения выполняются под одной блокировкой, поэтому перевод и его проверка атомарны.
+// Наружу отдаются только копии данных.
+type Store struct {
+	mu           sync.RWMutex
+	wallets      map[string]*models.Wallet
+	transactions map[int64]*models.Transaction
+	holds        map[int64]*models.Hold
+	ledger       []ledgerEntry
+	// ledgerTotals — текущая сумма проводок каждого счёта
+	ledgerTotals map[string]models.Amount
+	lastId       int64
+	lastHoldId   int64
+
+	idempotency    map[idempotencyKey]*models.IdempotencyRecord
+	clients        map[int64]*models.Client
+	apiKeys        map[int64]*models.APIKey
+	apiKeyPrefixes map[string]int64
+	lastClientId   int64
+	lastAPIKeyId   int64
+}
+
+var (
+	_ repository.WalletStore      = (*Store)(nil)
+	_ repository.TransactionStore = (*Store)(nil)
+	_ repository.HoldStore        = (*Store)(nil)
+	_ repository.LedgerStore      = (*Store)(nil)
+	_ repository.IdempotencyStore = (*Store)(nil)
+	_ repository.ClientStore      = (*Store)(nil)
+)
+
+func NewStore() *Store {
+	return &Store{
+		wallets:        make(map[string]*models.Wallet),
+		transactions:   make(map[int64]*models.Transaction),
+		holds:          make(map[int64]*models.Hold),
+		ledgerTotals:   make(map[string]models.Amount),
+		idempotency:    make(map[idempotencyKey]*models.IdempotencyRecord),
+		clients:        make(map[int64]*models.Client),
+		apiKeys:        make(map[int64]*models.APIKey),
+		apiKeyPrefixes: make(map[string]int64),
+	}
+}
+
+// now возвращает текущее время с точностью TIMESTAMP в PostgreSQL
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func walletNotFound(address string) error {
+	return &repository.NotFoundError{Entity: "wallet", Field: "address", Key: address}
+}
+
+func transactionNotFound(id int64) error {
+	return &repository.NotFoundError{Entity: "transaction", Field: "id", Key: id}
+}
+
+func cloneWallet(w *models.Wallet) *models.Wallet {
+	c := *w
+	if w.Owner != nil {
+		owner := *w.Owner
+		c.Owner = &owner
+	}
+	return &c
+}
+
+func cloneTransaction(t *models.Transaction) models.Transaction {
+	c := *t
+	if t.ReversalOf != nil {
+		id := *t.ReversalOf
+		c.ReversalOf = &id
+	}
+	if t.ReversedBy != nil {
+		id := *t.ReversedBy
+		c.ReversedBy = &id
+	}
+	if t.Conversion != nil {
+		conversion := *t.Conversion
+		c.Conversion = &conversion
+	}
+	c.FromBalance, c.ToBalance = nil, nil
+	return c
+}
+
+// addLedgerPair записывает пару проводок в валюте currency: списание amount со счёта debit
+// и зачисление на счёт credit
+func (s *Store) addLedgerPair(transactionId *int64, kind models.LedgerEntryKind, currency models.Currency, debit, credit string, amount models.Amount) {
+	for _, e := range []ledgerEntry{
+		{transactionId: transactionId, account: debit, amount: amount.Neg(), kind: kind, currency: currency},
+		{transactionId: transactionId, account: credit, amount: amount, kind: kind, currency: currency},
+	} {
+		s.ledgerTotals[e.account] = s.ledgerTotals[e.account].Add(e.amount)
+		e.balanceAfter = s.ledgerTotals[e.account]
+		s.ledger = append(s.ledger, e)
+	}
+}
+
+func (s *Store) CreateWallet(ctx context.Context, address string, currency models.Currency, balance models.Amount, owner *int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.wallets[address]; ok {
+		return fmt.Errorf("failed to create wallet: wallet with address %v already exists", address)
+	}
+	if balance.Sign() < 0 {
+		return fmt.Errorf("failed to create wallet: %w", errNegativeBalance)
+	}
+
+	s.wallets[address] = cloneWallet(&models.Wallet{
+		Address:   address,
+		Balance:   balance,
+		Currency:  currency,
+		Status:    models.WalletActive,
+		Owner:     owner,
+		CreatedAt: now(),
+	})
+
+	if !balance.IsZero() {
+		s.addLedgerPair(nil, models.LedgerEntryOpening, currency, models.IssuanceAccount, address, balance)
+	}
+	return nil
+}
+
+func (s *Store) GetWallet(ctx context.Context, address string) (*models.Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.wallets[address]
+	if !ok {
+		return nil, walletNotFound(address)
+	}
+	return cloneWallet(w), nil
+}
+
+func (s *Store) UpdateWalletBalabnce(ctx context.Context, address string, balance models.Amount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.wallets[address]
+	if !ok {
+		return walletNotFound(address)
+	}
+	if balance.Sign() < 0 {
+		return fmt.Errorf("failed to update wallet with address %v: %w", address, errNegativeBalance)
+	}
+	if balance.Cmp(w.Held) < 0 {
+		return fmt.Errorf("failed to update wallet with address %v: %w", address, errHeldExceedsBalance)
+	}
+
+	if delta := balance.Sub(w.Balance); !delta.IsZero() {
+		s.addLedgerPair(nil, models.LedgerEntryAdjustment, w.Currency, models.IssuanceAccount, address, delta)
+	}
+	w.Balance = balance
+	return nil
+}
+
+func (s *Store) UpdateWalletStatus(ctx context.Context, address string, status models.WalletStatus, check repository.WalletCheck) (*models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.wallets[address]
+	if !ok {
+		return nil, walletNotFound(address)
+	}
+	if check != nil {
+		if err := check(cloneWallet(w)); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Status = status
+	return cloneWallet(w), nil
+}
+
+// RemoveWallet, как и внешние ключи в PostgreSQL, не удаляет кошелёк, на который ссылаются транзакции и холды
+func (s *Store) RemoveWallet(ctx context.Context, address string, check repository.WalletCheck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.wallets[address]
+	if !ok {
+		return walletNotFound(address)
+	}
+	if check != nil {
+		if err := check(cloneWallet(w)); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range s.transactions {
+		if t.From == address || t.To == address {
+			return fmt.Errorf("failed to delete wallet with address %v: referenced by transaction %v", address, t.Id)
+		}
+	}
+	for _, h := range s.holds {
+		if h.From == address || h.To == address {
+			return fmt.Errorf("failed to delete wallet with address %v: referenced by hold %v", address, h.Id)
+		}
+	}
+
+	if !w.Balance.IsZero() {
+		s.addLedgerPair(nil, models.LedgerEntryClosing, w.Currency, address, models.IssuanceAccount, w.Balance)
+	}
+	delete(s.wallets, address)
+	return nil
+}
+
+func (s *Store) IsEmpty(ctx context.Context) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.wallets) == 0, nil
+}
+
+func (s *Store) CountWalletsByStatus(ctx context.Context) (map[models.WalletStatus]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[models.WalletStatus]int64)
+	for _, w := range s.wallets {
+		counts[w.Status]++
+	}
+	return counts, nil
+}
+
+func (s *Store) ExecuteTransfer(ctx context.Context, from, to string, amount models.Amount, check repository.TransferCheck) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transfer(ctx, models.Transaction{From: from, To: to, Amount: amount}, models.LedgerEntryTransfer, models.Amount{}, check)
+}
+
+func (s *Store) ExecuteConversion(ctx context.Context, from, to string, amount models.Amount, conversion models.Conversion, check repository.TransferCheck) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transfer(ctx, models.Transaction{From: from, To: to, Amount: amount, Conversion: &conversion}, models.LedgerEntryConversion, models.Amount{}, check)
+}
+
+func (s *Store) ReverseTransfer(ctx context.Context, id int64, reason string, checkOriginal repository.ReversalCheck, check repository.TransferCheck) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	original, ok := s.transactions[id]
+	if !ok {
+		return nil, transactionNotFound(id)
+	}
+	if checkOriginal != nil {
+		c := cloneTransaction(original)
+		if err := checkOriginal(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	// Повторяет уникальный индекс по reversal_of: транзакцию можно сторнировать один раз
+	for _, t := range s.transactions {
+		if t.ReversalOf != nil && *t.ReversalOf == id {
+			return nil, fmt.Errorf("transaction record failed: transaction %v is already reversed by %v", id, t.Id)
+		}
+	}
+
+	reversal, err := s.transfer(ctx, models.Transaction{
+		From:       original.To,
+		To:         original.From,
+		Amount:     original.Amount,
+		ReversalOf: &original.Id,
+		Reason:     reason,
+	}, models.LedgerEntryReversal, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
+
+	reversedBy := reversal.Id
+	original.ReversedBy = &reversedBy
+	return reversal, nil
+}
+
+// transfer переводит t.Amount с кошелька t.From на кошелёк t.To и записывает транзакцию
+// вместе с проводками вида kind, снимая с отправителя холд на сумму release. Вызывается
+// под блокировкой на запись; ничего не меняет, если перевод отклонён.
+func (s *Store) transfer(ctx context.Context, t models.Transaction, kind models.LedgerEntryKind, release models.Amount, check repository.TransferCheck) (*models.Transaction, error) {
+	// Как и PostgreSQL, сообщаем об отсутствии кошелька с меньшим адресом первым
+	first, second := t.From, t.To
+	if second < first {
+		first, second = second, first
+	}
+	for _, address := range []string{first, second} {
+		if _, ok := s.wallets[address]; !ok {
+			return nil, walletNotFound(address)
+		}
+	}
+	from, to := s.wallets[t.From], s.wallets[t.To]
+
+	if check != nil {
+		if err := check(cloneWallet(from), cloneWallet(to)); err != nil {
+			slog.DebugContext(ctx, "transfer check rejected", "from", t.From, "to", t.To, "amount", t.Amount, "error", err)
+			return nil, err
+		}
+	}
+
+	if from.Balance.Sub(t.Amount).Sign() < 0 {
+		return nil, fmt.Errorf("sender balance update failed: %w", errNegativeBalance)
+	}
+	if held := from.Held.Sub(release); held.Sign() < 0 || from.Balance.Sub(t.Amount).Cmp(held) < 0 {
+		return nil, fmt.Errorf("sender balance update failed: %w", errHeldExceedsBalance)
+	}
+
+	credit := t.Amount
+	if t.Conversion != nil {
+		credit = t.Conversion.ToAmount
+	}
+
+	from.Balance = from.Balance.Sub(t.Amount)
+	from.Held = from.Held.Sub(release)
+	fromBalance := from.Balance
+	to.Balance = to.Balance.Add(credit)
+	toBalance := to.Balance
+
+	s.lastId++
+	t.Id = s.lastId
+	t.Currency = from.Currency
+	t.CreatedAt = now()
+	t.FromBalance, t.ToBalance = nil, nil
+	stored := cloneTransaction(&t)
+	s.transactions[t.Id] = &stored
+
+	if t.Conversion == nil {
+		s.addLedgerPair(&stored.Id, kind, t.Currency, t.From, t.To, t.Amount)
+	} else {
+		s.addLedgerPair(&stored.Id, kind, t.Currency, t.From, models.FXAccount, t.Amount)
+		s.addLedgerPair(&stored.Id, kind, t.Conversion.ToCurrency, models.FXAccount, t.To, credit)
+	}
+
+	result := cloneTransaction(&stored)
+	result.FromBalance = &fromBalance
+	result.ToBalance = &toBalance
+
+	slog.DebugContext(ctx, "transfer recorded", "transaction_id", result.Id, "kind", kind,
+		"from", t.From, "to", t.To, "amount", t.Amount, "credit", credit, "from_balance", fromBalance, "to_balance", toBalance)
+
+	return &result, nil
+}
+
+func (s *Store) GetTransactionById(ctx context.Context, id int64) (*models.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, transactionNotFound(id)
+	}
+	c := cloneTransaction(t)
+	return &c, nil
+}
+
+func (s *Store) GetTransactionByInfo(ctx context.Context, from, to string, createdAt time.Time) (*models.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.sorted() {
+		if t.From == from && t.To == to && t.CreatedAt.Equal(createdAt) {
+			c := cloneTransaction(t)
+			return &c, nil
+		}
+	}
+
+	return nil, &repository.NotFoundError{
+		Entity: "transaction",
+		Field:  "from_wallet/to_wallet/created_at",
+		Key:    fmt.Sprintf("%v/%v/%v", from, to, createdAt.Format(time.RFC3339Nano)),
+	}
+}
+
+// RemoveTransaction удаляет транзакцию; ссылки на неё из других транзакций и проводок
+// обнуляются, как ON DELETE SET NULL в схеме PostgreSQL
+func (s *Store) RemoveTransaction(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.transactions[id]; !ok {
+		return transactionNotFound(id)
+	}
+	delete(s.transactions, id)
+
+	for _, t := range s.transactions {
+		if t.ReversalOf != nil && *t.ReversalOf == id {
+			t.ReversalOf = nil
+		}
+		if t.ReversedBy != nil && *t.ReversedBy == id {
+			t.ReversedBy = nil
+		}
+	}
+	for i := range s.ledger {
+		if e := &s.ledger[i]; e.transactionId != nil && *e.transactionId == id {
+			e.transactionId = nil
+		}
+	}
+	for _, h := range s.holds {
+		if h.TransactionId != nil && *h.TransactionId == id {
+			h.TransactionId = nil
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetLastTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
+	return s.ListTransactions(ctx, models.TransactionFilter{Limit: limit})
+}
+
+// ListTransactions возвращает транзакции, подходящие под фильтр, упорядоченные по (created_at, id) по убыванию
+func (s *Store) ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if filter.Limit < 0 {
+		return nil, fmt.Errorf("failed to list transactions: negative limit %d", filter.Limit)
+	}
+
+	owned := func(address string) bool {
+		w, ok := s.wallets[address]
+		return ok && w.Owner != nil && *w.Owner == *filter.Owner
+	}
+
+	transactions := make([]models.Transaction, 0, filter.Limit)
+
+	for _, t := range s.sorted() {
+		if len(transactions) == filter.Limit {
+			break
+		}
+
+		if filter.Wallet != "" {
+			switch filter.WalletRole {
+			case models.WalletRoleSender:
+				if t.From != filter.Wallet {
+					continue
+				}
+			case models.WalletRoleReceiver:
+				if t.To != filter.Wallet {
+					continue
+				}
+			default:
+				if t.From != filter.Wallet && t.To != filter.Wallet {
+					continue
+				}
+			}
+		}
+		if filter.Owner != nil && !owned(t.From) && !owned(t.To) {
+			continue
+		}
+		if filter.MinAmount != nil && t.Amount.Cmp(*filter.MinAmount) < 0 {
+			continue
+		}
+		if filter.MaxAmount != nil && t.Amount.Cmp(*filter.MaxAmount) > 0 {
+			continue
+		}
+		if !inWindow(t.CreatedAt, t.Id, filter.Since, filter.Until, filter.After) {
+			continue
+		}
+
+		transactions = append(transactions, cloneTransaction(t))
+	}
+
+	return transactions, nil
+}
+
+// GetWalletHistory возвращает транзакции кошелька от новых к старым вместе с балансом
+// кошелька после каждой из них
+func (s *Store) GetWalletHistory(ctx context.Context, address string, filter models.WalletHistoryFilter) ([]models.WalletHistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if filter.Limit < 0 {
+		return nil, fmt.Errorf("failed to get history of wallet %v: negative limit %d", address, filter.Limit)
+	}
+
+	entries := make([]models.WalletHistoryEntry, 0, filter.Limit)
+
+	if _, ok := s.wallets[address]; !ok {
+		return entries, nil
+	}
+
+	// Баланс после операции берётся из проводки кошелька, как и в PostgreSQL
+	balances := make(map[int64]models.Amount)
+	for _, e := range s.ledger {
+		if e.account == address && e.transactionId != nil {
+			balances[*e.transactionId] = e.balanceAfter
+		}
+	}
+
+	for _, t := range s.sorted() {
+		if len(entries) == filter.Limit {
+			break
+		}
+
+		var e models.WalletHistoryEntry
+		switch address {
+		case t.From:
+			e = models.WalletHistoryEntry{Direction: models.DirectionDebit, Counterparty: t.To}
+		case t.To:
+			e = models.WalletHistoryEntry{Direction: models.DirectionCredit, Counterparty: t.From}
+		default:
+			continue
+		}
+		e.TransactionId, e.Amount, e.Currency, e.BalanceAfter, e.CreatedAt = t.Id, t.Amount, t.Currency, balances[t.Id], t.CreatedAt
+		// Получателю конвертации зачислена сумма в его валюте
+		if e.Direction == models.DirectionCredit && t.Conversion != nil {
+			e.Amount, e.Currency = t.Conversion.ToAmount, t.Conversion.ToCurrency
+		}
+
+		if inWindow(t.CreatedAt, t.Id, filter.Since, filter.Until, filter.After) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+// Verify сверяет балансы кошельков с журналом проводок
+func (s *Store) Verify(ctx context.Context) (*models.LedgerReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report := models.LedgerReport{
+		Totals:         make(map[models.Currency]models.Amount),
+		SystemBalances: make(map[models.Currency]map[string]models.Amount),
+	}
+
+	totals := make(map[string]models.Amount)
+	for _, e := range s.ledger {
+		report.Totals[e.currency] = report.Totals[e.currency].Add(e.amount)
+		// Системные счета (начинаются с '@') в сверку с кошельками не входят
+		if strings.HasPrefix(e.account, "@") {
+			system := report.SystemBalances[e.currency]
+			if system == nil {
+				system = make(map[string]models.Amount)
+				report.SystemBalances[e.currency] = system
+			}
+			system[e.account] = system[e.account].Add(e.amount)
+		} else {
+			totals[e.account] = totals[e.account].Add(e.amount)
+		}
+	}
+
+	// Кошельки без проводок тоже сверяются: их баланс должен быть нулевым
+	for address := range s.wallets {
+		if _, ok := totals[address]; !ok {
+			totals[address] = models.Amount{}
+		}
+	}
+
+	for account, total := range totals {
+		var balance models.Amount
+		if w, ok := s.wallets[account]; ok {
+			balance = w.Balance
+		}
+		if balance.Cmp(total) != 0 {
+			report.Mismatches = append(report.Mismatches, models.LedgerMismatch{Address: account, Balance: balance, LedgerBalance: total})
+		}
+	}
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Address < report.Mismatches[j].Address
+	})
+
+	return &report, nil
+}
+
+// sorted возвращает транзакции в порядке (created_at, id) по убыванию
+func (s *Store) sorted() []*models.Transaction {
+	transactions := make([]*models.Transaction, 0, len(s.transactions))
+	for _, t := range s.transactions {
+		transactions = append(transactions, t)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		a, b := transactions[i], transactions[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.Id > b.Id
+	})
+	return transactions
+}
+
+// inWindow проверяет интервал [since, until) и положение строго после курсора after
+func inWindow(createdAt time.Time, id int64, since, until *time.Time, after *models.TransactionCursor) bool {
+	if since != nil && createdAt.Before(*since) {
+		return false
+	}
+	if until != nil && !createdAt.Before(*until) {
+		return false
+	}
+	if after != nil {
+		if createdAt.After(after.CreatedAt) {
+			return false
+		}
+		if createdAt.Equal(after.CreatedAt) && id >= after.Id {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// Менеджер для клиентов и их API-ключей
+type ClientRepository struct {
+	db *DB
+}
+
+func NewClientRepository(db *DB) *ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+// clientColumns — столбцы клиента в порядке, который ожидает clientDest
+const clientColumns = `id, name, admin, subject, created_at`
+
+func clientDest(c *models.Client) []interface{} {
+	return []interface{}{&c.Id, &c.Name, &c.Admin, &c.Subject, timestamp{&c.CreatedAt}}
+}
+
+// apiKeyColumns — столбцы ключа в порядке, который ожидает apiKeyDest
+const apiKeyColumns = `id, client_id, prefix, key_hash, created_at, revoked_at`
+
+func apiKeyDest(k *models.APIKey) []interface{} {
+	return []interface{}{&k.Id, &k.ClientId, &k.Prefix, &k.Hash, timestamp{&k.CreatedAt}, nullTimestamp{&k.RevokedAt}}
+}
+
+// CreateClient создаёт клиента вместе с его первым ключом, заданным префиксом и хэшем
+func (cr *ClientRepository) CreateClient(ctx context.Context, name string, admin bool, prefix, hash string) (*models.Client, *models.APIKey, error) {
+	tx, err := cr.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO clients (name, admin, created_at) VALUES (?, ?, ?)
+              RETURNING ` + clientColumns
+
+	var c models.Client
+
+	err = tx.QueryRow(ctx, query, name, admin, formatTime(now())).Scan(clientDest(&c)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	k, err := insertAPIKey(ctx, tx, c.Id, prefix, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	return &c, k, nil
+}
+
+func (cr *ClientRepository) GetClient(ctx context.Context, id int64) (*models.Client, error) {
+	query := `SELECT ` + clientColumns + ` FROM clients WHERE id = ?`
+
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, id).Scan(clientDest(&c)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "client", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to find client with id %v: %w", id, err)
+	}
+
+	return &c, nil
+}
+
+// EnsureSubjectClient возвращает клиента с субъектом JWT subject, создавая его при первом обращении
+func (cr *ClientRepository) EnsureSubjectClient(ctx context.Context, subject string) (*models.Client, error) {
+	// DO UPDATE без изменений нужен, чтобы RETURNING вернул уже существующую строку
+	query := `INSERT INTO clients (name, subject, created_at) VALUES (?1, ?1, ?2)
+              ON CONFLICT (subject) DO UPDATE SET subject = excluded.subject
+              RETURNING ` + clientColumns
+
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, subject, formatTime(now())).Scan(clientDest(&c)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure client for subject %v: %w", subject, err)
+	}
+
+	return &c, nil
+}
+
+// CreateAPIKey сохраняет ключ клиента clientId по его префиксу и хэшу
+func (cr *ClientRepository) CreateAPIKey(ctx context.Context, clientId int64, prefix, hash string) (*models.APIKey, error) {
+	return insertAPIKey(ctx, cr.db, clientId, prefix, hash)
+}
+
+func insertAPIKey(ctx context.Context, q querier, clientId int64, prefix, hash string) (*models.APIKey, error) {
+	query := `INSERT INTO api_keys (client_id, prefix, key_hash, created_at) VALUES (?, ?, ?, ?)
+              RETURNING ` + apiKeyColumns
+
+	var k models.APIKey
+
+	err := q.QueryRow(ctx, query, clientId, prefix, hash, formatTime(now())).Scan(apiKeyDest(&k)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key for client %v: %w", clientId, err)
+	}
+
+	return &k, nil
+}
+
+// GetAPIKeyByPrefix возвращает ключ с указанным префиксом вместе с его клиентом
+func (cr *ClientRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, *models.Client, error) {
+	query := `SELECT k.id, k.client_id, k.prefix, k.key_hash, k.created_at, k.revoked_at,
+                     c.id, c.name, c.admin, c.subject, c.created_at
+              FROM api_keys k
+              JOIN clients c ON c.id = k.client_id
+              WHERE k.prefix = ?`
+
+	var k models.APIKey
+	var c models.Client
+
+	err := cr.db.QueryRow(ctx, query, prefix).Scan(append(apiKeyDest(&k), clientDest(&c)...)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, &repository.NotFoundError{Entity: "api key", Field: "prefix", Key: prefix}
+		}
+		return nil, nil, fmt.Errorf("failed to find api key with prefix %v: %w", prefix, err)
+	}
+
+	return &k, &c, nil
+}
+
+// RevokeAPIKey отзывает ключ; повторный отзыв не меняет время первого
+func (cr *ClientRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`
+
+	result, err := cr.db.Exec(ctx, query, formatTime(now()), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key with id %v: %w", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key with id %v: %w", id, err)
+	}
+	if affected == 0 {
+		return &repository.NotFoundError{Entity: "api key", Field: "id", Key: id}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package sqlite содержит репозитории поверх встроенной базы SQLite. Они повторяют семантику
+// репозиториев PostgreSQL и выбираются ключом database.driver: sqlite.
+//
+// Суммы хранятся целым числом минимальных единиц, время — строкой фиксированной ширины в UTC,
+// поэтому сравнение строк совпадает с хронологическим порядком. Транзакции начинаются
+// с BEGIN IMMEDIATE (см. database.InitSQLite): писатель в базе один, и блокировка на запись,
+// взятая в начале транзакции, заменяет SELECT ... FOR UPDATE.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// timeFormat совпадает с форматом миграций SQLite; микросекунды — точность TIMESTAMP в PostgreSQL
+const timeFormat = "2006-01-02 15:04:05.000000"
+
+var (
+	_ repository.WalletStore      = (*WalletRepository)(nil)
+	_ repository.TransactionStore = (*TransactionRepository)(nil)
+	_ repository.HoldStore        = (*HoldRepository)(nil)
+	_ repository.LedgerStore      = (*LedgerRepository)(nil)
+	_ repository.IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ repository.ClientStore      = (*ClientRepository)(nil)
+)
+
+// DB — соединения с базой SQLite, трассирующие каждый SQL-запрос
+type DB struct {
+	db *sql.DB
+}
+
+func NewDB(db *sql.DB) *DB {
+	return &DB{db: db}
+}
+
+// conn — *sql.DB или открытая на нём транзакция
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// querier — DB или Tx
+type querier interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Query(ctx context.Context, query string, args ...interface{}) (*tracedRows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) tracedRow
+}
+
+func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return exec(ctx, db.db, query, args)
+}
+
+func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*tracedRows, error) {
+	return queryRows(ctx, db.db, query, args)
+}
+
+func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) tracedRow {
+	return queryRow(ctx, db.db, query, args)
+}
+
+// Begin начинает транзакцию, сразу занимающую базу на запись
+func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+// Ping проверяет, что база данных отвечает
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Stats возвращает текущее состояние пула соединений database/sql
+func (db *DB) Stats() models.PoolStats {
+	stat := db.db.Stats()
+	return models.PoolStats{
+		TotalConns:             int32(stat.OpenConnections),
+		AcquiredConns:          int32(stat.InUse),
+		IdleConns:              int32(stat.Idle),
+		MaxConns:               int32(stat.MaxOpenConnections),
+		AcquireDurationSeconds: stat.WaitDuration.Seconds(),
+		EmptyAcquireCount:      stat.WaitCount,
+	}
+}
+
+// Tx — транзакция, трассирующая запросы так же, как DB
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (tx *Tx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return exec(ctx, tx.tx, query, args)
+}
+
+func (tx *Tx) Query(ctx context.Context, query string, args ...interface{}) (*tracedRows, error) {
+	return queryRows(ctx, tx.tx, query, args)
+}
+
+func (tx *Tx) QueryRow(ctx context.Context, query string, args ...interface{}) tracedRow {
+	return queryRow(ctx, tx.tx, query, args)
+}
+
+func (tx *Tx) Commit() error {
+	return tx.tx.Commit()
+}
+
+// Rollback откатывает транзакцию; после Commit ничего не делает
+func (tx *Tx) Rollback() error {
+	return tx.tx.Rollback()
+}
+
+// now возвращает текущее время с точностью, в которой оно хранится
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// formatTime переводит время в строку, в которой оно хранится в базе
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// timestamp читает время в dst. Драйвер сам разбирает столбцы типа TIMESTAMP,
+// а выражения и столбцы подзапросов приходят строкой.
+type timestamp struct {
+	dst *time.Time
+}
+
+func (t timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t.dst = v.UTC()
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	return nil
+}
+
+func (t timestamp) parse(s string) error {
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999", s)
+	if err != nil {
+		return fmt.Errorf("cannot parse time %q: %w", s, err)
+	}
+	*t.dst = parsed.UTC()
+	return nil
+}
+
+// nullTimestamp читает время, допускающее NULL
+type nullTimestamp struct {
+	dst **time.Time
+}
+
+func (t nullTimestamp) Scan(src interface{}) error {
+	if src == nil {
+		*t.dst = nil
+		return nil
+	}
+
+	var v time.Time
+	if err := (timestamp{&v}).Scan(src); err != nil {
+		return err
+	}
+	*t.dst = &v
+	return nil
+}
+
+// amount читает сумму, хранящуюся целым числом минимальных единиц.
+// models.Amount.Scan для этого не подходит: целое число он считает целыми единицами.
+type amount struct {
+	dst *models.Amount
+}
+
+func (a amount) Scan(src interface{}) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("%w: cannot scan %T", models.ErrInvalidAmount, src)
+	}
+	*a.dst = models.NewAmount(v)
+	return nil
+}
+
+// nullAmount читает сумму, допускающую NULL
+type nullAmount struct {
+	dst **models.Amount
+}
+
+func (a nullAmount) Scan(src interface{}) error {
+	if src == nil {
+		*a.dst = nil
+		return nil
+	}
+
+	var v models.Amount
+	if err := (amount{&v}).Scan(src); err != nil {
+		return err
+	}
+	*a.dst = &v
+	return nil
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// Менеджер для холдов
+type HoldRepository struct {
+	db *DB
+}
+
+func NewHoldRepository(db *DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+// holdColumns — столбцы холда в порядке, который ожидает scanHold
+const holdColumns = `id, from_wallet, to_wallet, amount, currency, status, expires_at, created_at,
+	closed_at, captured_amount, transaction_id`
+
+func scanHold(row rowScanner, h *models.Hold) error {
+	return row.Scan(&h.Id, &h.From, &h.To, amount{&h.Amount}, &h.Currency, &h.Status, timestamp{&h.ExpiresAt}, timestamp{&h.CreatedAt},
+		nullTimestamp{&h.ClosedAt}, nullAmount{&h.CapturedAmount}, &h.TransactionId)
+}
+
+// CreateHold резервирует amount на кошельке from для перевода на кошелёк to
+func (hr *HoldRepository) CreateHold(ctx context.Context, from, to string, value models.Amount, expiresAt time.Time, check repository.TransferCheck) (*models.Hold, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallets, err := getWallets(ctx, tx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(wallets[from], wallets[to]); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE wallets SET held = held + ? WHERE address = ?`, value.Minor(), from)
+	if err != nil {
+		return nil, fmt.Errorf("sender held amount update failed: %w", err)
+	}
+
+	var hold models.Hold
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO holds (from_wallet, to_wallet, amount, currency, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING `+holdColumns,
+		from, to, value.Minor(), string(wallets[from].Currency), formatTime(expiresAt), formatTime(now()),
+	)
+	if err := scanHold(row, &hold); err != nil {
+		return nil, fmt.Errorf("hold record failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold committed", "hold_id", hold.Id)
+
+	return &hold, nil
+}
+
+func (hr *HoldRepository) GetHold(ctx context.Context, id int64) (*models.Hold, error) {
+	return getHold(ctx, hr.db, id)
+}
+
+// getHold читает холд; внутри транзакции строка уже защищена блокировкой базы на запись
+func getHold(ctx context.Context, q querier, id int64) (*models.Hold, error) {
+	var hold models.Hold
+
+	err := scanHold(q.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = ?`, id), &hold)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "hold", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to find hold with id %v: %w", id, err)
+	}
+
+	return &hold, nil
+}
+
+// CaptureHold переводит amount по холду id и закрывает холд в той же транзакции
+func (hr *HoldRepository) CaptureHold(ctx context.Context, id int64, value models.Amount, checkHold repository.HoldCheck, check repository.TransferCheck) (*models.Hold, *models.Transaction, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := getHold(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if checkHold != nil {
+		if err := checkHold(hold); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Весь холд освобождается тем же запросом, что списывает amount
+	t, err := transfer(ctx, tx, models.Transaction{From: hold.From, To: hold.To, Amount: value}, models.LedgerEntryTransfer, hold.Amount, check)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	row := tx.QueryRow(ctx,
+		`UPDATE holds SET status = ?, closed_at = ?, captured_amount = ?, transaction_id = ?
+		WHERE id = ? AND status = ? RETURNING `+holdColumns,
+		string(models.HoldCaptured), formatTime(now()), value.Minor(), t.Id, id, string(models.HoldActive),
+	)
+	if err := scanHold(row, hold); err != nil {
+		return nil, nil, fmt.Errorf("failed to close hold %v: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold capture committed", "hold_id", id, "transaction_id", t.Id)
+
+	return hold, t, nil
+}
+
+// ReleaseHold закрывает холд id в состоянии status и возвращает его сумму в доступный баланс
+func (hr *HoldRepository) ReleaseHold(ctx context.Context, id int64, status models.HoldStatus, checkHold repository.HoldCheck) (*models.Hold, error) {
+	tx, err := hr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := getHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if checkHold != nil {
+		if err := checkHold(hold); err != nil {
+			return nil, err
+		}
+	}
+
+	row := tx.QueryRow(ctx,
+		`UPDATE holds SET status = ?, closed_at = ? WHERE id = ? AND status = ? RETURNING `+holdColumns,
+		string(status), formatTime(now()), id, string(models.HoldActive),
+	)
+	if err := scanHold(row, hold); err != nil {
+		return nil, fmt.Errorf("failed to close hold %v: %w", id, err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE wallets SET held = held - ? WHERE address = ?`, hold.Amount.Minor(), hold.From)
+	if err != nil {
+		return nil, fmt.Errorf("sender held amount update failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "hold release committed", "hold_id", id, "status", status)
+
+	return hold, nil
+}
+
+func (hr *HoldRepository) ListExpiredHolds(ctx context.Context, at time.Time, limit int) ([]int64, error) {
+	rows, err := hr.db.Query(ctx,
+		`SELECT id FROM holds WHERE status = ? AND expires_at <= ? ORDER BY expires_at, id LIMIT ?`,
+		string(models.HoldActive), formatTime(at), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan hold id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	return ids, nil
+}
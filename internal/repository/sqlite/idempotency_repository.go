@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// Менеджер для ключей идемпотентности
+type IdempotencyRepository struct {
+	db *DB
+}
+
+func NewIdempotencyRepository(db *DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve атомарно занимает ключ под новый запрос. Просроченный ключ занимается заново.
+// Если ключ уже занят, возвращается существующая запись и false.
+func (ir *IdempotencyRepository) Reserve(ctx context.Context, scope, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	query := `INSERT INTO idempotency_keys (scope, key, fingerprint, created_at, expires_at)
+              VALUES (?1, ?2, ?3, ?4, ?5)
+              ON CONFLICT (scope, key) DO UPDATE
+              SET fingerprint = excluded.fingerprint,
+                  status_code = NULL,
+                  response_headers = NULL,
+                  response_body = NULL,
+                  created_at = excluded.created_at,
+                  expires_at = excluded.expires_at
+              WHERE idempotency_keys.expires_at <= ?4
+              RETURNING created_at, expires_at`
+
+	record := models.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint}
+	createdAt := now()
+
+	err := ir.db.QueryRow(ctx, query, scope, key, fingerprint, formatTime(createdAt), formatTime(createdAt.Add(ttl))).Scan(
+		timestamp{&record.CreatedAt},
+		timestamp{&record.ExpiresAt},
+	)
+	if err == nil {
+		return &record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key %v: %w", key, err)
+	}
+
+	existing, err := ir.Get(ctx, scope, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (ir *IdempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	query := `SELECT fingerprint, status_code, response_headers, response_body, created_at, expires_at
+              FROM idempotency_keys WHERE scope = ? AND key = ?`
+
+	record := models.IdempotencyRecord{Scope: scope, Key: key}
+	var statusCode *int
+	var headers *string
+
+	err := ir.db.QueryRow(ctx, query, scope, key).Scan(
+		&record.Fingerprint,
+		&statusCode,
+		&headers,
+		&record.Body,
+		timestamp{&record.CreatedAt},
+		timestamp{&record.ExpiresAt},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "idempotency key", Field: "value", Key: key}
+		}
+		return nil, fmt.Errorf("failed to get idempotency key %v: %w", key, err)
+	}
+
+	if statusCode != nil {
+		record.StatusCode = *statusCode
+	}
+	if headers != nil {
+		if err := json.Unmarshal([]byte(*headers), &record.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode headers of idempotency key %v: %w", key, err)
+		}
+	}
+
+	return &record, nil
+}
+
+// Complete сохраняет ответ на запрос, выполненный под ключом
+func (ir *IdempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	query := `UPDATE idempotency_keys
+              SET status_code = ?, response_headers = ?, response_body = ?
+              WHERE scope = ? AND key = ?`
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode headers of idempotency key %v: %w", key, err)
+	}
+
+	_, err = ir.db.Exec(ctx, query, statusCode, string(encoded), body, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key %v: %w", key, err)
+	}
+
+	return nil
+}
+
+// Release освобождает ключ незавершённого запроса, чтобы его можно было повторить
+func (ir *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys
+              WHERE scope = ? AND key = ? AND status_code IS NULL`
+
+	_, err := ir.db.Exec(ctx, query, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key %v: %w", key, err)
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком; такие ключи Reserve и так занимает заново,
+// удаление лишь не даёт таблице расти
+func (ir *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= ?`
+
+	res, err := ir.db.Exec(ctx, query, formatTime(now()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return deleted, nil
+}
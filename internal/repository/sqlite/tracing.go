@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"TransactionSystem/internal/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "TransactionSystem/internal/repository/sqlite"
+
+// startQuery начинает спан SQL-запроса. Текст запроса записывается без параметров,
+// поэтому адреса и суммы в трассы не попадают.
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "SQL"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracing.Start(ctx, tracerName, operation,
+		semconv.DBSystemSqlite,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// endQuery завершает спан; отсутствие строк ошибкой запроса не считается
+func endQuery(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+func exec(ctx context.Context, c conn, query string, args []interface{}) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	result, err := c.ExecContext(ctx, query, args...)
+	endQuery(span, err)
+	return result, err
+}
+
+func queryRows(ctx context.Context, c conn, query string, args []interface{}) (*tracedRows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		endQuery(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func queryRow(ctx context.Context, c conn, query string, args []interface{}) tracedRow {
+	ctx, span := startQuery(ctx, query)
+	return tracedRow{Row: c.QueryRowContext(ctx, query, args...), span: span}
+}
+
+// tracedRows завершает спан запроса, когда строки прочитаны или закрыты
+type tracedRows struct {
+	*sql.Rows
+	span  trace.Span
+	ended bool
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.end()
+	return err
+}
+
+func (r *tracedRows) end() {
+	if !r.ended {
+		r.ended = true
+		endQuery(r.span, r.Rows.Err())
+	}
+}
+
+// tracedRow завершает спан запроса после Scan, когда результат уже получен
+type tracedRow struct {
+	*sql.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	endQuery(r.span, err)
+	return err
+}
@@ -0,0 +1,477 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// Менеджер для транзакций
+type TransactionRepository struct {
+	db *DB
+}
+
+func NewTransactionRepository(db *DB) *TransactionRepository {
+	return &TransactionRepository{db: db}
+}
+
+func (tr *TransactionRepository) ExecuteTransfer(ctx context.Context, from, to string, amount models.Amount, check repository.TransferCheck) (*models.Transaction, error) {
+	tx, err := tr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := transfer(ctx, tx, models.Transaction{From: from, To: to, Amount: amount}, models.LedgerEntryTransfer, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "transfer committed", "transaction_id", t.Id)
+
+	return t, nil
+}
+
+// ExecuteConversion списывает amount с кошелька from и зачисляет conversion.ToAmount на кошелёк to.
+// Обе части перевода проходят через системный счёт models.FXAccount.
+func (tr *TransactionRepository) ExecuteConversion(ctx context.Context, from, to string, amount models.Amount, conversion models.Conversion, check repository.TransferCheck) (*models.Transaction, error) {
+	tx, err := tr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := transfer(ctx, tx, models.Transaction{From: from, To: to, Amount: amount, Conversion: &conversion}, models.LedgerEntryConversion, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "conversion committed", "transaction_id", t.Id)
+
+	return t, nil
+}
+
+// ReverseTransfer сторнирует транзакцию id: создаёт компенсирующий перевод от получателя
+// к отправителю на ту же сумму и помечает исходную транзакцию как сторнированную.
+// checkOriginal проверяет исходную транзакцию, check — кошельки компенсирующего перевода.
+func (tr *TransactionRepository) ReverseTransfer(ctx context.Context, id int64, reason string, checkOriginal repository.ReversalCheck, check repository.TransferCheck) (*models.Transaction, error) {
+	tx, err := tr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Транзакция уже владеет блокировкой базы на запись, поэтому параллельное
+	// сторнирование той же транзакции увидит reversed_by, записанный здесь
+	var original models.Transaction
+
+	row := tx.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, id)
+	if err := scanTransaction(row, &original); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "transaction", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to lock transaction with id %v: %w", id, err)
+	}
+
+	if checkOriginal != nil {
+		if err := checkOriginal(&original); err != nil {
+			return nil, err
+		}
+	}
+
+	reversal, err := transfer(ctx, tx, models.Transaction{
+		From:       original.To,
+		To:         original.From,
+		Amount:     original.Amount,
+		ReversalOf: &original.Id,
+		Reason:     reason,
+	}, models.LedgerEntryReversal, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE transactions SET reversed_by = ? WHERE id = ?`, reversal.Id, original.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark transaction %v as reversed: %w", original.Id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "reversal committed", "transaction_id", reversal.Id, "reversal_of", original.Id)
+
+	return reversal, nil
+}
+
+// transfer переводит t.Amount с кошелька t.From на кошелёк t.To внутри транзакции БД tx
+// и записывает транзакцию вместе с проводками вида kind. Если задана t.Conversion,
+// получателю зачисляется t.Conversion.ToAmount. release — сумма холда, которая снимается
+// с кошелька отправителя вместе со списанием (ноль, если перевод не списывает холд).
+func transfer(ctx context.Context, tx *Tx, t models.Transaction, kind models.LedgerEntryKind, release models.Amount, check repository.TransferCheck) (*models.Transaction, error) {
+	from, to, value := t.From, t.To, t.Amount
+	credit := value
+	if t.Conversion != nil {
+		credit = t.Conversion.ToAmount
+	}
+
+	wallets, err := getWallets(ctx, tx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "wallets locked", "from", from, "to", to,
+		"from_balance", wallets[from].Balance, "to_balance", wallets[to].Balance)
+
+	if check != nil {
+		if err := check(wallets[from], wallets[to]); err != nil {
+			slog.DebugContext(ctx, "transfer check rejected", "from", from, "to", to, "amount", value, "error", err)
+			return nil, err
+		}
+	}
+
+	var fromBalance, toBalance models.Amount
+
+	// Обновляем балансы относительно текущих значений, а не перезаписываем их.
+	// Холд снимается тем же запросом, чтобы триггер chk_wallet_held видел итоговые значения.
+	err = tx.QueryRow(ctx,
+		`UPDATE wallets SET balance = balance - ?, held = held - ? WHERE address = ? RETURNING balance`,
+		value.Minor(), release.Minor(), from,
+	).Scan(amount{&fromBalance})
+	if err != nil {
+		return nil, fmt.Errorf("sender balance update failed: %w", err)
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE wallets SET balance = balance + ? WHERE address = ? RETURNING balance`,
+		credit.Minor(), to,
+	).Scan(amount{&toBalance})
+	if err != nil {
+		return nil, fmt.Errorf("receiver balance update failed: %w", err)
+	}
+
+	// Создаем запись о транзакции в валюте отправителя; совпадение валют проверяет check
+	var rate, toAmount, toCurrency, residue, quoteId interface{}
+	if c := t.Conversion; c != nil {
+		rate, toAmount, toCurrency, residue, quoteId = c.Rate.String(), c.ToAmount.Minor(), string(c.ToCurrency), c.Residue.String(), c.QuoteId
+	}
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO transactions
+		(from_wallet, to_wallet, amount, currency, created_at, reversal_of, reason, rate, to_amount, to_currency, residue, quote_id)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''))
+		RETURNING `+transactionColumns,
+		from, to, value.Minor(), string(wallets[from].Currency), formatTime(now()), t.ReversalOf, t.Reason,
+		rate, toAmount, toCurrency, residue, quoteId,
+	)
+	if err := scanTransaction(row, &t); err != nil {
+		return nil, fmt.Errorf("transaction record failed: %w", err)
+	}
+
+	// Журнал проводок — источник истины, балансы кошельков — его проекция.
+	// Конвертация проводится двумя парами: в валюте отправителя и в валюте получателя.
+	if t.Conversion == nil {
+		err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Currency, from, to, value)
+	} else {
+		err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Currency, from, models.FXAccount, value)
+		if err == nil {
+			err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Conversion.ToCurrency, models.FXAccount, to, credit)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.FromBalance = &fromBalance
+	t.ToBalance = &toBalance
+
+	slog.DebugContext(ctx, "transfer recorded", "transaction_id", t.Id, "kind", kind,
+		"from", from, "to", to, "amount", value, "credit", credit, "from_balance", fromBalance, "to_balance", toBalance)
+
+	return &t, nil
+}
+
+// getWallets читает кошельки from и to в том же порядке, в каком их блокирует PostgreSQL,
+// чтобы при отсутствии обоих ошибка называла тот же кошелёк
+func getWallets(ctx context.Context, tx *Tx, from, to string) (map[string]*models.Wallet, error) {
+	first, second := from, to
+	if second < first {
+		first, second = second, first
+	}
+
+	wallets := make(map[string]*models.Wallet, 2)
+	for _, address := range []string{first, second} {
+		wallet, err := getWallet(ctx, tx, address)
+		if err != nil {
+			return nil, err
+		}
+		wallets[address] = wallet
+	}
+	return wallets, nil
+}
+
+// transactionColumns — столбцы транзакции в порядке, который ожидает scanTransaction
+const transactionColumns = `id, from_wallet, to_wallet, amount, currency, created_at, reversal_of, reversed_by, reason,
+	rate, to_amount, to_currency, residue, quote_id`
+
+// rowScanner — tracedRow или tracedRows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner, t *models.Transaction) error {
+	var (
+		reason, toCurrency, quoteId *string
+		rate                        *models.Rate
+		toAmount                    *models.Amount
+		residue                     *models.Residue
+	)
+
+	err := row.Scan(&t.Id, &t.From, &t.To, amount{&t.Amount}, &t.Currency, timestamp{&t.CreatedAt}, &t.ReversalOf, &t.ReversedBy, &reason,
+		&rate, nullAmount{&toAmount}, &toCurrency, &residue, &quoteId)
+	if err != nil {
+		return err
+	}
+
+	if reason != nil {
+		t.Reason = *reason
+	}
+
+	t.Conversion = nil
+	if rate != nil && toAmount != nil && toCurrency != nil && residue != nil {
+		t.Conversion = &models.Conversion{Rate: *rate, ToAmount: *toAmount, ToCurrency: models.Currency(*toCurrency), Residue: *residue}
+		if quoteId != nil {
+			t.Conversion.QuoteId = *quoteId
+		}
+	}
+
+	return nil
+}
+
+func (tr *TransactionRepository) GetTransactionById(ctx context.Context, id int64) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = ?`
+
+	var t models.Transaction
+
+	err := scanTransaction(tr.db.QueryRow(ctx, query, id), &t)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "transaction", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to find transaction with id %v: %w", id, err)
+	}
+
+	return &t, nil
+}
+
+func (tr *TransactionRepository) GetTransactionByInfo(ctx context.Context, from, to string, createdAt time.Time) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + `
+              FROM transactions
+              WHERE from_wallet = ? AND to_wallet = ? AND created_at = ?`
+
+	var t models.Transaction
+
+	err := scanTransaction(tr.db.QueryRow(ctx, query, from, to, formatTime(createdAt)), &t)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{
+				Entity: "transaction",
+				Field:  "from_wallet/to_wallet/created_at",
+				Key:    fmt.Sprintf("%v/%v/%v", from, to, createdAt.Format(time.RFC3339Nano)),
+			}
+		}
+		return nil, fmt.Errorf("failed to find transaction for from_wallet %v, to_wallet %v at %v: %w", from, to, createdAt, err)
+	}
+
+	return &t, nil
+}
+
+func (tr *TransactionRepository) RemoveTransaction(ctx context.Context, id int64) error {
+	query := `DELETE FROM transactions WHERE id = ?`
+
+	result, err := tr.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction with id %v: %w", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction with id %v: %w", id, err)
+	}
+	if affected == 0 {
+		return &repository.NotFoundError{Entity: "transaction", Field: "id", Key: id}
+	}
+
+	return nil
+}
+
+func (tr *TransactionRepository) GetLastTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + `
+              FROM transactions
+              ORDER BY created_at DESC
+              LIMIT ?`
+
+	return tr.queryTransactions(ctx, limit, query, limit)
+}
+
+// ListTransactions возвращает транзакции, подходящие под фильтр, упорядоченные по (created_at, id)
+// по убыванию. Постраничный вывод выполняется по ключу (keyset), без OFFSET.
+func (tr *TransactionRepository) ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	// where добавляет условие; %[1]d в тексте условия заменяется номером параметра
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Wallet != "" {
+		switch filter.WalletRole {
+		case models.WalletRoleSender:
+			where("from_wallet = ?%[1]d", filter.Wallet)
+		case models.WalletRoleReceiver:
+			where("to_wallet = ?%[1]d", filter.Wallet)
+		default:
+			where("(from_wallet = ?%[1]d OR to_wallet = ?%[1]d)", filter.Wallet)
+		}
+	}
+	if filter.Owner != nil {
+		where(`(from_wallet IN (SELECT address FROM wallets WHERE owner_id = ?%[1]d)
+              OR to_wallet IN (SELECT address FROM wallets WHERE owner_id = ?%[1]d))`, *filter.Owner)
+	}
+	if filter.MinAmount != nil {
+		where("amount >= ?%[1]d", filter.MinAmount.Minor())
+	}
+	if filter.MaxAmount != nil {
+		where("amount <= ?%[1]d", filter.MaxAmount.Minor())
+	}
+	if filter.Since != nil {
+		where("created_at >= ?%[1]d", formatTime(*filter.Since))
+	}
+	if filter.Until != nil {
+		where("created_at < ?%[1]d", formatTime(*filter.Until))
+	}
+	if filter.After != nil {
+		args = append(args, formatTime(filter.After.CreatedAt), filter.After.Id)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (?%d, ?%d)", len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + transactionColumns + `
+              FROM transactions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT ?%d", len(args))
+
+	return tr.queryTransactions(ctx, filter.Limit, query, args...)
+}
+
+func (tr *TransactionRepository) queryTransactions(ctx context.Context, limit int, query string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := tr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0, limit)
+
+	for rows.Next() {
+		var t models.Transaction
+
+		if err := scanTransaction(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// GetWalletHistory возвращает транзакции кошелька от новых к старым вместе с балансом
+// кошелька после каждой из них. Баланс хранится в проводке кошелька (ledger_entries.balance_after),
+// поэтому в нём учтены корректировки и удалённые транзакции, а страница не требует чтения всей истории.
+func (tr *TransactionRepository) GetWalletHistory(ctx context.Context, address string, filter models.WalletHistoryFilter) ([]models.WalletHistoryEntry, error) {
+	args := []interface{}{address}
+	var conditions []string
+
+	if filter.Since != nil {
+		args = append(args, formatTime(*filter.Since))
+		conditions = append(conditions, fmt.Sprintf("created_at >= ?%d", len(args)))
+	}
+	if filter.Until != nil {
+		args = append(args, formatTime(*filter.Until))
+		conditions = append(conditions, fmt.Sprintf("created_at < ?%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, formatTime(filter.After.CreatedAt), filter.After.Id)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (?%d, ?%d)", len(args)-1, len(args)))
+	}
+
+	// Страница выбирается по транзакциям кошелька, баланс после операции
+	// берётся из проводки кошелька по этой транзакции
+	query := `WITH entries AS (
+                  SELECT id, 'debit' AS direction, to_wallet AS counterparty, amount, currency, created_at
+                  FROM transactions WHERE from_wallet = ?1
+                  UNION ALL
+                  SELECT id, 'credit' AS direction, from_wallet AS counterparty,
+                         COALESCE(to_amount, amount) AS amount, COALESCE(to_currency, currency) AS currency, created_at
+                  FROM transactions WHERE to_wallet = ?1
+              )
+              SELECT e.id, e.direction, e.counterparty, e.amount, e.currency, l.balance_after, e.created_at
+              FROM (SELECT * FROM entries`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT ?%d) e
+              JOIN ledger_entries l ON l.transaction_id = e.id AND l.account = ?1
+              ORDER BY e.created_at DESC, e.id DESC`, len(args))
+
+	rows, err := tr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of wallet %v: %w", address, err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.WalletHistoryEntry, 0, filter.Limit)
+
+	for rows.Next() {
+		var e models.WalletHistoryEntry
+
+		err := rows.Scan(&e.TransactionId, &e.Direction, &e.Counterparty, amount{&e.Amount}, &e.Currency, amount{&e.BalanceAfter}, timestamp{&e.CreatedAt})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return entries, nil
+}
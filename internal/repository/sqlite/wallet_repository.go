@@ -0,0 +1,214 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// Менеджер для кошельков
+type WalletRepository struct {
+	db *DB
+}
+
+func NewWalletRepository(db *DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// CreateWallet создаёт кошелёк в валюте currency клиента owner (nil — без владельца).
+// Ненулевой начальный баланс отражается в журнале проводкой со счёта models.IssuanceAccount.
+func (wr *WalletRepository) CreateWallet(ctx context.Context, address string, currency models.Currency, balance models.Amount, owner *int64) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO wallets (address, balance, currency, owner_id, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err = tx.Exec(ctx, query, address, balance.Minor(), string(currency), owner, formatTime(now()))
+	if err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	if !balance.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryOpening, currency, models.IssuanceAccount, address, balance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// walletColumns — столбцы кошелька в порядке, который ожидает scanWallet
+const walletColumns = `address, balance, held, currency, status, owner_id, created_at`
+
+func scanWallet(row tracedRow, w *models.Wallet) error {
+	return row.Scan(&w.Address, amount{&w.Balance}, amount{&w.Held}, &w.Currency, &w.Status, &w.Owner, timestamp{&w.CreatedAt})
+}
+
+func (wr *WalletRepository) GetWallet(ctx context.Context, address string) (*models.Wallet, error) {
+	return getWallet(ctx, wr.db, address)
+}
+
+// getWallet читает кошелёк; внутри транзакции строка уже защищена блокировкой базы на запись
+func getWallet(ctx context.Context, q querier, address string) (*models.Wallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM wallets WHERE address = ?`
+
+	var w models.Wallet
+
+	err := scanWallet(q.QueryRow(ctx, query, address), &w)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Entity: "wallet", Field: "address", Key: address}
+		}
+		return nil, fmt.Errorf("failed to find wallet with address %v: %w", address, err)
+	}
+
+	return &w, nil
+}
+
+// UpdateWalletBalabnce устанавливает баланс кошелька. Разница со старым балансом
+// записывается в журнал корректирующей проводкой.
+func (wr *WalletRepository) UpdateWalletBalabnce(ctx context.Context, address string, balance models.Amount) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := getWallet(ctx, tx, address)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE wallets SET balance = ? WHERE address = ?`
+
+	_, err = tx.Exec(ctx, query, balance.Minor(), address)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet with address %v: %w", address, err)
+	}
+
+	if delta := balance.Sub(wallet.Balance); !delta.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryAdjustment, wallet.Currency, models.IssuanceAccount, address, delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateWalletStatus переводит кошелёк в состояние status, если это разрешает check
+func (wr *WalletRepository) UpdateWalletStatus(ctx context.Context, address string, status models.WalletStatus, check repository.WalletCheck) (*models.Wallet, error) {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := getWallet(ctx, tx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(wallet); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `UPDATE wallets SET status = ? WHERE address = ?`
+
+	_, err = tx.Exec(ctx, query, string(status), address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update status of wallet %v: %w", address, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	wallet.Status = status
+	return wallet, nil
+}
+
+// RemoveWallet удаляет кошелёк, если это разрешает check. Остаток баланса возвращается
+// в журнале на счёт models.IssuanceAccount, чтобы журнал оставался сбалансированным.
+func (wr *WalletRepository) RemoveWallet(ctx context.Context, address string, check repository.WalletCheck) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := getWallet(ctx, tx, address)
+	if err != nil {
+		return err
+	}
+
+	if check != nil {
+		if err := check(wallet); err != nil {
+			return err
+		}
+	}
+
+	if !wallet.Balance.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryClosing, wallet.Currency, address, models.IssuanceAccount, wallet.Balance)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `DELETE FROM wallets WHERE address = ?`
+
+	_, err = tx.Exec(ctx, query, address)
+	if err != nil {
+		return fmt.Errorf("failed to delete wallet with address %v: %w", address, err)
+	}
+
+	return tx.Commit()
+}
+
+func (wr *WalletRepository) IsEmpty(ctx context.Context) (bool, error) {
+	query := `SELECT COUNT(*) FROM wallets`
+
+	var count int
+	err := wr.db.QueryRow(ctx, query).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check wallets: %w", err)
+	}
+
+	return count == 0, nil
+}
+
+// CountWalletsByStatus возвращает число кошельков в каждом состоянии
+func (wr *WalletRepository) CountWalletsByStatus(ctx context.Context) (map[models.WalletStatus]int64, error) {
+	query := `SELECT status, COUNT(*) FROM wallets GROUP BY status`
+
+	rows, err := wr.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count wallets: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.WalletStatus]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet count: %w", err)
+		}
+		counts[models.WalletStatus(status)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while fetching rows: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"TransactionSystem/internal/models"
+)
+
+// WalletStore — хранилище кошельков, с которым работают сервисы.
+// Реализации должны вести себя одинаково: отсутствующий кошелёк даёт *NotFoundError,
+// проверки WalletCheck выполняются атомарно с изменением.
+type WalletStore interface {
+	CreateWallet(ctx context.Context, address string, currency models.Currency, balance models.Amount, owner *int64) error
+	GetWallet(ctx context.Context, address string) (*models.Wallet, error)
+	UpdateWalletBalabnce(ctx context.Context, address string, balance models.Amount) error
+	UpdateWalletStatus(ctx context.Context, address string, status models.WalletStatus, check WalletCheck) (*models.Wallet, error)
+	RemoveWallet(ctx context.Context, address string, check WalletCheck) error
+	IsEmpty(ctx context.Context) (bool, error)
+	CountWalletsByStatus(ctx context.Context) (map[models.WalletStatus]int64, error)
+}
+
+// TransactionStore — хранилище транзакций. Переводы атомарны: изменение обоих балансов,
+// запись транзакции и проводок либо выполняются целиком, либо не выполняются вовсе.
+// Списки упорядочены по (created_at, id) по убыванию.
+type TransactionStore interface {
+	ExecuteTransfer(ctx context.Context, from, to string, amount models.Amount, check TransferCheck) (*models.Transaction, error)
+	ExecuteConversion(ctx context.Context, from, to string, amount models.Amount, conversion models.Conversion, check TransferCheck) (*models.Transaction, error)
+	ReverseTransfer(ctx context.Context, id int64, reason string, checkOriginal ReversalCheck, check TransferCheck) (*models.Transaction, error)
+	GetTransactionById(ctx context.Context, id int64) (*models.Transaction, error)
+	GetTransactionByInfo(ctx context.Context, from, to string, createdAt time.Time) (*models.Transaction, error)
+	RemoveTransaction(ctx context.Context, id int64) error
+	GetLastTransactions(ctx context.Context, limit int) ([]models.Transaction, error)
+	ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error)
+	GetWalletHistory(ctx context.Context, address string, filter models.WalletHistoryFilter) ([]models.WalletHistoryEntry, error)
+}
+
+// HoldStore — холды. Создание и закрытие холда атомарно меняют Wallet.Held кошелька-отправителя;
+// списание холда выполняется тем же переводом, что и ExecuteTransfer, в одной транзакции
+// с закрытием холда. Закрытый холд больше не меняется.
+type HoldStore interface {
+	CreateHold(ctx context.Context, from, to string, amount models.Amount, expiresAt time.Time, check TransferCheck) (*models.Hold, error)
+	GetHold(ctx context.Context, id int64) (*models.Hold, error)
+	// CaptureHold переводит amount (не больше суммы холда) на кошелёк получателя и освобождает весь холд
+	CaptureHold(ctx context.Context, id int64, amount models.Amount, checkHold HoldCheck, check TransferCheck) (*models.Hold, *models.Transaction, error)
+	// ReleaseHold закрывает холд без перевода в состоянии status: HoldVoided или HoldExpired
+	ReleaseHold(ctx context.Context, id int64, status models.HoldStatus, checkHold HoldCheck) (*models.Hold, error)
+	// ListExpiredHolds возвращает id не более limit активных холдов с ExpiresAt не позже now
+	ListExpiredHolds(ctx context.Context, now time.Time, limit int) ([]int64, error)
+}
+
+// LedgerStore — журнал проводок, который ведут реализации WalletStore и TransactionStore
+type LedgerStore interface {
+	Verify(ctx context.Context) (*models.LedgerReport, error)
+}
+
+// IdempotencyStore — ключи идемпотентности с сохранёнными ответами
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, scope, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error)
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+	// DeleteExpired удаляет ключи с истёкшим сроком и возвращает их число
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// ClientStore — клиенты и их API-ключи
+type ClientStore interface {
+	CreateClient(ctx context.Context, name string, admin bool, prefix, hash string) (*models.Client, *models.APIKey, error)
+	GetClient(ctx context.Context, id int64) (*models.Client, error)
+	EnsureSubjectClient(ctx context.Context, subject string) (*models.Client, error)
+	CreateAPIKey(ctx context.Context, clientId int64, prefix, hash string) (*models.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, *models.Client, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+var (
+	_ WalletStore      = (*WalletRepository)(nil)
+	_ TransactionStore = (*TransactionRepository)(nil)
+	_ HoldStore        = (*HoldRepository)(nil)
+	_ LedgerStore      = (*LedgerRepository)(nil)
+	_ IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ ClientStore      = (*ClientRepository)(nil)
+)
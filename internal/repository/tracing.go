@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"TransactionSystem/internal/tracing"
+
+	"github.com/jackc/pgx/v4"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "TransactionSystem/internal/repository"
+
+// startQuery начинает спан SQL-запроса. Текст запроса записывается без параметров,
+// поэтому адреса и суммы в трассы не попадают.
+func startQuery(ctx context.Context, sql string) (context.Context, trace.Span) {
+	operation := "SQL"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	ctx, span := tracing.Start(ctx, tracerName, operation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(sql),
+	)
+	return ctx, span
+}
+
+// endQuery завершает спан; отсутствие строк ошибкой запроса не считается
+func endQuery(span trace.Span, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// tracedRows завершает спан запроса, когда строки прочитаны или закрыты
+type tracedRows struct {
+	pgx.Rows
+	span  trace.Span
+	ended bool
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *tracedRows) end() {
+	if !r.ended {
+		r.ended = true
+		endQuery(r.span, r.Rows.Err())
+	}
+}
+
+// tracedRow завершает спан запроса после Scan, когда результат уже получен
+type tracedRow struct {
+	pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	endQuery(r.span, err)
+	return err
+}
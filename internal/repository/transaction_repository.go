@@ -3,25 +3,26 @@ package repository
 import (
     "context"
     "fmt"
+    "log/slog"
+    "strings"
     "time"
 
     "TransactionSystem/internal/models"
 
-    "github.com/jackc/pgx/v4/pgxpool"
     "github.com/jackc/pgx/v4"
 )
 
 // Менеджер для транзакций
 type TransactionRepository struct {
-	db *pgxpool.Pool
+	db *DB
 }
 
-func NewTransactionRepository(db *pgxpool.Pool) *TransactionRepository {
+func NewTransactionRepository(db *DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-func (tr *TransactionRepository) CreateTransaction(ctx context.Context, from, to string, amount float64) (int64, error) {
-	query := `INSERT INTO "TransactionSystem".transactions (from_wallet, to_wallet, amount) 
+func (tr *TransactionRepository) CreateTransaction(ctx context.Context, from, to string, amount models.Amount) (int64, error) {
+	query := `INSERT INTO {schema}.transactions (from_wallet, to_wallet, amount) 
               VALUES ($1, $2, $3) RETURNING id`
 
 	var transactionId int64
@@ -34,59 +35,280 @@ func (tr *TransactionRepository) CreateTransaction(ctx context.Context, from, to
 	return transactionId, nil
 }
 
-func (tr *TransactionRepository) ExecuteTransfer(ctx context.Context, from, to string, balance_from, balance_to, amount float64) error {
+// TransferCheck вызывается внутри транзакции БД, когда оба кошелька уже заблокированы.
+// Ошибка, возвращённая проверкой, отменяет перевод.
+type TransferCheck func(from, to *models.Wallet) error
+
+func (tr *TransactionRepository) ExecuteTransfer(ctx context.Context, from, to string, amount models.Amount, check TransferCheck) (*models.Transaction, error) {
 	tx, err := tr.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("transaction start failed: %w", err)
+		return nil, fmt.Errorf("transaction start failed: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Обновляем балансы кошельков
-    updateQuery := `
-        UPDATE "TransactionSystem".wallets 
-        SET balance = CASE 
-            WHEN address = $1 THEN CAST($3 AS numeric)
-            WHEN address = $2 THEN CAST($4 AS numeric) 
-        END 
-        WHERE address IN ($1, $2)`
+	t, err := transfer(ctx, tx, models.Transaction{From: from, To: to, Amount: amount}, models.LedgerEntryTransfer, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = tx.Exec(ctx, updateQuery, from, to, balance_from, balance_to)
-    if err != nil {
-        return fmt.Errorf("balance update failed: %w", err)
-    }
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "transfer committed", "transaction_id", t.Id)
+
+	return t, nil
+}
+
+// ExecuteConversion списывает amount с кошелька from и зачисляет conversion.ToAmount на кошелёк to.
+// Обе части перевода проходят через системный счёт models.FXAccount.
+func (tr *TransactionRepository) ExecuteConversion(ctx context.Context, from, to string, amount models.Amount, conversion models.Conversion, check TransferCheck) (*models.Transaction, error) {
+	tx, err := tr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := transfer(ctx, tx, models.Transaction{From: from, To: to, Amount: amount, Conversion: &conversion}, models.LedgerEntryConversion, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "conversion committed", "transaction_id", t.Id)
+
+	return t, nil
+}
+
+// ReversalCheck вызывается внутри транзакции БД, когда исходная транзакция уже заблокирована.
+// Ошибка, возвращённая проверкой, отменяет сторнирование.
+type ReversalCheck func(original *models.Transaction) error
+
+// ReverseTransfer сторнирует транзакцию id: создаёт компенсирующий перевод от получателя
+// к отправителю на ту же сумму и помечает исходную транзакцию как сторнированную.
+// checkOriginal проверяет исходную транзакцию, check — кошельки компенсирующего перевода.
+func (tr *TransactionRepository) ReverseTransfer(ctx context.Context, id int64, reason string, checkOriginal ReversalCheck, check TransferCheck) (*models.Transaction, error) {
+	tx, err := tr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка исходной транзакции не даёт сторнировать её дважды параллельными запросами
+	var original models.Transaction
+
+	row := tx.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM {schema}.transactions WHERE id = $1 FOR UPDATE`,
+		id,
+	)
+	if err := scanTransaction(row, &original); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "transaction", Field: "id", Key: id}
+		}
+		return nil, fmt.Errorf("failed to lock transaction with id %v: %w", id, err)
+	}
+
+	if checkOriginal != nil {
+		if err := checkOriginal(&original); err != nil {
+			return nil, err
+		}
+	}
+
+	reversal, err := transfer(ctx, tx, models.Transaction{
+		From:       original.To,
+		To:         original.From,
+		Amount:     original.Amount,
+		ReversalOf: &original.Id,
+		Reason:     reason,
+	}, models.LedgerEntryReversal, models.Amount{}, check)
+	if err != nil {
+		return nil, err
+	}
 
-	// Создаем запись о транзакции
 	_, err = tx.Exec(ctx,
-		`INSERT INTO "TransactionSystem".transactions 
-		(from_wallet, to_wallet, amount) 
-		VALUES ($1, $2, $3)`,
-		from, to, amount,
+		`UPDATE {schema}.transactions SET reversed_by = $2 WHERE id = $1`,
+		original.Id, reversal.Id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark transaction %v as reversed: %w", original.Id, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	slog.DebugContext(ctx, "reversal committed", "transaction_id", reversal.Id, "reversal_of", original.Id)
+
+	return reversal, nil
+}
+
+// transfer переводит t.Amount с кошелька t.From на кошелёк t.To внутри транзакции БД tx
+// и записывает транзакцию вместе с проводками вида kind. Если задана t.Conversion,
+// получателю зачисляется t.Conversion.ToAmount. release — сумма холда, которая снимается
+// с кошелька отправителя вместе со списанием (ноль, если перевод не списывает холд).
+func transfer(ctx context.Context, tx pgx.Tx, t models.Transaction, kind models.LedgerEntryKind, release models.Amount, check TransferCheck) (*models.Transaction, error) {
+	from, to, amount := t.From, t.To, t.Amount
+	credit := amount
+	if t.Conversion != nil {
+		credit = t.Conversion.ToAmount
+	}
+
+	wallets, err := lockWallets(ctx, tx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "wallets locked", "from", from, "to", to,
+		"from_balance", wallets[from].Balance, "to_balance", wallets[to].Balance)
+
+	if check != nil {
+		if err := check(wallets[from], wallets[to]); err != nil {
+			slog.DebugContext(ctx, "transfer check rejected", "from", from, "to", to, "amount", amount, "error", err)
+			return nil, err
+		}
+	}
+
+	var fromBalance, toBalance models.Amount
+
+	// Обновляем балансы относительно текущих значений, а не перезаписываем их.
+	// Холд снимается тем же запросом: chk_wallet_held проверяется после каждого запроса.
+	err = tx.QueryRow(ctx,
+		`UPDATE {schema}.wallets SET balance = balance - $2, held = held - $3 WHERE address = $1 RETURNING balance`,
+		from, amount, release,
+	).Scan(&fromBalance)
+	if err != nil {
+		return nil, fmt.Errorf("sender balance update failed: %w", err)
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE {schema}.wallets SET balance = balance + $2 WHERE address = $1 RETURNING balance`,
+		to, credit,
+	).Scan(&toBalance)
+	if err != nil {
+		return nil, fmt.Errorf("receiver balance update failed: %w", err)
+	}
+
+	// Создаем запись о транзакции в валюте отправителя; совпадение валют проверяет check
+	var rate, toAmount, toCurrency, residue, quoteId interface{}
+	if c := t.Conversion; c != nil {
+		rate, toAmount, toCurrency, residue, quoteId = c.Rate, c.ToAmount, string(c.ToCurrency), c.Residue, c.QuoteId
+	}
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO {schema}.transactions 
+		(from_wallet, to_wallet, amount, currency, reversal_of, reason, rate, to_amount, to_currency, residue, quote_id) 
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, NULLIF($11, ''))
+		RETURNING `+transactionColumns,
+		from, to, amount, string(wallets[from].Currency), t.ReversalOf, t.Reason, rate, toAmount, toCurrency, residue, quoteId,
 	)
+	if err := scanTransaction(row, &t); err != nil {
+		return nil, fmt.Errorf("transaction record failed: %w", err)
+	}
+
+	// Журнал проводок — источник истины, балансы кошельков — его проекция.
+	// Конвертация проводится двумя парами: в валюте отправителя и в валюте получателя.
+	if t.Conversion == nil {
+		err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Currency, from, to, amount)
+	} else {
+		err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Currency, from, models.FXAccount, amount)
+		if err == nil {
+			err = insertLedgerPair(ctx, tx, &t.Id, kind, t.Conversion.ToCurrency, models.FXAccount, to, credit)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("transaction record failed: %w", err)
+		return nil, err
 	}
 
-	return tx.Commit(ctx)
+	t.FromBalance = &fromBalance
+	t.ToBalance = &toBalance
+
+	slog.DebugContext(ctx, "transfer recorded", "transaction_id", t.Id, "kind", kind,
+		"from", from, "to", to, "amount", amount, "credit", credit, "from_balance", fromBalance, "to_balance", toBalance)
+
+	return &t, nil
 }
 
+// lockWallets блокирует кошельки from и to всегда в порядке возрастания адреса,
+// чтобы встречные переводы не приводили к взаимной блокировке
+func lockWallets(ctx context.Context, tx pgx.Tx, from, to string) (map[string]*models.Wallet, error) {
+	first, second := from, to
+	if second < first {
+		first, second = second, first
+	}
+
+	wallets := make(map[string]*models.Wallet, 2)
+	for _, address := range []string{first, second} {
+		wallet, err := lockWallet(ctx, tx, address)
+		if err != nil {
+			return nil, err
+		}
+		wallets[address] = wallet
+	}
+	return wallets, nil
+}
+
+// lockWallet читает кошелёк с блокировкой строки до конца транзакции
+func lockWallet(ctx context.Context, tx pgx.Tx, address string) (*models.Wallet, error) {
+	query := `SELECT address, balance, held, currency, status, owner_id, created_at 
+              FROM {schema}.wallets WHERE address = $1 FOR UPDATE`
+
+	var w models.Wallet
+
+	err := tx.QueryRow(ctx, query, address).Scan(&w.Address, &w.Balance, &w.Held, &w.Currency, &w.Status, &w.Owner, &w.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &NotFoundError{Entity: "wallet", Field: "address", Key: address}
+		}
+		return nil, fmt.Errorf("failed to lock wallet with address %v: %w", address, err)
+	}
+
+	return &w, nil
+}
+
+// transactionColumns — столбцы транзакции в порядке, который ожидает scanTransaction
+const transactionColumns = `id, from_wallet, to_wallet, amount, currency, created_at, reversal_of, reversed_by, reason,
+    rate, to_amount, to_currency, residue, quote_id`
+
+func scanTransaction(row pgx.Row, t *models.Transaction) error {
+	var (
+		reason, toCurrency, quoteId *string
+		rate                        *models.Rate
+		toAmount                    *models.Amount
+		residue                     *models.Residue
+	)
+
+	err := row.Scan(&t.Id, &t.From, &t.To, &t.Amount, &t.Currency, &t.CreatedAt, &t.ReversalOf, &t.ReversedBy, &reason,
+		&rate, &toAmount, &toCurrency, &residue, &quoteId)
+	if err != nil {
+		return err
+	}
+
+	if reason != nil {
+		t.Reason = *reason
+	}
+
+	t.Conversion = nil
+	if rate != nil && toAmount != nil && toCurrency != nil && residue != nil {
+		t.Conversion = &models.Conversion{Rate: *rate, ToAmount: *toAmount, ToCurrency: models.Currency(*toCurrency), Residue: *residue}
+		if quoteId != nil {
+			t.Conversion.QuoteId = *quoteId
+		}
+	}
+
+	return nil
+}
 
 func (tr *TransactionRepository) GetTransactionById(ctx context.Context, id int64) (*models.Transaction, error) {
-    query := `SELECT id, from_wallet, to_wallet, amount, created_at 
-    		  FROM "TransactionSystem".transactions WHERE id = $1`
+    query := `SELECT ` + transactionColumns + `
+    		  FROM {schema}.transactions WHERE id = $1`
 
     var t models.Transaction
 
-    err := tr.db.QueryRow(ctx, query, id).Scan(
-        &t.Id,
-        &t.From,
-        &t.To,
-        &t.Amount,
-        &t.CreatedAt,
-    )
+    err := scanTransaction(tr.db.QueryRow(ctx, query, id), &t)
 
     if err != nil {
         if err == pgx.ErrNoRows {
-            return nil, fmt.Errorf("transaction with id %v not found: %w", id, err)
+            return nil, &NotFoundError{Entity: "transaction", Field: "id", Key: id}
         }
         return nil, fmt.Errorf("failed to find transaction with id %v: %w", id, err)
     }
@@ -95,44 +317,46 @@ func (tr *TransactionRepository) GetTransactionById(ctx context.Context, id int6
 }
 	
 func (tr *TransactionRepository) GetTransactionByInfo(ctx context.Context, from, to string, createdAt time.Time) (*models.Transaction, error) {
-    query := `SELECT id, from_wallet, to_wallet, amount, created_at
-              FROM "TransactionSystem".transactions 
+    query := `SELECT ` + transactionColumns + `
+              FROM {schema}.transactions 
               WHERE from_wallet = $1 AND to_wallet = $2 AND created_at = $3`
 
     var t models.Transaction
 
-    err := tr.db.QueryRow(ctx, query, from, to, createdAt).Scan(
-        &t.Id,
-        &t.From,
-        &t.To,
-        &t.Amount,
-        &t.CreatedAt,
-    )
+    err := scanTransaction(tr.db.QueryRow(ctx, query, from, to, createdAt), &t)
 
     if err != nil {
         if err == pgx.ErrNoRows {
-            return nil, fmt.Errorf("transaction not found for from_wallet %v, to_wallet %v at %v: %w", from, to, createdAt, err)
+            return nil, &NotFoundError{
+                Entity: "transaction",
+                Field:  "from_wallet/to_wallet/created_at",
+                Key:    fmt.Sprintf("%v/%v/%v", from, to, createdAt.Format(time.RFC3339Nano)),
+            }
         }
-        return nil, fmt.Errorf("transaction not found for from_wallet %v, to_wallet %v at %v: %w", from, to, createdAt, err)
+        return nil, fmt.Errorf("failed to find transaction for from_wallet %v, to_wallet %v at %v: %w", from, to, createdAt, err)
     }
 
     return &t, nil
 }
 
 func (tr *TransactionRepository) RemoveTransaction(ctx context.Context, id int64) error {
-	query := `DELETE FROM "TransactionSystem".transactions WHERE id = $1`
+	query := `DELETE FROM {schema}.transactions WHERE id = $1`
 
-	_, err := tr.db.Exec(ctx, query, id)
+	result, err := tr.db.Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete transaction with id %v: %w", id, err)
 	}
 
+	if result.RowsAffected() == 0 {
+		return &NotFoundError{Entity: "transaction", Field: "id", Key: id}
+	}
+
 	return nil
 }
 
 func (tr *TransactionRepository) GetLastTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
-    query := `SELECT id, from_wallet, to_wallet, amount, created_at 
-              FROM "TransactionSystem".transactions 
+    query := `SELECT ` + transactionColumns + ` 
+              FROM {schema}.transactions 
               ORDER BY created_at DESC
               LIMIT $1`
 
@@ -147,7 +371,7 @@ func (tr *TransactionRepository) GetLastTransactions(ctx context.Context, limit
     for rows.Next() {
         var t models.Transaction
 
-        if err := rows.Scan(&t.Id, &t.From, &t.To, &t.Amount, &t.CreatedAt); err != nil {
+        if err := scanTransaction(rows, &t); err != nil {
             return nil, fmt.Errorf("failed to scan transaction: %w", err)
         }
 
@@ -160,3 +384,149 @@ func (tr *TransactionRepository) GetLastTransactions(ctx context.Context, limit
 
     return transactions, nil
 }
+
+// ListTransactions возвращает транзакции, подходящие под фильтр, упорядоченные по (created_at, id)
+// по убыванию. Постраничный вывод выполняется по ключу (keyset), без OFFSET.
+func (tr *TransactionRepository) ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+    var (
+        conditions []string
+        args       []interface{}
+    )
+
+    // where добавляет условие; %[1]d в тексте условия заменяется номером параметра
+    where := func(condition string, arg interface{}) {
+        args = append(args, arg)
+        conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+    }
+
+    if filter.Wallet != "" {
+        switch filter.WalletRole {
+        case models.WalletRoleSender:
+            where("from_wallet = $%[1]d", filter.Wallet)
+        case models.WalletRoleReceiver:
+            where("to_wallet = $%[1]d", filter.Wallet)
+        default:
+            where("(from_wallet = $%[1]d OR to_wallet = $%[1]d)", filter.Wallet)
+        }
+    }
+    if filter.Owner != nil {
+        where(`(from_wallet IN (SELECT address FROM {schema}.wallets WHERE owner_id = $%[1]d)
+              OR to_wallet IN (SELECT address FROM {schema}.wallets WHERE owner_id = $%[1]d))`, *filter.Owner)
+    }
+    if filter.MinAmount != nil {
+        where("amount >= $%[1]d", *filter.MinAmount)
+    }
+    if filter.MaxAmount != nil {
+        where("amount <= $%[1]d", *filter.MaxAmount)
+    }
+    if filter.Since != nil {
+        where("created_at >= $%[1]d", filter.Since.UTC())
+    }
+    if filter.Until != nil {
+        where("created_at < $%[1]d", filter.Until.UTC())
+    }
+    if filter.After != nil {
+        args = append(args, filter.After.CreatedAt.UTC(), filter.After.Id)
+        conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+    }
+
+    query := `SELECT ` + transactionColumns + ` 
+              FROM {schema}.transactions`
+    if len(conditions) > 0 {
+        query += " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    args = append(args, filter.Limit)
+    query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+    rows, err := tr.db.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list transactions: %w", err)
+    }
+    defer rows.Close()
+
+    transactions := make([]models.Transaction, 0, filter.Limit)
+
+    for rows.Next() {
+        var t models.Transaction
+
+        if err := scanTransaction(rows, &t); err != nil {
+            return nil, fmt.Errorf("failed to scan transaction: %w", err)
+        }
+
+        transactions = append(transactions, t)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error while fetching rows: %w", err)
+    }
+
+    return transactions, nil
+}
+
+// GetWalletHistory возвращает транзакции кошелька от новых к старым вместе с балансом
+// кошелька после каждой из них. Баланс хранится в проводке кошелька (ledger_entries.balance_after),
+// поэтому в нём учтены корректировки и удалённые транзакции, а страница не требует чтения всей истории.
+func (tr *TransactionRepository) GetWalletHistory(ctx context.Context, address string, filter models.WalletHistoryFilter) ([]models.WalletHistoryEntry, error) {
+    args := []interface{}{address}
+    var conditions []string
+
+    if filter.Since != nil {
+        args = append(args, filter.Since.UTC())
+        conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+    }
+    if filter.Until != nil {
+        args = append(args, filter.Until.UTC())
+        conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+    }
+    if filter.After != nil {
+        args = append(args, filter.After.CreatedAt.UTC(), filter.After.Id)
+        conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+    }
+
+    // Страница выбирается по индексам кошелька в transactions, баланс после операции
+    // берётся из проводки кошелька по этой транзакции
+    query := `WITH entries AS (
+                  SELECT id, 'debit' AS direction, to_wallet AS counterparty, amount, currency, created_at
+                  FROM {schema}.transactions WHERE from_wallet = $1
+                  UNION ALL
+                  SELECT id, 'credit' AS direction, from_wallet AS counterparty,
+                         COALESCE(to_amount, amount) AS amount, COALESCE(to_currency, currency) AS currency, created_at
+                  FROM {schema}.transactions WHERE to_wallet = $1
+              )
+              SELECT e.id, e.direction, e.counterparty, e.amount, e.currency, l.balance_after, e.created_at
+              FROM (SELECT * FROM entries`
+    if len(conditions) > 0 {
+        query += " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    args = append(args, filter.Limit)
+    query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d) e
+              JOIN {schema}.ledger_entries l ON l.transaction_id = e.id AND l.account = $1
+              ORDER BY e.created_at DESC, e.id DESC`, len(args))
+
+    rows, err := tr.db.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get history of wallet %v: %w", address, err)
+    }
+    defer rows.Close()
+
+    entries := make([]models.WalletHistoryEntry, 0, filter.Limit)
+
+    for rows.Next() {
+        var e models.WalletHistoryEntry
+
+        err := rows.Scan(&e.TransactionId, &e.Direction, &e.Counterparty, &e.Amount, &e.Currency, &e.BalanceAfter, &e.CreatedAt)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan history entry: %w", err)
+        }
+
+        entries = append(entries, e)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error while fetching rows: %w", err)
+    }
+
+    return entries, nil
+}
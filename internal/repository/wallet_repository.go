@@ -6,61 +6,79 @@ import (
 
     "TransactionSystem/internal/models"
 
-    "github.com/jackc/pgx/v4/pgxpool"
     "github.com/jackc/pgx/v4"
 )
 
 // Менеджер для кошельков
 type WalletRepository struct {
-	db *pgxpool.Pool
+	db *DB
 }
 
-func NewWalletRepository(db *pgxpool.Pool) *WalletRepository {
+func NewWalletRepository(db *DB) *WalletRepository {
 	return &WalletRepository{db: db}
 }
 
-func (wr *WalletRepository) CreateWallet(ctx context.Context, address string, balance float64) error {
-	query := `INSERT INTO "TransactionSystem".wallets (address, balance) VALUES ($1, $2)`
+// CreateWallet создаёт кошелёк в валюте currency клиента owner (nil — без владельца).
+// Ненулевой начальный баланс отражается в журнале проводкой со счёта models.IssuanceAccount.
+func (wr *WalletRepository) CreateWallet(ctx context.Context, address string, currency models.Currency, balance models.Amount, owner *int64) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO {schema}.wallets (address, balance, currency, owner_id) VALUES ($1, $2, $3, $4)`
 
-	_, err := wr.db.Exec(ctx, query, address, balance)
+	_, err = tx.Exec(ctx, query, address, balance, string(currency), owner)
 	if err != nil {
 		return fmt.Errorf("failed to create wallet: %w", err)
 	}
 
-	return nil
+	if !balance.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryOpening, currency, models.IssuanceAccount, address, balance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
-func (wr *WalletRepository) GetWalletBalance(ctx context.Context, address string) (float64, error) {
-	query := `SELECT balance FROM "TransactionSystem".wallets WHERE address = $1`
+func (wr *WalletRepository) GetWalletBalance(ctx context.Context, address string) (models.Amount, error) {
+	query := `SELECT balance FROM {schema}.wallets WHERE address = $1`
 
-	var balance float64
+	var balance models.Amount
 	
 	err := wr.db.QueryRow(ctx, query, address).Scan(&balance)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-            return 0, fmt.Errorf("wallet with address %v not found: %w", address, err)
+            return models.Amount{}, &NotFoundError{Entity: "wallet", Field: "address", Key: address}
         }
-        return 0, fmt.Errorf("failed to find wallet with address %v: %w", address, err)
+        return models.Amount{}, fmt.Errorf("failed to find wallet with address %v: %w", address, err)
 	}
 
 	return balance, nil
 }
 
 func (wr *WalletRepository) GetWallet(ctx context.Context, address string) (*models.Wallet, error) {
-    query := `SELECT address, balance, created_at 
-    		  FROM "TransactionSystem".wallets WHERE address = $1`
+    query := `SELECT address, balance, held, currency, status, owner_id, created_at 
+    		  FROM {schema}.wallets WHERE address = $1`
 
     var w models.Wallet
 
     err := wr.db.QueryRow(ctx, query, address).Scan(
     	&w.Address,
 	    &w.Balance, 
+	    &w.Held,
+	    &w.Currency,
+	    &w.Status,
+	    &w.Owner,
 		&w.CreatedAt,
     )
 
     if err != nil {
         if err == pgx.ErrNoRows {
-            return nil, fmt.Errorf("wallet with adress %v not found: %w", address, err)
+            return nil, &NotFoundError{Entity: "wallet", Field: "address", Key: address}
         }
         return nil, fmt.Errorf("failed to find wallet with address %v: %w", address, err)
     }
@@ -68,38 +86,114 @@ func (wr *WalletRepository) GetWallet(ctx context.Context, address string) (*mod
     return &w, nil
 }
 
-func (wr *WalletRepository) UpdateWalletBalabnce(ctx context.Context, address string, balance float64) error {
-	query := `UPDATE "TransactionSystem".wallets SET balance = $1 WHERE address = $2`
+// UpdateWalletBalabnce устанавливает баланс кошелька. Разница со старым балансом
+// записывается в журнал корректирующей проводкой.
+func (wr *WalletRepository) UpdateWalletBalabnce(ctx context.Context, address string, balance models.Amount) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	wallet, err := lockWallet(ctx, tx, address)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE {schema}.wallets SET balance = $1 WHERE address = $2`
 
-	_, err := wr.db.Exec(ctx, query, balance, address)
+	_, err = tx.Exec(ctx, query, balance, address)
     if err != nil {
         return fmt.Errorf("failed to update wallet with address %v: %w", address, err)
     }
 
+	if delta := balance.Sub(wallet.Balance); !delta.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryAdjustment, wallet.Currency, models.IssuanceAccount, address, delta)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
-func (wr *WalletRepository) RemoveWallet(ctx context.Context, address string) error {
-	query := `DELETE FROM "TransactionSystem".wallets WHERE address = $1`
+// WalletCheck вызывается внутри транзакции БД, когда кошелёк уже заблокирован.
+// Ошибка, возвращённая проверкой, отменяет изменение.
+type WalletCheck func(wallet *models.Wallet) error
 
-	result, err := wr.db.Exec(ctx, query, address)
+// UpdateWalletStatus переводит кошелёк в состояние status, если это разрешает check
+func (wr *WalletRepository) UpdateWalletStatus(ctx context.Context, address string, status models.WalletStatus, check WalletCheck) (*models.Wallet, error) {
+	tx, err := wr.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to delete wallet with address %v: %w", address, err)
+		return nil, fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	wallet, err := lockWallet(ctx, tx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(wallet); err != nil {
+			return nil, err
+		}
 	}
 
-	//  проверяем, были ли затронуты строки
-	rowsAffected := result.RowsAffected()
+	query := `UPDATE {schema}.wallets SET status = $1 WHERE address = $2`
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("wallet with address %v not found", address)
+	_, err = tx.Exec(ctx, query, string(status), address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update status of wallet %v: %w", address, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
 	}
 
-	return nil
+	wallet.Status = status
+	return wallet, nil
+}
+
+// RemoveWallet удаляет кошелёк, если это разрешает check. Остаток баланса возвращается
+// в журнале на счёт models.IssuanceAccount, чтобы журнал оставался сбалансированным.
+func (wr *WalletRepository) RemoveWallet(ctx context.Context, address string, check WalletCheck) error {
+	tx, err := wr.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transaction start failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	wallet, err := lockWallet(ctx, tx, address)
+	if err != nil {
+		return err
+	}
+
+	if check != nil {
+		if err := check(wallet); err != nil {
+			return err
+		}
+	}
+
+	if !wallet.Balance.IsZero() {
+		err = insertLedgerPair(ctx, tx, nil, models.LedgerEntryClosing, wallet.Currency, address, models.IssuanceAccount, wallet.Balance)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `DELETE FROM {schema}.wallets WHERE address = $1`
+
+	_, err = tx.Exec(ctx, query, address)
+	if err != nil {
+		return fmt.Errorf("failed to delete wallet with address %v: %w", address, err)
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (wr *WalletRepository) IsEmpty(ctx context.Context) (bool, error) {
-    query := `SELECT COUNT(*) FROM "TransactionSystem".wallets`
+    query := `SELECT COUNT(*) FROM {schema}.wallets`
     
     var count int
     err := wr.db.QueryRow(ctx, query).Scan(&count)
@@ -108,4 +202,29 @@ func (wr *WalletRepository) IsEmpty(ctx context.Context) (bool, error) {
     }
     
     return count == 0, nil
-}
\ No newline at end of file
+}
+// CountWalletsByStatus возвращает число кошельков в каждом состоянии
+func (wr *WalletRepository) CountWalletsByStatus(ctx context.Context) (map[models.WalletStatus]int64, error) {
+    query := `SELECT status, COUNT(*) FROM {schema}.wallets GROUP BY status`
+
+    rows, err := wr.db.Query(ctx, query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to count wallets: %w", err)
+    }
+    defer rows.Close()
+
+    counts := make(map[models.WalletStatus]int64)
+    for rows.Next() {
+        var status string
+        var count int64
+        if err := rows.Scan(&status, &count); err != nil {
+            return nil, fmt.Errorf("failed to scan wallet count: %w", err)
+        }
+        counts[models.WalletStatus(status)] = count
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error while fetching rows: %w", err)
+    }
+
+    return counts, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/models"
+)
+
+// Проверки доступа сервисного слоя. Запрос без auth.Principal в контексте
+// (режим auth.mode=none, CLI, внутренние вызовы) не ограничивается.
+
+// authorizeWallet разрешает операцию с кошельком w его владельцу и администратору
+func authorizeWallet(ctx context.Context, w *models.Wallet) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Admin {
+		return nil
+	}
+	if w.Owner != nil && *w.Owner == p.ClientId {
+		return nil
+	}
+	return fmt.Errorf("%w: wallet %s belongs to another client", ErrForbidden, w.Address)
+}
+
+// authorizeQuote проверяет, что котировку использует запросивший её клиент или администратор
+func authorizeQuote(ctx context.Context, q *models.FXQuote) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Admin {
+		return nil
+	}
+	if q.Owner != nil && *q.Owner == p.ClientId {
+		return nil
+	}
+	return fmt.Errorf("%w: fx quote %s belongs to another client", ErrForbidden, q.Id)
+}
+
+// requireAdmin разрешает операцию только администратору
+func requireAdmin(ctx context.Context) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Admin {
+		return nil
+	}
+	return fmt.Errorf("%w: admin key required", ErrForbidden)
+}
+
+// ownerScope возвращает id клиента, которым нужно ограничить выборку,
+// или nil, если вызывающий видит все кошельки
+func ownerScope(ctx context.Context) *int64 {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Admin {
+		return nil
+	}
+	id := p.ClientId
+	return &id
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// maxClientNameLength ограничивает имя клиента
+const maxClientNameLength = 200
+
+type ClientService struct {
+	clientRepo repository.ClientStore
+}
+
+func NewClientService(clientRepo repository.ClientStore) *ClientService {
+	return &ClientService{clientRepo: clientRepo}
+}
+
+// CreateClient регистрирует клиента и выдаёт ему первый API-ключ. Ключ возвращается
+// только здесь: в базе хранится лишь его хэш.
+func (cs *ClientService) CreateClient(ctx context.Context, name string, admin bool) (*models.Client, *models.IssuedAPIKey, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxClientNameLength {
+		return nil, nil, &ValidationError{ErrInvalidClient, "name", fmt.Sprintf("must be between 1 and %d characters", maxClientNameLength)}
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, apiKey, err := cs.clientRepo.CreateClient(ctx, name, admin, prefix, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.InfoContext(ctx, "client created", "client_id", client.Id, "admin", client.Admin, "key_id", apiKey.Id)
+	return client, &models.IssuedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+// IssueKey выдаёт клиенту clientId ещё один API-ключ, например для ротации
+func (cs *ClientService) IssueKey(ctx context.Context, clientId int64) (*models.IssuedAPIKey, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := cs.clientRepo.GetClient(ctx, clientId); err != nil {
+		return nil, fmt.Errorf("failed to get client %d: %w", clientId, notFound(err, ErrClientNotFound))
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := cs.clientRepo.CreateAPIKey(ctx, clientId, prefix, hash)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "api key issued", "client_id", clientId, "key_id", apiKey.Id)
+	return &models.IssuedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+// RevokeKey отзывает API-ключ id; запросы с ним перестают аутентифицироваться сразу
+func (cs *ClientService) RevokeKey(ctx context.Context, id int64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if err := cs.clientRepo.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke api key %d: %w", id, notFound(err, ErrAPIKeyNotFound))
+	}
+	slog.InfoContext(ctx, "api key revoked", "key_id", id)
+	return nil
+}
+
+// Authenticate находит клиента по API-ключу. Неизвестный, отозванный или
+// неверный ключ даёт ErrUnauthenticated без уточнения причины.
+func (cs *ClientService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	prefix, ok := auth.ParseKey(key)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	apiKey, client, err := cs.clientRepo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		var nf *repository.NotFoundError
+		if errors.As(err, &nf) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	if apiKey.RevokedAt != nil || !auth.VerifyKey(key, apiKey.Hash) {
+		return nil, ErrUnauthenticated
+	}
+
+	return &auth.Principal{ClientId: client.Id, Admin: client.Admin, Scopes: auth.ClientScopes}, nil
+}
+
+// AuthenticateToken сопоставляет субъект проверенного JWT с клиентом, создавая клиента
+// при первом обращении: кошельки, созданные с токеном, принадлежат субъекту.
+// Права определяются только scope токена, в том числе права администратора.
+func (cs *ClientService) AuthenticateToken(ctx context.Context, claims *auth.Claims) (*auth.Principal, error) {
+	client, err := cs.clientRepo.EnsureSubjectClient(ctx, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate token: %w", err)
+	}
+
+	return &auth.Principal{
+		ClientId: client.Id,
+		Subject:  claims.Subject,
+		Admin:    slices.Contains(claims.Scopes, auth.ScopeAdmin),
+		Scopes:   claims.Scopes,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Доменные ошибки сервисного слоя. Вызывающий код проверяет их через errors.Is,
+// слой api сопоставляет их с HTTP-статусами.
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrSameWallet          = errors.New("sender and receiver cannot be the same")
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+	ErrNegativeBalance     = errors.New("balance cannot be negative")
+	ErrInvalidLimit        = errors.New("limit must be greater than zero")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrInvalidCursor       = models.ErrInvalidCursor
+	ErrLedgerImbalanced    = errors.New("ledger is out of balance")
+
+	ErrUnknownCurrency     = models.ErrUnknownCurrency
+	ErrUnsupportedCurrency = models.ErrUnsupportedCurrency
+	ErrAmountScale         = models.ErrAmountScale
+	ErrCurrencyMismatch    = errors.New("sender and receiver wallets have different currencies")
+
+	ErrInvalidRate             = models.ErrInvalidRate
+	ErrFXRateNotFound          = models.ErrRateNotFound
+	ErrSameCurrency            = errors.New("conversion requires different currencies")
+	ErrFXQuoteNotFound         = errors.New("fx quote not found")
+	ErrFXQuoteExpired          = errors.New("fx quote has expired")
+	ErrFXQuoteMismatch         = errors.New("fx quote does not match wallet currencies")
+	ErrFXRatesReadOnly         = errors.New("exchange rates cannot be set with the configured provider")
+	ErrConversionNotReversible = errors.New("conversion transfer cannot be reversed")
+
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrHoldExpired        = errors.New("hold has expired")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+	ErrInvalidHoldTTL     = errors.New("invalid hold ttl")
+
+	ErrWalletFrozen               = errors.New("wallet is frozen")
+	ErrWalletClosed               = errors.New("wallet is closed")
+	ErrWalletStatusTransition     = errors.New("wallet status transition is not allowed")
+	ErrWalletBalanceNotZero       = errors.New("wallet balance must be zero to close it")
+
+	ErrTransactionAlreadyReversed = errors.New("transaction is already reversed")
+	ErrTransactionNotReversible   = errors.New("reversal transaction cannot be reversed")
+	ErrReversalReasonRequired     = errors.New("reversal reason is required")
+
+	ErrIdempotencyKeyInvalid    = errors.New("idempotency key must be between 1 and 255 characters")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
+
+	ErrUnauthenticated   = errors.New("missing or invalid credentials")
+	ErrForbidden         = errors.New("access denied")
+	ErrInsufficientScope = errors.New("credentials lack the required scope")
+	ErrClientNotFound    = errors.New("client not found")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrInvalidClient     = errors.New("invalid client")
+)
+
+// transferFailureReasons — причины неудачных переводов в порядке проверки
+var transferFailureReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrSameWallet, "same_wallet"},
+	{ErrInvalidAmount, "invalid_amount"},
+	{ErrAmountScale, "invalid_amount"},
+	{ErrCurrencyMismatch, "currency_mismatch"},
+	{ErrSameCurrency, "currency_mismatch"},
+	{ErrFXRateNotFound, "fx_rate_not_found"},
+	{ErrFXQuoteNotFound, "fx_quote_invalid"},
+	{ErrFXQuoteExpired, "fx_quote_invalid"},
+	{ErrFXQuoteMismatch, "fx_quote_invalid"},
+	{ErrCaptureExceedsHold, "invalid_amount"},
+	{ErrHoldNotFound, "hold_invalid"},
+	{ErrHoldNotActive, "hold_invalid"},
+	{ErrHoldExpired, "hold_invalid"},
+	{ErrInsufficientFunds, "insufficient_funds"},
+	{ErrWalletNotFound, "wallet_not_found"},
+	{ErrWalletFrozen, "wallet_frozen"},
+	{ErrWalletClosed, "wallet_closed"},
+	{ErrForbidden, "forbidden"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+// TransferFailureReason возвращает короткую причину неудачного перевода,
+// пригодную для метки метрики; неизвестные ошибки считаются внутренними
+func TransferFailureReason(err error) string {
+	for _, r := range transferFailureReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "internal"
+}
+
+// ValidationError уточняет доменную ошибку Err: какой параметр некорректен и почему
+type ValidationError struct {
+	Err    error
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s %s", e.Err, e.Field, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// WalletStatusError сообщает, что кошелёк Address в состоянии Status не может участвовать в операции.
+// Оборачивает ErrWalletFrozen или ErrWalletClosed.
+type WalletStatusError struct {
+	Address string
+	Status  models.WalletStatus
+}
+
+func (e *WalletStatusError) Error() string {
+	return fmt.Sprintf("wallet %s is %s", e.Address, e.Status)
+}
+
+func (e *WalletStatusError) Unwrap() error {
+	if e.Status == models.WalletClosed {
+		return ErrWalletClosed
+	}
+	return ErrWalletFrozen
+}
+
+// CurrencyMismatchError сообщает, что валюты кошельков перевода различаются. Оборачивает ErrCurrencyMismatch.
+type CurrencyMismatchError struct {
+	From models.Currency
+	To   models.Currency
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf("cannot transfer %s to a %s wallet without conversion", e.From, e.To)
+}
+
+func (e *CurrencyMismatchError) Unwrap() error {
+	return ErrCurrencyMismatch
+}
+
+// HoldStatusError сообщает, что холд Id уже закрыт со статусом Status. Оборачивает ErrHoldNotActive.
+type HoldStatusError struct {
+	Id     int64
+	Status models.HoldStatus
+}
+
+func (e *HoldStatusError) Error() string {
+	return fmt.Sprintf("hold %d is %s", e.Id, e.Status)
+}
+
+func (e *HoldStatusError) Unwrap() error {
+	return ErrHoldNotActive
+}
+
+// requireActive возвращает *WalletStatusError, если кошелёк не активен
+func requireActive(w *models.Wallet) error {
+	if w.Status != models.WalletActive {
+		return &WalletStatusError{Address: w.Address, Status: w.Status}
+	}
+	return nil
+}
+
+// notFound оборачивает ошибку "запись не найдена" из репозитория доменной ошибкой target.
+// Исходная *repository.NotFoundError остаётся в цепочке, чтобы можно было узнать ключ поиска.
+func notFound(err error, target error) error {
+	var nf *repository.NotFoundError
+	if errors.As(err, &nf) {
+		return fmt.Errorf("%w: %w", target, nf)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %v", target, err)
+	}
+	return err
+}
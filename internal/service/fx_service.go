@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FXRateProvider — источник курсов для переводов с конвертацией
+type FXRateProvider interface {
+	// Rate возвращает курс from→to; для неизвестной пары — ошибку, оборачивающую models.ErrRateNotFound
+	Rate(ctx context.Context, from, to models.Currency) (models.Rate, error)
+}
+
+// FXRateSetter — поставщик курсов, которые задаёт администратор
+type FXRateSetter interface {
+	FXRateProvider
+	SetRate(ctx context.Context, from, to models.Currency, rate models.Rate) error
+}
+
+// FXService выдаёт курсы для конвертации и котировки, фиксирующие курс на время quoteTTL.
+// Котировки хранятся в памяти процесса: они не переживают перезапуск и не видны другим
+// экземплярам сервиса, поэтому котировку нужно использовать на том же экземпляре, где она выдана.
+type FXService struct {
+	provider   FXRateProvider
+	currencies *models.Currencies
+	quoteTTL   time.Duration
+
+	mu     sync.Mutex
+	quotes map[string]models.FXQuote
+}
+
+func NewFXService(provider FXRateProvider, currencies *models.Currencies, quoteTTL time.Duration) *FXService {
+	return &FXService{
+		provider:   provider,
+		currencies: currencies,
+		quoteTTL:   quoteTTL,
+		quotes:     make(map[string]models.FXQuote),
+	}
+}
+
+// Rate возвращает текущий курс поставщика from→to
+func (fs *FXService) Rate(ctx context.Context, from, to models.Currency) (models.Rate, error) {
+	if err := fs.checkPair(from, to); err != nil {
+		return models.Rate{}, err
+	}
+	rate, err := fs.provider.Rate(ctx, from, to)
+	if err != nil {
+		return models.Rate{}, fmt.Errorf("failed to get rate %s/%s: %w", from, to, err)
+	}
+	return rate, nil
+}
+
+// CreateQuote фиксирует текущий курс from→to. Перевод с конвертацией по котировке
+// использует её курс, пока котировка не истекла.
+func (fs *FXService) CreateQuote(ctx context.Context, from, to models.Currency) (_ *models.FXQuote, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "FXService.CreateQuote",
+		attribute.String("fx.from", string(from)),
+		attribute.String("fx.to", string(to)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	rate, err := fs.Rate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	quote := models.FXQuote{
+		Id:        uuid.New().String(),
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		ExpiresAt: now.Add(fs.quoteTTL),
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		owner := p.ClientId
+		quote.Owner = &owner
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// Истёкшие котировки хранятся ещё quoteTTL, чтобы на них отвечать ErrFXQuoteExpired
+	for id, q := range fs.quotes {
+		if now.Sub(q.ExpiresAt) > fs.quoteTTL {
+			delete(fs.quotes, id)
+		}
+	}
+	fs.quotes[quote.Id] = quote
+
+	slog.InfoContext(ctx, "fx quote created", "quote_id", quote.Id, "from", from, "to", to, "rate", rate, "expires_at", quote.ExpiresAt)
+	return &quote, nil
+}
+
+// Quote возвращает действующую котировку id; при аутентификации — только клиенту, который её запросил
+func (fs *FXService) Quote(ctx context.Context, id string) (*models.FXQuote, error) {
+	fs.mu.Lock()
+	quote, ok := fs.quotes[id]
+	fs.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrFXQuoteNotFound, id)
+	}
+	if err := authorizeQuote(ctx, &quote); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(quote.ExpiresAt) {
+		return nil, fmt.Errorf("%w: %s expired at %s", ErrFXQuoteExpired, id, quote.ExpiresAt.Format(time.RFC3339))
+	}
+	return &quote, nil
+}
+
+// SetRate устанавливает курс from→to; доступно администратору, если поставщик курсов
+// это допускает (fx.provider: manual)
+func (fs *FXService) SetRate(ctx context.Context, from, to models.Currency, rate models.Rate) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "FXService.SetRate",
+		attribute.String("fx.from", string(from)),
+		attribute.String("fx.to", string(to)),
+		attribute.String("fx.rate", rate.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	setter, ok := fs.provider.(FXRateSetter)
+	if !ok {
+		return ErrFXRatesReadOnly
+	}
+	if err := fs.checkPair(from, to); err != nil {
+		return err
+	}
+	if rate.IsZero() {
+		return &ValidationError{ErrInvalidRate, "rate", "must be positive"}
+	}
+
+	if err := setter.SetRate(ctx, from, to, rate); err != nil {
+		return fmt.Errorf("failed to set rate %s/%s: %w", from, to, err)
+	}
+	slog.InfoContext(ctx, "fx rate set", "from", from, "to", to, "rate", rate)
+	return nil
+}
+
+// checkPair проверяет, что обе валюты известны и различаются
+func (fs *FXService) checkPair(from, to models.Currency) error {
+	if _, err := fs.currencies.Scale(from); err != nil {
+		return err
+	}
+	if _, err := fs.currencies.Scale(to); err != nil {
+		return err
+	}
+	if from == to {
+		return ErrSameCurrency
+	}
+	return nil
+}
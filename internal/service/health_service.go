@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"TransactionSystem/internal/models"
+)
+
+// readinessTimeout ограничивает время проверок, чтобы зависшая база не задерживала ответ пробе
+const readinessTimeout = 2 * time.Second
+
+// Database — хранилище, доступность которого проверяет HealthService
+type Database interface {
+	Ping(ctx context.Context) error
+	Stats() models.PoolStats
+}
+
+// MigrationSource сообщает применённую и ожидаемую версии схемы
+type MigrationSource interface {
+	CurrentVersion(ctx context.Context) (int64, error)
+	LatestVersion() int64
+}
+
+type HealthService struct {
+	db           Database
+	migrations   MigrationSource
+	version      string
+	startedAt    time.Time
+	shuttingDown atomic.Bool
+}
+
+// NewHealthService создаёт HealthService; version — версия сборки, отдаваемая в статусе
+func NewHealthService(db Database, migrations MigrationSource, version string) *HealthService {
+	return &HealthService{
+		db:         db,
+		migrations: migrations,
+		version:    version,
+		startedAt:  time.Now(),
+	}
+}
+
+// BeginShutdown помечает сервис как останавливающийся, после чего он перестаёт быть готовым
+func (hs *HealthService) BeginShutdown() {
+	hs.shuttingDown.Store(true)
+}
+
+func (hs *HealthService) ShuttingDown() bool {
+	return hs.shuttingDown.Load()
+}
+
+// Ready проверяет, что база данных отвечает, схема мигрирована до ожидаемой версии
+// и сервис не останавливается
+func (hs *HealthService) Ready(ctx context.Context) *models.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	readiness, _ := hs.ready(ctx)
+	return readiness
+}
+
+// Status возвращает подробное состояние сервиса: пул соединений, версии сборки и схемы, время работы
+func (hs *HealthService) Status(ctx context.Context) *models.SystemStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	readiness, migrations := hs.ready(ctx)
+	return &models.SystemStatus{
+		Version:       hs.version,
+		StartedAt:     hs.startedAt.UTC(),
+		UptimeSeconds: time.Since(hs.startedAt).Seconds(),
+		ShuttingDown:  hs.ShuttingDown(),
+		Migrations:    migrations,
+		Pool:          hs.db.Stats(),
+		Readiness:     *readiness,
+	}
+}
+
+func (hs *HealthService) ready(ctx context.Context) (*models.Readiness, models.MigrationVersion) {
+	checks := map[string]string{
+		models.CheckDatabase:   models.CheckOK,
+		models.CheckMigrations: models.CheckOK,
+		models.CheckShutdown:   models.CheckOK,
+	}
+
+	if err := hs.db.Ping(ctx); err != nil {
+		checks[models.CheckDatabase] = fmt.Sprintf("ping failed: %v", err)
+	}
+
+	migrations := models.MigrationVersion{Expected: hs.migrations.LatestVersion()}
+	current, err := hs.migrations.CurrentVersion(ctx)
+	switch {
+	case err != nil:
+		migrations.Error = err.Error()
+		checks[models.CheckMigrations] = err.Error()
+	case current != migrations.Expected:
+		checks[models.CheckMigrations] = fmt.Sprintf("schema at version %d, expected %d", current, migrations.Expected)
+	}
+	migrations.Current = current
+
+	if hs.ShuttingDown() {
+		checks[models.CheckShutdown] = "shutting down"
+	}
+
+	readiness := &models.Readiness{Ready: true, Checks: checks}
+	for _, result := range checks {
+		if result != models.CheckOK {
+			readiness.Ready = false
+		}
+	}
+	return readiness, migrations
+}
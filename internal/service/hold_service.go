@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// expiryBatchSize — сколько истёкших холдов ExpireHolds снимает за один запрос к хранилищу
+const expiryBatchSize = 100
+
+// HoldService резервирует средства на кошельке-отправителе до списания (capture) или снятия (void).
+// Захолдированная сумма остаётся в балансе, но не входит в доступный остаток.
+type HoldService struct {
+	holdRepo   repository.HoldStore
+	walletRepo repository.WalletStore
+	metrics    TransferMetrics
+	currencies *models.Currencies
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func NewHoldService(holdRepo repository.HoldStore, walletRepo repository.WalletStore, defaultTTL, maxTTL time.Duration) *HoldService {
+	return &HoldService{
+		holdRepo:   holdRepo,
+		walletRepo: walletRepo,
+		metrics:    noopTransferMetrics{},
+		currencies: models.ISOCurrencies(),
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}
+}
+
+// SetCurrencies задаёт реестр допустимых валют; по умолчанию только валюты ISO 4217
+func (hs *HoldService) SetCurrencies(c *models.Currencies) {
+	hs.currencies = c
+}
+
+// SetMetrics подключает учёт списаний холдов как переводов; nil отключает его
+func (hs *HoldService) SetMetrics(m TransferMetrics) {
+	if m == nil {
+		m = noopTransferMetrics{}
+	}
+	hs.metrics = m
+}
+
+// TTLFromSeconds переводит ttl_seconds запроса в срок холда (0 — срок по умолчанию).
+// Секунды сравниваются с holds.max_ttl до умножения, поэтому огромное значение
+// не переполняет time.Duration и не превращается в допустимый срок.
+func (hs *HoldService) TTLFromSeconds(seconds int64) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, &ValidationError{ErrInvalidHoldTTL, "ttl_seconds", "must not be negative"}
+	}
+	if seconds > int64(hs.maxTTL/time.Second) {
+		return 0, &ValidationError{ErrInvalidHoldTTL, "ttl_seconds", fmt.Sprintf("must be between 1 and %d", int64(hs.maxTTL/time.Second))}
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// CreateHold резервирует amount на кошельке from в пользу кошелька to на время ttl
+// (0 — срок по умолчанию). Проверки те же, что у перевода, но сравнивается доступный остаток.
+func (hs *HoldService) CreateHold(ctx context.Context, from, to string, amount models.Amount, ttl time.Duration) (_ *models.Hold, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "HoldService.CreateHold",
+		attribute.String("wallet.from", from),
+		attribute.String("wallet.to", to),
+		attribute.String("amount", amount.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if from == to {
+		return nil, ErrSameWallet
+	}
+	if amount.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if ttl == 0 {
+		ttl = hs.defaultTTL
+	}
+	if ttl < time.Second || ttl > hs.maxTTL {
+		return nil, &ValidationError{ErrInvalidHoldTTL, "ttl_seconds", fmt.Sprintf("must be between 1 and %d", int64(hs.maxTTL/time.Second))}
+	}
+
+	hold, err := hs.holdRepo.CreateHold(ctx, from, to, amount, time.Now().Add(ttl), func(fromWallet, toWallet *models.Wallet) error {
+		if err := authorizeWallet(ctx, fromWallet); err != nil {
+			return err
+		}
+		if err := requireActive(fromWallet); err != nil {
+			return err
+		}
+		if err := requireActive(toWallet); err != nil {
+			return err
+		}
+		if fromWallet.Currency != toWallet.Currency {
+			return &CurrencyMismatchError{From: fromWallet.Currency, To: toWallet.Currency}
+		}
+		if err := hs.currencies.CheckAmount(fromWallet.Currency, amount); err != nil {
+			return err
+		}
+		if fromWallet.Available().Cmp(amount) < 0 {
+			return ErrInsufficientFunds
+		}
+		return nil
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "hold failed", "from", from, "to", to, "amount", amount, "error", err)
+		return nil, notFound(err, ErrWalletNotFound)
+	}
+
+	slog.InfoContext(ctx, "hold created", "hold_id", hold.Id, "from", from, "to", to, "amount", amount, "expires_at", hold.ExpiresAt)
+	return hold, nil
+}
+
+// GetHold возвращает холд; при аутентификации — только владельцу кошелька-отправителя
+func (hs *HoldService) GetHold(ctx context.Context, id int64) (*models.Hold, error) {
+	hold, err := hs.holdRepo.GetHold(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrHoldNotFound)
+	}
+	if err := hs.authorizeHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CaptureHold списывает amount (нулевое значение — всю сумму) обычным переводом на кошелёк получателя.
+// Холд закрывается целиком: остаток сверх amount снова становится доступен отправителю.
+func (hs *HoldService) CaptureHold(ctx context.Context, id int64, amount models.Amount) (_ *models.Hold, _ *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "HoldService.CaptureHold",
+		attribute.Int64("hold.id", id),
+		attribute.String("amount", amount.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	hold, transaction, err := hs.captureHold(ctx, id, amount)
+	if err != nil {
+		reason := TransferFailureReason(err)
+		hs.metrics.TransferFailed(reason)
+		slog.WarnContext(ctx, "hold capture failed", "hold_id", id, "amount", amount, "reason", reason, "error", err)
+		return nil, nil, err
+	}
+
+	hs.metrics.TransferSucceeded(transaction.Amount, transaction.Currency)
+	slog.InfoContext(ctx, "hold captured", "hold_id", id, "transaction_id", transaction.Id,
+		"from", hold.From, "to", hold.To, "amount", transaction.Amount, "held", hold.Amount)
+	return hold, transaction, nil
+}
+
+func (hs *HoldService) captureHold(ctx context.Context, id int64, amount models.Amount) (*models.Hold, *models.Transaction, error) {
+	if amount.Sign() < 0 {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	// Сумма холда не меняется, поэтому её можно прочитать до блокировки
+	hold, err := hs.GetHold(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if amount.IsZero() {
+		amount = hold.Amount
+	}
+	if amount.Cmp(hold.Amount) > 0 {
+		return nil, nil, &ValidationError{ErrCaptureExceedsHold, "amount", "must not exceed the held amount " + hold.Amount.String()}
+	}
+
+	checkHold := func(h *models.Hold) error {
+		if err := requireOpen(h); err != nil {
+			return err
+		}
+		if !time.Now().Before(h.ExpiresAt) {
+			return fmt.Errorf("%w: hold %d expired at %s", ErrHoldExpired, h.Id, h.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	// Баланс не проверяется: списываемая сумма уже зарезервирована холдом
+	capturedHold, transaction, err := hs.holdRepo.CaptureHold(ctx, id, amount, checkHold, func(fromWallet, toWallet *models.Wallet) error {
+		if err := authorizeWallet(ctx, fromWallet); err != nil {
+			return err
+		}
+		if err := requireActive(fromWallet); err != nil {
+			return err
+		}
+		if err := requireActive(toWallet); err != nil {
+			return err
+		}
+		if fromWallet.Currency != toWallet.Currency {
+			return &CurrencyMismatchError{From: fromWallet.Currency, To: toWallet.Currency}
+		}
+		return hs.currencies.CheckAmount(fromWallet.Currency, amount)
+	})
+	if err != nil {
+		return nil, nil, holdNotFound(err)
+	}
+	return capturedHold, transaction, nil
+}
+
+// VoidHold снимает холд без перевода и возвращает средства в доступный остаток отправителя
+func (hs *HoldService) VoidHold(ctx context.Context, id int64) (_ *models.Hold, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "HoldService.VoidHold",
+		attribute.Int64("hold.id", id),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// Владелец кошелька не меняется, поэтому права можно проверить до блокировки холда
+	hold, err := hs.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireOpen(hold); err != nil {
+		return nil, err
+	}
+
+	hold, err = hs.holdRepo.ReleaseHold(ctx, id, models.HoldVoided, requireOpen)
+	if err != nil {
+		return nil, notFound(err, ErrHoldNotFound)
+	}
+	slog.InfoContext(ctx, "hold voided", "hold_id", id, "from", hold.From, "amount", hold.Amount)
+	return hold, nil
+}
+
+// ExpireHolds снимает активные холды с истёкшим сроком и возвращает их число
+func (hs *HoldService) ExpireHolds(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "HoldService.ExpireHolds")
+	defer func() { tracing.End(span, err) }()
+
+	expired := 0
+	for {
+		ids, err := hs.holdRepo.ListExpiredHolds(ctx, time.Now(), expiryBatchSize)
+		if err != nil {
+			return expired, fmt.Errorf("failed to list expired holds: %w", err)
+		}
+		for _, id := range ids {
+			hold, err := hs.holdRepo.ReleaseHold(ctx, id, models.HoldExpired, requireOpen)
+			// Холд могли списать или снять между выборкой и блокировкой
+			if errors.Is(err, ErrHoldNotActive) {
+				continue
+			}
+			if err != nil {
+				return expired, fmt.Errorf("failed to expire hold %d: %w", id, err)
+			}
+			expired++
+			slog.InfoContext(ctx, "hold expired", "hold_id", id, "from", hold.From, "amount", hold.Amount)
+		}
+		if len(ids) < expiryBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunExpiry снимает истёкшие холды каждые interval, пока не отменён ctx
+func (hs *HoldService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := hs.ExpireHolds(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "hold expiry failed", "error", err)
+			}
+		}
+	}
+}
+
+// authorizeHold проверяет, что вызывающий владеет кошельком-отправителем холда
+func (hs *HoldService) authorizeHold(ctx context.Context, h *models.Hold) error {
+	wallet, err := hs.walletRepo.GetWallet(ctx, h.From)
+	if err != nil {
+		return notFound(err, ErrWalletNotFound)
+	}
+	return authorizeWallet(ctx, wallet)
+}
+
+// requireOpen возвращает *HoldStatusError, если холд уже закрыт
+func requireOpen(h *models.Hold) error {
+	if h.Status != models.HoldActive {
+		return &HoldStatusError{Id: h.Id, Status: h.Status}
+	}
+	return nil
+}
+
+// holdNotFound различает ненайденный холд и ненайденный кошелёк перевода
+func holdNotFound(err error) error {
+	var nf *repository.NotFoundError
+	if errors.As(err, &nf) && nf.Entity == "hold" {
+		return notFound(err, ErrHoldNotFound)
+	}
+	return notFound(err, ErrWalletNotFound)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+// DefaultIdempotencyTTL используется, если время жизни ключа не задано в конфиге
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService struct {
+	idempotencyRepo repository.IdempotencyStore
+	ttl             time.Duration
+}
+
+func NewIdempotencyService(ir repository.IdempotencyStore, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyService{
+		idempotencyRepo: ir,
+		ttl:             ttl,
+	}
+}
+
+// Begin занимает ключ для нового запроса и возвращает nil.
+// Если запрос с тем же ключом и тем же отпечатком уже выполнен, возвращает сохранённый ответ.
+func (is *IdempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrIdempotencyKeyInvalid
+	}
+
+	record, reserved, err := is.idempotencyRepo.Reserve(ctx, scope, key, fingerprint, is.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete сохраняет ответ, который будет возвращаться при повторах запроса
+func (is *IdempotencyService) Complete(ctx context.Context, scope, key string, statusCode int, headers map[string]string, body []byte) error {
+	return is.idempotencyRepo.Complete(ctx, scope, key, statusCode, headers, body)
+}
+
+// Release освобождает ключ, если запрос не удалось выполнить и его можно повторить
+func (is *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+	return is.idempotencyRepo.Release(ctx, scope, key)
+}
+
+// PurgeExpired удаляет ключи с истёкшим сроком и возвращает их число
+func (is *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	deleted, err := is.idempotencyRepo.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "expired idempotency keys purged", "count", deleted)
+	}
+	return deleted, nil
+}
+
+// RunPurge удаляет ключи с истёкшим сроком каждые interval, пока не отменён ctx
+func (is *IdempotencyService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := is.PurgeExpired(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "idempotency key purge failed", "error", err)
+			}
+		}
+	}
+}
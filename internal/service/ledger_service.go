@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+)
+
+type LedgerService struct {
+	ledgerRepo repository.LedgerStore
+}
+
+func NewLedgerService(lr repository.LedgerStore) *LedgerService {
+	return &LedgerService{ledgerRepo: lr}
+}
+
+// CheckInvariants проверяет инварианты двойной записи: сумма проводок в каждой валюте равна нулю,
+// а баланс каждого кошелька равен сумме его проводок. При нарушении возвращает отчёт
+// вместе с ErrLedgerImbalanced.
+func (ls *LedgerService) CheckInvariants(ctx context.Context) (*models.LedgerReport, error) {
+	report, err := ls.ledgerRepo.Verify(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	if !report.Balanced() {
+		return report, fmt.Errorf("%w: unbalanced currencies %v, %d mismatched wallets", ErrLedgerImbalanced, report.UnbalancedCurrencies(), len(report.Mismatches))
+	}
+
+	return report, nil
+}
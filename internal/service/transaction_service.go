@@ -4,62 +4,266 @@ import (
     "context"
     "errors"
     "fmt"
+    "log/slog"
+    "strings"
     "time"
 
     "TransactionSystem/internal/models"
     "TransactionSystem/internal/repository"
+    "TransactionSystem/internal/tracing"
+
+    "go.opentelemetry.io/otel/attribute"
+)
+
+const (
+    DefaultPageLimit = 50
+    MaxPageLimit     = 1000
 )
 
+const tracerName = "TransactionSystem/internal/service"
+
+// TransferMetrics учитывает результаты переводов SendMoney
+type TransferMetrics interface {
+    // currency — валюта отправителя, в которой списан amount
+    TransferSucceeded(amount models.Amount, currency models.Currency)
+    // reason — одно из значений TransferFailureReason
+    TransferFailed(reason string)
+}
+
+type noopTransferMetrics struct{}
+
+func (noopTransferMetrics) TransferSucceeded(models.Amount, models.Currency) {}
+func (noopTransferMetrics) TransferFailed(string)           {}
+
 type TransactionService struct {
-    transactionRepo *repository.TransactionRepository
-    walletRepo      *repository.WalletRepository
+    transactionRepo repository.TransactionStore
+    walletRepo      repository.WalletStore
+    metrics         TransferMetrics
+    currencies      *models.Currencies
+    fx              *FXService
 }
 
-func NewTransactionService(tr *repository.TransactionRepository, wr *repository.WalletRepository) *TransactionService {
+func NewTransactionService(tr repository.TransactionStore, wr repository.WalletStore) *TransactionService {
     return &TransactionService{
         transactionRepo: tr,
         walletRepo:      wr,
+        metrics:         noopTransferMetrics{},
+        currencies:      models.ISOCurrencies(),
+    }
+}
+
+// SetCurrencies задаёт реестр допустимых валют; по умолчанию только валюты ISO 4217
+func (ts *TransactionService) SetCurrencies(c *models.Currencies) {
+    ts.currencies = c
+}
+
+// SetFX подключает курсы для ConvertMoney; без них переводы с конвертацией отклоняются
+func (ts *TransactionService) SetFX(fx *FXService) {
+    ts.fx = fx
+}
+
+// SetMetrics подключает учёт переводов; nil, как и значение по умолчанию, отключает его
+func (ts *TransactionService) SetMetrics(m TransferMetrics) {
+    if m == nil {
+        m = noopTransferMetrics{}
     }
+    ts.metrics = m
 }
 
-func (ts *TransactionService) SendMoney(ctx context.Context, from, to string, amount float64) error {
+// SendMoney переводит amount с кошелька from на кошелёк to и возвращает созданную транзакцию
+// вместе с итоговыми балансами обоих кошельков
+func (ts *TransactionService) SendMoney(ctx context.Context, from, to string, amount models.Amount) (_ *models.Transaction, err error) {
+    ctx, span := tracing.Start(ctx, tracerName, "TransactionService.SendMoney",
+        attribute.String("wallet.from", from),
+        attribute.String("wallet.to", to),
+        attribute.String("amount", amount.String()),
+    )
+    defer func() { tracing.End(span, err) }()
+
+    transaction, err := ts.sendMoney(ctx, from, to, amount)
+    if err != nil {
+        reason := TransferFailureReason(err)
+        ts.metrics.TransferFailed(reason)
+        slog.WarnContext(ctx, "transfer failed", "from", from, "to", to, "amount", amount, "reason", reason, "error", err)
+        return nil, err
+    }
+
+    ts.metrics.TransferSucceeded(amount, transaction.Currency)
+    slog.InfoContext(ctx, "transfer completed", "transaction_id", transaction.Id, "from", from, "to", to, "amount", amount)
+    return transaction, nil
+}
+
+func (ts *TransactionService) sendMoney(ctx context.Context, from, to string, amount models.Amount) (*models.Transaction, error) {
     if from == to {
-        return errors.New("sender and receiver cannot be the same")
+        return nil, ErrSameWallet
     }
-    if amount <= 0 {
-        return errors.New("amount must be greater than zero")
+    if amount.Sign() <= 0 {
+        return nil, ErrInvalidAmount
     }
 
-    fromBalance, err := ts.walletRepo.GetWalletBalance(ctx, from)
+    // Проверка баланса выполняется внутри транзакции БД после блокировки кошельков,
+    // поэтому параллельные переводы не могут увести баланс в минус
+    // Списать средства может только владелец кошелька-отправителя.
+    // Переводы между кошельками в разных валютах без конвертации отклоняются.
+    transaction, err := ts.transactionRepo.ExecuteTransfer(ctx, from, to, amount, func(fromWallet, toWallet *models.Wallet) error {
+        if err := authorizeWallet(ctx, fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(toWallet); err != nil {
+            return err
+        }
+        if fromWallet.Currency != toWallet.Currency {
+            return &CurrencyMismatchError{From: fromWallet.Currency, To: toWallet.Currency}
+        }
+        if err := ts.currencies.CheckAmount(fromWallet.Currency, amount); err != nil {
+            return err
+        }
+        if fromWallet.Available().Cmp(amount) < 0 {
+            return ErrInsufficientFunds
+        }
+        return nil
+    })
     if err != nil {
-        return fmt.Errorf("failed to get sender balance: %w", err)
+        return nil, notFound(err, ErrWalletNotFound)
     }
-    if fromBalance < amount {
-        return errors.New("insufficient funds")
+
+    return transaction, nil
+}
+
+// ConvertMoney переводит amount в валюте кошелька from на кошелёк to в другой валюте.
+// Курс берётся из котировки quoteId или, если она не указана, у поставщика курсов.
+// Зачисляемая сумма округляется вниз до точности валюты получателя, остаток записывается в транзакцию.
+func (ts *TransactionService) ConvertMoney(ctx context.Context, from, to string, amount models.Amount, quoteId string) (_ *models.Transaction, err error) {
+    ctx, span := tracing.Start(ctx, tracerName, "TransactionService.ConvertMoney",
+        attribute.String("wallet.from", from),
+        attribute.String("wallet.to", to),
+        attribute.String("amount", amount.String()),
+        attribute.String("fx.quote_id", quoteId),
+    )
+    defer func() { tracing.End(span, err) }()
+
+    transaction, err := ts.convertMoney(ctx, from, to, amount, quoteId)
+    if err != nil {
+        reason := TransferFailureReason(err)
+        ts.metrics.TransferFailed(reason)
+        slog.WarnContext(ctx, "conversion failed", "from", from, "to", to, "amount", amount, "quote_id", quoteId, "reason", reason, "error", err)
+        return nil, err
+    }
+
+    ts.metrics.TransferSucceeded(amount, transaction.Currency)
+    c := transaction.Conversion
+    slog.InfoContext(ctx, "conversion completed", "transaction_id", transaction.Id, "from", from, "to", to,
+        "amount", amount, "currency", transaction.Currency, "to_amount", c.ToAmount, "to_currency", c.ToCurrency,
+        "rate", c.Rate, "residue", c.Residue)
+    return transaction, nil
+}
+
+func (ts *TransactionService) convertMoney(ctx context.Context, from, to string, amount models.Amount, quoteId string) (*models.Transaction, error) {
+    if from == to {
+        return nil, ErrSameWallet
+    }
+    if amount.Sign() <= 0 {
+        return nil, ErrInvalidAmount
+    }
+    if ts.fx == nil {
+        return nil, fmt.Errorf("%w: currency conversion is not configured", ErrFXRateNotFound)
     }
 
-    toBalance, err := ts.walletRepo.GetWalletBalance(ctx, to)
+    // Владелец кошелька не меняется, поэтому права на отправителя проверяются до того,
+    // как читаются котировка и кошелёк получателя
+    fromWallet, err := ts.walletRepo.GetWallet(ctx, from)
     if err != nil {
-        return fmt.Errorf("failed to get receiver balance: %w", err)
+        return nil, notFound(err, ErrWalletNotFound)
+    }
+    if err := authorizeWallet(ctx, fromWallet); err != nil {
+        return nil, err
     }
 
-    newFromBalance := fromBalance - amount
-    newToBalance := toBalance + amount
+    // Валюты пары известны из котировки либо из самих кошельков; валюта кошелька не меняется,
+    // поэтому повторная проверка под блокировкой нужна только для котировки
+    var fromCurrency, toCurrency models.Currency
+    var rate models.Rate
+    if quoteId != "" {
+        quote, err := ts.fx.Quote(ctx, quoteId)
+        if err != nil {
+            return nil, err
+        }
+        fromCurrency, toCurrency, rate = quote.From, quote.To, quote.Rate
+    } else {
+        toWallet, err := ts.walletRepo.GetWallet(ctx, to)
+        if err != nil {
+            return nil, notFound(err, ErrWalletNotFound)
+        }
+        fromCurrency, toCurrency = fromWallet.Currency, toWallet.Currency
 
-    // проиводим транзакцию
-    return ts.transactionRepo.ExecuteTransfer(
-        ctx,
-        from,
-        to,
-        newFromBalance,
-        newToBalance,
-        amount,
-    )
+        if rate, err = ts.fx.Rate(ctx, fromCurrency, toCurrency); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := ts.currencies.CheckAmount(fromCurrency, amount); err != nil {
+        return nil, err
+    }
+    scale, err := ts.currencies.Scale(toCurrency)
+    if err != nil {
+        return nil, err
+    }
+    toAmount, residue, err := models.Convert(amount, rate, scale)
+    if err != nil {
+        return nil, err
+    }
+    if toAmount.IsZero() {
+        return nil, &ValidationError{ErrInvalidAmount, "amount", fmt.Sprintf("%s %s converts to zero %s", amount, fromCurrency, toCurrency)}
+    }
+
+    conversion := models.Conversion{Rate: rate, ToAmount: toAmount, ToCurrency: toCurrency, Residue: residue, QuoteId: quoteId}
+
+    transaction, err := ts.transactionRepo.ExecuteConversion(ctx, from, to, amount, conversion, func(fromWallet, toWallet *models.Wallet) error {
+        if err := authorizeWallet(ctx, fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(toWallet); err != nil {
+            return err
+        }
+        if fromWallet.Currency != fromCurrency || toWallet.Currency != toCurrency {
+            return fmt.Errorf("%w: quote is %s/%s, wallets are %s/%s",
+                ErrFXQuoteMismatch, fromCurrency, toCurrency, fromWallet.Currency, toWallet.Currency)
+        }
+        if fromWallet.Available().Cmp(amount) < 0 {
+            return ErrInsufficientFunds
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, notFound(err, ErrWalletNotFound)
+    }
+
+    return transaction, nil
 }
 
+// GetLastTransactions возвращает limit последних транзакций. Клиент, не являющийся
+// администратором, видит только транзакции своих кошельков.
 func (ts *TransactionService) GetLastTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
     if limit <= 0 {
-        return nil, errors.New("limit must be greater than zero")
+        return nil, ErrInvalidLimit
+    }
+
+    if owner := ownerScope(ctx); owner != nil {
+        transactions, err := ts.transactionRepo.ListTransactions(ctx, models.TransactionFilter{
+            WalletRole: models.WalletRoleAny,
+            Owner:      owner,
+            Limit:      limit,
+        })
+        if err != nil {
+            return nil, fmt.Errorf("failed to retrieve transactions: %w", err)
+        }
+        return transactions, nil
     }
 
     transactions, err := ts.transactionRepo.GetLastTransactions(ctx, limit)
@@ -70,10 +274,114 @@ func (ts *TransactionService) GetLastTransactions(ctx context.Context, limit int
     return transactions, nil
 }
 
+// ListTransactions возвращает страницу транзакций по фильтру, от новых к старым.
+// cursor — значение NextCursor предыдущей страницы, для первой страницы пустая строка.
+// Выборка клиента, не являющегося администратором, ограничена его кошельками.
+func (ts *TransactionService) ListTransactions(ctx context.Context, filter models.TransactionFilter, cursor string) (*models.TransactionPage, error) {
+    if filter.Limit == 0 {
+        filter.Limit = DefaultPageLimit
+    }
+    if filter.Limit < 0 || filter.Limit > MaxPageLimit {
+        return nil, &ValidationError{ErrInvalidFilter, "limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit)}
+    }
+
+    if filter.WalletRole == "" {
+        filter.WalletRole = models.WalletRoleAny
+    }
+    if !filter.WalletRole.Valid() {
+        return nil, &ValidationError{ErrInvalidFilter, "role", "must be one of any, sender, receiver"}
+    }
+
+    if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.Cmp(*filter.MaxAmount) > 0 {
+        return nil, &ValidationError{ErrInvalidFilter, "min_amount", "must not exceed max_amount"}
+    }
+    if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+        return nil, &ValidationError{ErrInvalidFilter, "since", "must be before until"}
+    }
+
+    if cursor != "" {
+        after, err := models.ParseTransactionCursor(cursor)
+        if err != nil {
+            return nil, err
+        }
+        filter.After = after
+    }
+
+    filter.Owner = ownerScope(ctx)
+
+    // Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+    limit := filter.Limit
+    filter.Limit++
+
+    transactions, err := ts.transactionRepo.ListTransactions(ctx, filter)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list transactions: %w", err)
+    }
+
+    page := &models.TransactionPage{Transactions: transactions}
+    if len(transactions) > limit {
+        page.Transactions = transactions[:limit]
+        last := page.Transactions[limit-1]
+        page.NextCursor = models.TransactionCursor{CreatedAt: last.CreatedAt, Id: last.Id}.Encode()
+    }
+
+    return page, nil
+}
+
+// GetWalletHistory возвращает страницу истории кошелька: направление, контрагента, сумму
+// и баланс кошелька после каждой транзакции, от новых к старым
+func (ts *TransactionService) GetWalletHistory(ctx context.Context, address string, filter models.WalletHistoryFilter, cursor string) (*models.WalletHistoryPage, error) {
+    if filter.Limit == 0 {
+        filter.Limit = DefaultPageLimit
+    }
+    if filter.Limit < 0 || filter.Limit > MaxPageLimit {
+        return nil, &ValidationError{ErrInvalidFilter, "limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit)}
+    }
+    if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+        return nil, &ValidationError{ErrInvalidFilter, "since", "must be before until"}
+    }
+
+    if cursor != "" {
+        after, err := models.ParseTransactionCursor(cursor)
+        if err != nil {
+            return nil, err
+        }
+        filter.After = after
+    }
+
+    wallet, err := ts.walletRepo.GetWallet(ctx, address)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+    }
+    if err := authorizeWallet(ctx, wallet); err != nil {
+        return nil, err
+    }
+
+    limit := filter.Limit
+    filter.Limit++
+
+    entries, err := ts.transactionRepo.GetWalletHistory(ctx, address, filter)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get wallet history: %w", err)
+    }
+
+    page := &models.WalletHistoryPage{Entries: entries}
+    if len(entries) > limit {
+        page.Entries = entries[:limit]
+        last := page.Entries[limit-1]
+        page.NextCursor = models.TransactionCursor{CreatedAt: last.CreatedAt, Id: last.TransactionId}.Encode()
+    }
+
+    return page, nil
+}
+
 func (ts *TransactionService) GetTransactionById(ctx context.Context, id int64) (*models.Transaction, error) {
     transaction, err := ts.transactionRepo.GetTransactionById(ctx, id)
     if err != nil {
-        return nil, fmt.Errorf("failed to get transaction: %w", err)
+        return nil, fmt.Errorf("failed to get transaction: %w", notFound(err, ErrTransactionNotFound))
+    }
+    if err := ts.authorizeTransaction(ctx, transaction); err != nil {
+        return nil, err
     }
     return transaction, nil
 }
@@ -81,14 +389,107 @@ func (ts *TransactionService) GetTransactionById(ctx context.Context, id int64)
 func (ts *TransactionService) GetTransactionByInfo(ctx context.Context, from, to string, createdAt time.Time) (*models.Transaction, error) {
     transaction, err := ts.transactionRepo.GetTransactionByInfo(ctx, from, to, createdAt)
     if err != nil {
-        return nil, fmt.Errorf("failed to get transaction by info: %w", err)
+        return nil, fmt.Errorf("failed to get transaction by info: %w", notFound(err, ErrTransactionNotFound))
+    }
+    if err := ts.authorizeTransaction(ctx, transaction); err != nil {
+        return nil, err
     }
     return transaction, nil
 }
 
-func (ts *TransactionService) RemoveTransaction(ctx context.Context, id int64) error {
+// authorizeTransaction разрешает просмотр транзакции владельцу любого из её кошельков
+func (ts *TransactionService) authorizeTransaction(ctx context.Context, t *models.Transaction) error {
+    if ownerScope(ctx) == nil {
+        return nil
+    }
+    for _, address := range []string{t.From, t.To} {
+        wallet, err := ts.walletRepo.GetWallet(ctx, address)
+        if err != nil {
+            // Кошелёк мог быть удалён администратором
+            var nf *repository.NotFoundError
+            if errors.As(err, &nf) {
+                continue
+            }
+            return fmt.Errorf("failed to get wallet %s: %w", address, err)
+        }
+        if authorizeWallet(ctx, wallet) == nil {
+            return nil
+        }
+    }
+    return fmt.Errorf("%w: transaction %d belongs to another client", ErrForbidden, t.Id)
+}
+
+// ReverseTransaction сторнирует транзакцию id: возвращает сумму с получателя отправителю
+// компенсирующей транзакцией с причиной reason и помечает исходную как сторнированную.
+// Сторнировать можно только один раз и только пока у получателя хватает средств.
+func (ts *TransactionService) ReverseTransaction(ctx context.Context, id int64, reason string) (_ *models.Transaction, err error) {
+    ctx, span := tracing.Start(ctx, tracerName, "TransactionService.ReverseTransaction", attribute.Int64("transaction.id", id))
+    defer func() { tracing.End(span, err) }()
+
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return nil, &ValidationError{ErrReversalReasonRequired, "reason", "must not be empty"}
+    }
+
+    var amount models.Amount
+
+    checkOriginal := func(original *models.Transaction) error {
+        if original.ReversalOf != nil {
+            return ErrTransactionNotReversible
+        }
+        if original.ReversedBy != nil {
+            return ErrTransactionAlreadyReversed
+        }
+        // Обратная конвертация потребовала бы другого курса
+        if original.Conversion != nil {
+            return ErrConversionNotReversible
+        }
+        amount = original.Amount
+        return nil
+    }
+
+    // Компенсирующий перевод идёт от получателя исходной транзакции,
+    // его баланс не должен уйти в минус, а сторнировать может только его владелец
+    reversal, err := ts.transactionRepo.ReverseTransfer(ctx, id, reason, checkOriginal, func(fromWallet, toWallet *models.Wallet) error {
+        if err := authorizeWallet(ctx, fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(fromWallet); err != nil {
+            return err
+        }
+        if err := requireActive(toWallet); err != nil {
+            return err
+        }
+        if fromWallet.Available().Cmp(amount) < 0 {
+            return ErrInsufficientFunds
+        }
+        return nil
+    })
+    if err != nil {
+        var nf *repository.NotFoundError
+        if errors.As(err, &nf) && nf.Entity == "wallet" {
+            return nil, fmt.Errorf("failed to reverse transaction: %w", notFound(err, ErrWalletNotFound))
+        }
+        return nil, fmt.Errorf("failed to reverse transaction: %w", notFound(err, ErrTransactionNotFound))
+    }
+
+    slog.InfoContext(ctx, "transaction reversed", "transaction_id", reversal.Id, "reversal_of", id,
+        "from", reversal.From, "to", reversal.To, "amount", reversal.Amount, "reason", reason)
+    return reversal, nil
+}
+
+// RemoveTransaction физически удаляет запись о транзакции, не меняя балансы.
+// Используется только в административном режиме, обычный способ отмены — ReverseTransaction.
+func (ts *TransactionService) RemoveTransaction(ctx context.Context, id int64) (err error) {
+    ctx, span := tracing.Start(ctx, tracerName, "TransactionService.RemoveTransaction", attribute.Int64("transaction.id", id))
+    defer func() { tracing.End(span, err) }()
+
+    if err := requireAdmin(ctx); err != nil {
+        return err
+    }
     if err := ts.transactionRepo.RemoveTransaction(ctx, id); err != nil {
-        return fmt.Errorf("failed to remove transaction: %w", err)
+        return fmt.Errorf("failed to remove transaction: %w", notFound(err, ErrTransactionNotFound))
     }
+    slog.WarnContext(ctx, "transaction removed", "transaction_id", id)
     return nil
 }
@@ -3,31 +3,68 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
+	"TransactionSystem/internal/auth"
 	"TransactionSystem/internal/repository"
 	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/tracing"
 	
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type WalletService struct {
-	walletRepo *repository.WalletRepository
+	walletRepo repository.WalletStore
+	currencies *models.Currencies
 }
 
-func NewWalletService(walletRepo *repository.WalletRepository) *WalletService {
-	return &WalletService{walletRepo: walletRepo}
+func NewWalletService(walletRepo repository.WalletStore) *WalletService {
+	return &WalletService{walletRepo: walletRepo, currencies: models.ISOCurrencies()}
 }
 
-func (ws *WalletService) CreateWallet(ctx context.Context, balance float64) (string, error) {
+// SetCurrencies задаёт реестр допустимых валют; по умолчанию только валюты ISO 4217
+func (ws *WalletService) SetCurrencies(c *models.Currencies) {
+	ws.currencies = c
+}
+
+// CreateWallet создаёт кошелёк в валюте currency (пустая строка — models.DefaultCurrency),
+// владельцем которого становится вызывающий клиент. Ненулевой начальный баланс выпускает
+// новые средства, поэтому при аутентификации он доступен только администратору.
+func (ws *WalletService) CreateWallet(ctx context.Context, currency models.Currency, balance models.Amount) (_ string, err error) {
 	address := uuid.New().String()
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
 
-	if (balance < 0) {
-		return "", fmt.Errorf("failed to create wallet: balance can't be negative")
+	ctx, span := tracing.Start(ctx, tracerName, "WalletService.CreateWallet",
+		attribute.String("wallet.address", address),
+		attribute.String("wallet.currency", string(currency)),
+		attribute.String("amount", balance.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if balance.Sign() < 0 {
+		return "", ErrNegativeBalance
+	}
+	if err := ws.currencies.CheckAmount(currency, balance); err != nil {
+		return "", err
 	}
 
-	if err := ws.walletRepo.CreateWallet(ctx, address, balance); err != nil {
+	var owner *int64
+	if p, ok := auth.FromContext(ctx); ok {
+		if !balance.IsZero() {
+			if err := requireAdmin(ctx); err != nil {
+				return "", err
+			}
+		}
+		owner = &p.ClientId
+	}
+
+	if err := ws.walletRepo.CreateWallet(ctx, address, currency, balance, owner); err != nil {
 		return "", fmt.Errorf("failed to create wallet: %w", err)
 	}
+	slog.InfoContext(ctx, "wallet created", "address", address, "currency", currency, "balance", balance, "owner", owner)
 	return address, nil
 }
 
@@ -35,29 +72,147 @@ func (ws *WalletService) IsEmpty(ctx context.Context) (bool, error) {
 	return ws.walletRepo.IsEmpty(ctx)
 }
 
-func (ws *WalletService) GetBalance(ctx context.Context, address string) (float64, error) {
-	balance, err := ws.walletRepo.GetWalletBalance(ctx, address)
+// CountWallets возвращает число кошельков по состояниям; состояния без кошельков имеют значение 0
+func (ws *WalletService) CountWallets(ctx context.Context) (map[models.WalletStatus]int64, error) {
+	counts, err := ws.walletRepo.CountWalletsByStatus(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get balance for wallet %s: %w", address, err)
+		return nil, err
+	}
+	for _, status := range []models.WalletStatus{models.WalletActive, models.WalletFrozen, models.WalletClosed} {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
 	}
-	return balance, nil
+	return counts, nil
 }
 
-func (ws *WalletService) GetWallet(ctx context.Context, address string) (*models.Wallet, error) {
+func (ws *WalletService) GetBalance(ctx context.Context, address string) (_ models.Amount, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "WalletService.GetBalance", attribute.String("wallet.address", address))
+	defer func() { tracing.End(span, err) }()
+
 	wallet, err := ws.walletRepo.GetWallet(ctx, address)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get wallet %s: %w", address, err)
+		return models.Amount{}, fmt.Errorf("failed to get balance for wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+	}
+	if err := authorizeWallet(ctx, wallet); err != nil {
+		return models.Amount{}, err
+	}
+	return wallet.Balance, nil
+}
+
+func (ws *WalletService) GetWallet(ctx context.Context, address string) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "WalletService.GetWallet", attribute.String("wallet.address", address))
+	defer func() { tracing.End(span, err) }()
+
+	wallet, err := ws.walletRepo.GetWallet(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+	}
+	if err := authorizeWallet(ctx, wallet); err != nil {
+		return nil, err
 	}
 	return wallet, nil
 }
 
-func (ws *WalletService) UpdateBalance(ctx context.Context, address string, newBalance float64) error {
-	if newBalance < 0 {
-		return fmt.Errorf("balance cannot be negative")
+// UpdateBalance устанавливает баланс кошелька напрямую; при аутентификации доступно только администратору
+func (ws *WalletService) UpdateBalance(ctx context.Context, address string, newBalance models.Amount) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "WalletService.UpdateBalance",
+		attribute.String("wallet.address", address),
+		attribute.String("amount", newBalance.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if newBalance.Sign() < 0 {
+		return ErrNegativeBalance
 	}
-	return ws.walletRepo.UpdateWalletBalabnce(ctx, address, newBalance)
+
+	wallet, err := ws.walletRepo.GetWallet(ctx, address)
+	if err != nil {
+		return fmt.Errorf("failed to update balance for wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+	}
+	if err := ws.currencies.CheckAmount(wallet.Currency, newBalance); err != nil {
+		return err
+	}
+	// Захолдированные средства должны оставаться на балансе до списания или снятия холда
+	if newBalance.Cmp(wallet.Held) < 0 {
+		return &ValidationError{ErrNegativeBalance, "balance", "must not be less than the held amount " + wallet.Held.String()}
+	}
+
+	if err := ws.walletRepo.UpdateWalletBalabnce(ctx, address, newBalance); err != nil {
+		return fmt.Errorf("failed to update balance for wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+	}
+	slog.InfoContext(ctx, "wallet balance adjusted", "address", address, "balance", newBalance)
+	return nil
+}
+
+// FreezeWallet временно блокирует переводы с кошелька и на него
+func (ws *WalletService) FreezeWallet(ctx context.Context, address string) (*models.Wallet, error) {
+	return ws.changeStatus(ctx, "WalletService.FreezeWallet", address, models.WalletFrozen, func(w *models.Wallet) error {
+		if w.Status != models.WalletActive {
+			return fmt.Errorf("%w: %s -> %s", ErrWalletStatusTransition, w.Status, models.WalletFrozen)
+		}
+		return nil
+	})
+}
+
+// UnfreezeWallet снимает блокировку, установленную FreezeWallet
+func (ws *WalletService) UnfreezeWallet(ctx context.Context, address string) (*models.Wallet, error) {
+	return ws.changeStatus(ctx, "WalletService.UnfreezeWallet", address, models.WalletActive, func(w *models.Wallet) error {
+		if w.Status != models.WalletFrozen {
+			return fmt.Errorf("%w: %s -> %s", ErrWalletStatusTransition, w.Status, models.WalletActive)
+		}
+		return nil
+	})
 }
 
-func (ws *WalletService) RemoveWallet(ctx context.Context, address string) error {
-	return ws.walletRepo.RemoveWallet(ctx, address)
+// CloseWallet окончательно закрывает активный кошелёк с нулевым балансом.
+// Запись о кошельке сохраняется вместе с его историей.
+func (ws *WalletService) CloseWallet(ctx context.Context, address string) (*models.Wallet, error) {
+	return ws.changeStatus(ctx, "WalletService.CloseWallet", address, models.WalletClosed, func(w *models.Wallet) error {
+		if w.Status != models.WalletActive {
+			return fmt.Errorf("%w: %s -> %s", ErrWalletStatusTransition, w.Status, models.WalletClosed)
+		}
+		if !w.Balance.IsZero() {
+			return ErrWalletBalanceNotZero
+		}
+		return nil
+	})
+}
+
+func (ws *WalletService) changeStatus(ctx context.Context, op, address string, status models.WalletStatus, check repository.WalletCheck) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, tracerName, op,
+		attribute.String("wallet.address", address),
+		attribute.String("wallet.status", string(status)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	wallet, err := ws.walletRepo.UpdateWalletStatus(ctx, address, status, func(w *models.Wallet) error {
+		if err := authorizeWallet(ctx, w); err != nil {
+			return err
+		}
+		return check(w)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set status %s for wallet %s: %w", status, address, notFound(err, ErrWalletNotFound))
+	}
+	slog.InfoContext(ctx, "wallet status changed", "address", address, "status", status, "balance", wallet.Balance)
+	return wallet, nil
+}
+
+// RemoveWallet физически удаляет кошелёк без истории транзакций. Используется только
+// в административном режиме, обычный способ вывести кошелёк из оборота — CloseWallet.
+func (ws *WalletService) RemoveWallet(ctx context.Context, address string) (err error) {
+	ctx, span := tracing.Start(ctx, tracerName, "WalletService.RemoveWallet", attribute.String("wallet.address", address))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ws.walletRepo.RemoveWallet(ctx, address, func(w *models.Wallet) error {
+		return authorizeWallet(ctx, w)
+	}); err != nil {
+		return fmt.Errorf("failed to remove wallet %s: %w", address, notFound(err, ErrWalletNotFound))
+	}
+	slog.WarnContext(ctx, "wallet removed", "address", address)
+	return nil
 }
\ No newline at end of file
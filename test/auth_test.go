@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_GenerateKey(t *testing.T) {
+	key, prefix, hash, err := auth.GenerateKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "tsk_"+prefix+"_"))
+	parsed, ok := auth.ParseKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+
+	assert.True(t, auth.VerifyKey(key, hash))
+	assert.False(t, auth.VerifyKey(key+"x", hash))
+	assert.NotContains(t, hash, key)
+
+	other, _, _, err := auth.GenerateKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	for _, bad := range []string{"", "tsk_", "tsk_abc_secret", "api_0123456789ab_secret", "tsk_0123456789ab_"} {
+		_, ok := auth.ParseKey(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestAuth_RequiresAPIKey(t *testing.T) {
+	captureLogs(t)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	// Ключ неверного формата отклоняется до обращения к базе, поэтому репозиторий не нужен
+	clientService := service.NewClientService(nil)
+	router := api.NewRouter(nil, nil, nil, healthService, clientService, api.Options{AuthMode: api.AuthModeAPIKey})
+
+	for _, key := range []string{"", "not-a-key"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/wallet/some-address", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "X-API-Key", rec.Header().Get("WWW-Authenticate"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "unauthenticated", body["code"])
+	}
+
+	// Пробы доступны без ключа
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,239 @@
+package service_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"TransactionSystem/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, _, err := config.LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
+
+func TestLoadConfig_Layers(t *testing.T) {
+	path := writeConfigFile(t, `
+database:
+  host: from-file
+  port: 6432
+  password: file-secret
+server:
+  port: 9000
+idempotency:
+  ttl: 1h
+`)
+
+	t.Setenv("TS_CONFIG", path)
+	t.Setenv("TS_DATABASE_PASSWORD", "env-secret")
+	t.Setenv("TS_SERVER_PORT", "9100")
+	t.Setenv("TS_SERVER_ADMIN_MODE", "true")
+
+	cfg, args, err := config.LoadConfig([]string{"--server.port=9200", "--idempotency.ttl", "30m", "migrate", "up"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-file", cfg.Database.Host)
+	assert.Equal(t, 6432, cfg.Database.Port)
+	assert.Equal(t, "env-secret", cfg.Database.Password)
+	assert.Equal(t, "transaction_system", cfg.Database.Dbname)
+	assert.Equal(t, 9200, cfg.Server.Port)
+	assert.True(t, cfg.Server.AdminMode)
+	assert.Equal(t, 30*time.Minute, cfg.Idempotency.TTL)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+}
+
+func TestLoadConfig_ConfigFlagOverridesEnvPath(t *testing.T) {
+	t.Setenv("TS_CONFIG", filepath.Join(t.TempDir(), "missing.yml"))
+	path := writeConfigFile(t, "server:\n  host: 127.0.0.1\n")
+
+	cfg, _, err := config.LoadConfig([]string{"--config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", cfg.Server.Host)
+
+	// Явно указанный, но отсутствующий файл — ошибка
+	_, _, err = config.LoadConfig(nil)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_ListsEveryInvalidKey(t *testing.T) {
+	t.Setenv("TS_DATABASE_PORT", "not-a-number")
+	t.Setenv("TS_DATABASE_HOST", "")
+
+	_, _, err := config.LoadConfig([]string{"--server.port=0", "--database.sslmode=sometimes", "--database.schema=bad-name",
+		"--server.shutdown_timeout=0s", "--server.tls.cert_file=cert.pem", "--log.level=verbose", "--auth.mode=basic"})
+
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+
+	keys := make([]string, 0, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		keys = append(keys, fe.Key)
+	}
+	assert.ElementsMatch(t, []string{"database.port", "database.host", "database.sslmode", "database.schema", "server.port",
+		"server.shutdown_timeout", "server.tls.key_file", "log.level", "auth.mode"}, keys)
+	assert.Contains(t, err.Error(), "TS_DATABASE_PORT")
+}
+
+func TestLoadConfig_DatabaseDriver(t *testing.T) {
+	// Для SQLite параметры подключения к PostgreSQL не проверяются
+	cfg, _, err := config.LoadConfig([]string{"--database.driver=sqlite", "--database.sslmode=sometimes"})
+	require.NoError(t, err)
+	assert.Equal(t, config.DriverSQLite, cfg.Database.Driver)
+
+	_, _, err = config.LoadConfig([]string{"--database.driver=sqlite", "--database.path="})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "database.path", verr.Errors[0].Key)
+
+	// Хранилищу в памяти параметры подключения не нужны, но без него не выдать первый API-ключ
+	cfg, _, err = config.LoadConfig([]string{"--database.driver=memory", "--database.host="})
+	require.NoError(t, err)
+	assert.Equal(t, config.DriverMemory, cfg.Database.Driver)
+
+	_, _, err = config.LoadConfig([]string{"--database.driver=memory", "--auth.mode=api_key"})
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "auth.mode", verr.Errors[0].Key)
+
+	t.Setenv("TS_DATABASE_DRIVER", "mysql")
+	_, _, err = config.LoadConfig(nil)
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "database.driver", verr.Errors[0].Key)
+}
+
+func TestLoadConfig_CustomCurrencies(t *testing.T) {
+	path := writeConfigFile(t, "currencies:\n  custom:\n    PTS: 0\n")
+	cfg, _, err := config.LoadConfig([]string{"--config", path})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"PTS": 0}, cfg.Currencies.Custom)
+
+	path = writeConfigFile(t, "currencies:\n  custom:\n    EUR: 2\n")
+	_, _, err = config.LoadConfig([]string{"--config", path})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "currencies.custom", verr.Errors[0].Key)
+}
+
+func TestLoadConfig_JWTRequiresOneKeySource(t *testing.T) {
+	_, _, err := config.LoadConfig([]string{"--auth.mode=jwt"})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "auth.jwt.jwks_file", verr.Errors[0].Key)
+
+	_, _, err = config.LoadConfig([]string{"--auth.mode=jwt", "--auth.jwt.jwks_file=jwks.json", "--auth.jwt.public_key_file=key.pem"})
+	assert.True(t, errors.As(err, &verr))
+
+	cfg, _, err := config.LoadConfig([]string{"--auth.mode=jwt", "--auth.jwt.jwks_file=jwks.json"})
+	require.NoError(t, err)
+	assert.Equal(t, "sub", cfg.Auth.JWT.SubjectClaim)
+	assert.Equal(t, 30*time.Second, cfg.Auth.JWT.Leeway)
+}
+
+func TestLoadConfig_FX(t *testing.T) {
+	cfg, _, err := config.LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.FXProviderManual, cfg.FX.Provider)
+	assert.Equal(t, 30*time.Second, cfg.FX.QuoteTTL)
+
+	_, _, err = config.LoadConfig([]string{"--fx.provider=static", "--fx.quote_ttl=0s"})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 2)
+	assert.Equal(t, "fx.rates_file", verr.Errors[0].Key)
+	assert.Equal(t, "fx.quote_ttl", verr.Errors[1].Key)
+
+	_, _, err = config.LoadConfig([]string{"--fx.provider=remote"})
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, "fx.provider", verr.Errors[0].Key)
+}
+
+func TestLoadConfig_Holds(t *testing.T) {
+	cfg, _, err := config.LoadConfig([]string{"--holds.default_ttl=5m"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Holds.DefaultTTL)
+	assert.Equal(t, 168*time.Hour, cfg.Holds.MaxTTL)
+	assert.Equal(t, time.Minute, cfg.Holds.ExpiryInterval)
+
+	_, _, err = config.LoadConfig([]string{"--holds.default_ttl=2h", "--holds.max_ttl=1h", "--holds.expiry_interval=0s"})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 2)
+	assert.Equal(t, "holds.expiry_interval", verr.Errors[0].Key)
+	assert.Equal(t, "holds.default_ttl", verr.Errors[1].Key)
+}
+
+func TestLoadConfig_IdempotencyPurgeInterval(t *testing.T) {
+	cfg, _, err := config.LoadConfig([]string{"--idempotency.purge_interval=10m"})
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, cfg.Idempotency.PurgeInterval)
+
+	_, _, err = config.LoadConfig([]string{"--idempotency.purge_interval=0s"})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "idempotency.purge_interval", verr.Errors[0].Key)
+
+	cfg, _, err = config.LoadConfig([]string{"--idempotency.max_body_bytes=4096"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), cfg.Idempotency.MaxBodyBytes)
+
+	_, _, err = config.LoadConfig([]string{"--idempotency.max_body_bytes=0"})
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Errors, 1)
+	assert.Equal(t, "idempotency.max_body_bytes", verr.Errors[0].Key)
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, `
+database:
+  hots: db.internal
+  port: 6432
+sever:
+  port: 9000
+currencies:
+  custom:
+    PTS: 0
+`)
+
+	_, _, err := config.LoadConfig([]string{"--config", path, "--log.level=verbose"})
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+
+	keys := make([]string, 0, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		keys = append(keys, fe.Key)
+	}
+	assert.ElementsMatch(t, []string{"database.hots", "sever", "log.level"}, keys)
+}
+
+func TestLoadConfig_EmptyFile(t *testing.T) {
+	for name, content := range map[string]string{"empty": "", "comments only": "# nothing here\n"} {
+		t.Run(name, func(t *testing.T) {
+			cfg, _, err := config.LoadConfig([]string{"--config", writeConfigFile(t, content)})
+			require.NoError(t, err)
+			assert.Equal(t, config.Default(), cfg)
+		})
+	}
+}
+
+func TestLoadConfig_BundledFile(t *testing.T) {
+	_, _, err := config.LoadConfig([]string{"--config", "../config/config.yml"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,29 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// skipIfNoDocker пропускает тесты с testcontainers, если Docker недоступен.
+// testcontainers паникует, когда не находит docker host, поэтому панику перехватываем.
+func skipIfNoDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// testSchema — схема, в которой тесты создают таблицы; совпадает со схемой по умолчанию
+const testSchema = "TransactionSystem"
+
+// inSchema подставляет в запрос экранированное имя схемы вместо {schema}, как это делают репозитории
+func inSchema(schema, sql string) string {
+	return strings.ReplaceAll(sql, "{schema}", pgx.Identifier{schema}.Sanitize())
+}
@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"TransactionSystem/internal/fx"
+	"TransactionSystem/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRatesFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rates.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadRatesFile(t *testing.T) {
+	rates, err := fx.LoadRatesFile(writeRatesFile(t, "USD:\n  EUR: 0.92\n  JPY: 151.30\nEUR:\n  USD: \"1.087\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[fx.Pair]models.Rate{
+		{From: "USD", To: "EUR"}: models.MustParseRate("0.92"),
+		{From: "USD", To: "JPY"}: models.MustParseRate("151.30"),
+		{From: "EUR", To: "USD"}: models.MustParseRate("1.087"),
+	}, rates)
+
+	for _, content := range []string{"USD:\n  USD: 1\n", "USD:\n  EUR: 0\n", "USD:\n  EUR: abc\n", "USD: 0.92\n"} {
+		_, err := fx.LoadRatesFile(writeRatesFile(t, content))
+		assert.Error(t, err, content)
+	}
+
+	// Пример из репозитория должен оставаться корректным
+	_, err = fx.LoadRatesFile("../config/fx_rates.yml")
+	assert.NoError(t, err)
+}
+
+func TestStaticRates(t *testing.T) {
+	ctx := context.Background()
+	rates, err := fx.LoadStaticRates(writeRatesFile(t, "USD:\n  EUR: 0.92\n"))
+	require.NoError(t, err)
+
+	rate, err := rates.Rate(ctx, "USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseRate("0.92"), rate)
+
+	// Обратный курс не вычисляется
+	_, err = rates.Rate(ctx, "EUR", "USD")
+	assert.ErrorIs(t, err, models.ErrRateNotFound)
+}
+
+func TestManualRates(t *testing.T) {
+	ctx := context.Background()
+	rates := fx.NewManualRates(map[fx.Pair]models.Rate{{From: "USD", To: "EUR"}: models.MustParseRate("0.92")})
+
+	rate, err := rates.Rate(ctx, "USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseRate("0.92"), rate)
+	_, err = rates.Rate(ctx, "EUR", "USD")
+	assert.ErrorIs(t, err, models.ErrRateNotFound)
+
+	require.NoError(t, rates.SetRate(ctx, "EUR", "USD", models.MustParseRate("1.09")))
+	rate, err = rates.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseRate("1.09"), rate)
+
+	assert.ErrorIs(t, rates.SetRate(ctx, "EUR", "USD", models.Rate{}), models.ErrInvalidRate)
+}
@@ -0,0 +1,239 @@
+package service_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"TransactionSystem/api"
+	"TransactionSystem/config"
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/service"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://issuer.example"
+	testAudience = "transaction-system"
+)
+
+// signingKey — локальный ключ, которым тесты подписывают токены
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func newSigningKeys(t *testing.T) (rsaKey, ecKey, edKey signingKey) {
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return signingKey{"rsa-1", jwt.SigningMethodRS256, rsaPrivate},
+		signingKey{"ec-1", jwt.SigningMethodES256, ecPrivate},
+		signingKey{"ed-1", jwt.SigningMethodEdDSA, edPrivate}
+}
+
+// writeJWKS сохраняет открытые ключи keys в JWKS-файл
+func writeJWKS(t *testing.T, keys ...signingKey) string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for _, k := range keys {
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "RSA", "kid": k.kid, "use": "sig",
+				"n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "EC", "kid": k.kid, "crv": "P-256",
+				"x": b64(pub.X.FillBytes(make([]byte, size))), "y": b64(pub.Y.FillBytes(make([]byte, size))),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": b64(pub)})
+		}
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// signToken подписывает токен с обязательными claims и scope; extra дополняет или заменяет claims
+func signToken(t *testing.T, key signingKey, subject, scope string, extra jwt.MapClaims) string {
+	claims := jwt.MapClaims{
+		"sub":   subject,
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"scope": scope,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.private)
+	require.NoError(t, err)
+	return signed
+}
+
+func jwtConfig(jwksFile string) config.JWTConfig {
+	cfg := config.Default().Auth.JWT
+	cfg.JWKSFile = jwksFile
+	cfg.Issuer = testIssuer
+	cfg.Audience = testAudience
+	return cfg
+}
+
+func TestJWTVerifier_JWKS(t *testing.T) {
+	rsaKey, ecKey, edKey := newSigningKeys(t)
+	verifier, err := auth.NewJWTVerifier(jwtConfig(writeJWKS(t, rsaKey, ecKey, edKey)))
+	require.NoError(t, err)
+
+	for _, key := range []signingKey{rsaKey, ecKey, edKey} {
+		claims, err := verifier.Verify(signToken(t, key, "shop", "wallet:read transfer:create", nil))
+		require.NoError(t, err, key.kid)
+		assert.Equal(t, "shop", claims.Subject)
+		assert.Equal(t, []string{auth.ScopeWalletRead, auth.ScopeTransferCreate}, claims.Scopes)
+	}
+
+	// scope может быть массивом
+	claims, err := verifier.Verify(signToken(t, rsaKey, "shop", "", jwt.MapClaims{"scope": []string{"admin"}}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeAdmin}, claims.Scopes)
+
+	_, otherKey, _ := newSigningKeys(t)
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "shop", "exp": time.Now().Add(time.Hour).Unix()})
+	hmacToken, err := hmac.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "shop"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	rejected := map[string]string{
+		"expired":         signToken(t, rsaKey, "shop", "", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"no exp":          signToken(t, rsaKey, "shop", "", jwt.MapClaims{"exp": nil}),
+		"not yet valid":   signToken(t, rsaKey, "shop", "", jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}),
+		"wrong issuer":    signToken(t, rsaKey, "shop", "", jwt.MapClaims{"iss": "https://evil.example"}),
+		"wrong audience":  signToken(t, rsaKey, "shop", "", jwt.MapClaims{"aud": "other"}),
+		"no subject":      signToken(t, rsaKey, "", "", nil),
+		"unknown key":     signToken(t, signingKey{"rsa-2", jwt.SigningMethodES256, otherKey.private}, "shop", "", nil),
+		"foreign key":     signToken(t, signingKey{ecKey.kid, jwt.SigningMethodES256, otherKey.private}, "shop", "", nil),
+		"bad scope claim": signToken(t, rsaKey, "shop", "", jwt.MapClaims{"scope": 42}),
+		"hmac":            hmacToken,
+		"alg none":        unsigned,
+		"garbage":         "not.a.token",
+	}
+	for name, token := range rejected {
+		_, err := verifier.Verify(token)
+		assert.True(t, errors.Is(err, auth.ErrInvalidToken), "%s: %v", name, err)
+	}
+}
+
+func TestJWTVerifier_PublicKeyFile(t *testing.T) {
+	_, ecKey, _ := newSigningKeys(t)
+	der, err := x509.MarshalPKIXPublicKey(ecKey.private.Public())
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	cfg := jwtConfig("")
+	cfg.PublicKeyFile = path
+	cfg.SubjectClaim = "client_id"
+	verifier, err := auth.NewJWTVerifier(cfg)
+	require.NoError(t, err)
+
+	// Ключ из PEM-файла используется независимо от kid
+	claims, err := verifier.Verify(signToken(t, ecKey, "", "wallet:read", jwt.MapClaims{"client_id": "backoffice"}))
+	require.NoError(t, err)
+	assert.Equal(t, "backoffice", claims.Subject)
+}
+
+func TestJWTVerifier_InvalidKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	for name, jwks := range map[string]string{
+		"empty":        `{"keys":[]}`,
+		"only enc":     `{"keys":[{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`,
+		"bad curve":    `{"keys":[{"kty":"EC","crv":"P-192","x":"AA","y":"AA"}]}`,
+		"off curve":    `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		"unknown type": `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+		"not json":     `keys`,
+	} {
+		_, err := auth.NewJWTVerifier(jwtConfig(write(name+".json", jwks)))
+		assert.Error(t, err, name)
+	}
+
+	cfg := jwtConfig("")
+	cfg.PublicKeyFile = write("public.pem", "not pem")
+	_, err := auth.NewJWTVerifier(cfg)
+	assert.Error(t, err)
+}
+
+func TestJWTAuth_RejectsMissingOrInvalidToken(t *testing.T) {
+	captureLogs(t)
+
+	rsaKey, _, _ := newSigningKeys(t)
+	verifier, err := auth.NewJWTVerifier(jwtConfig(writeJWKS(t, rsaKey)))
+	require.NoError(t, err)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	// Токен отклоняется до обращения к базе, поэтому репозиторий клиентов не нужен
+	router := api.NewRouter(nil, nil, nil, healthService, service.NewClientService(nil),
+		api.Options{AuthMode: api.AuthModeJWT, JWTVerifier: verifier})
+
+	expired := signToken(t, rsaKey, "shop", "wallet:read", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})
+	for header, challenge := range map[string]string{
+		"":                   `Bearer realm="transaction-system"`,
+		"Basic dXNlcjpwYXNz": `Bearer realm="transaction-system"`,
+		"Bearer " + expired:  `Bearer realm="transaction-system", error="invalid_token"`,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/wallet/some-address", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, challenge, rec.Header().Get("WWW-Authenticate"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "unauthenticated", body["code"])
+	}
+}
@@ -0,0 +1,122 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/logging"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs направляет slog по умолчанию в буфер в формате JSON до конца теста
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "debug", logging.FormatJSON)
+	require.NoError(t, err)
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		records = append(records, record)
+	}
+	return records
+}
+
+func findRecord(records []map[string]interface{}, msg string) map[string]interface{} {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestLogging_RequestIDPropagation(t *testing.T) {
+	logs := captureLogs(t)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(service.NewTransactionService(nil, nil), nil, nil, healthService, nil, api.Options{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(`{"from":"wallet-a","to":"wallet-b","amount":-1}`))
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "req-42", rec.Header().Get("X-Request-ID"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "req-42", body["request_id"])
+
+	records := logRecords(t, logs)
+
+	failed := findRecord(records, "transfer failed")
+	require.NotNil(t, failed)
+	assert.Equal(t, "req-42", failed[logging.RequestIDKey])
+	assert.Equal(t, "wallet-a", failed["from"])
+	assert.Equal(t, "wallet-b", failed["to"])
+	assert.Equal(t, -1.0, failed["amount"])
+	assert.Equal(t, "invalid_amount", failed["reason"])
+
+	completed := findRecord(records, "request completed")
+	require.NotNil(t, completed)
+	assert.Equal(t, "req-42", completed[logging.RequestIDKey])
+	assert.Equal(t, "/api/send", completed["route"])
+	assert.Equal(t, 422.0, completed["status"])
+}
+
+func TestLogging_GeneratesRequestID(t *testing.T) {
+	captureLogs(t)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(nil, nil, nil, healthService, nil, api.Options{})
+
+	for _, incoming := range []string{"", "bad id with spaces"} {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		if incoming != "" {
+			req.Header.Set("X-Request-ID", incoming)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		assert.NotEmpty(t, id)
+		assert.NotEqual(t, incoming, id)
+	}
+}
+
+func TestLogging_New(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "warn", logging.FormatText)
+	require.NoError(t, err)
+
+	ctx := logging.WithRequestID(context.Background(), "abc")
+	logger.InfoContext(ctx, "hidden")
+	logger.WarnContext(ctx, "shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "request_id=abc")
+
+	_, err = logging.New(&buf, "verbose", logging.FormatText)
+	assert.Error(t, err)
+	_, err = logging.New(&buf, "info", "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/metrics"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWalletCounter struct{}
+
+func (fakeWalletCounter) CountWallets(ctx context.Context) (map[models.WalletStatus]int64, error) {
+	return map[models.WalletStatus]int64{models.WalletActive: 7, models.WalletFrozen: 2, models.WalletClosed: 0}, nil
+}
+
+func scrape(t *testing.T, handler http.Handler) string {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	m := metrics.New(&fakeDatabase{}, fakeWalletCounter{})
+
+	// Проверки SendMoney до обращения к базе, поэтому репозитории не нужны
+	transactionService := service.NewTransactionService(nil, nil)
+	transactionService.SetMetrics(m)
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+
+	router := api.NewRouter(transactionService, nil, nil, healthService, nil, api.Options{Metrics: m})
+
+	send := func(body string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(body)))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"from":"a","to":"a","amount":1}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"from":"a","to":"b","amount":0}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"from":"a","to":"b","amount":-5}`))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	m.TransferSucceeded(models.MustParseAmount("10.25"), "USD")
+	m.TransferSucceeded(models.MustParseAmount("4.75"), "USD")
+	m.TransferSucceeded(models.MustParseAmount("1500"), "JPY")
+
+	body := scrape(t, router)
+
+	assert.Contains(t, body, `transaction_system_http_requests_total{method="POST",route="/api/send",status="422"} 3`)
+	assert.Contains(t, body, `transaction_system_http_requests_total{method="GET",route="/healthz",status="200"} 1`)
+	assert.Contains(t, body, `transaction_system_http_request_duration_seconds_count{method="POST",route="/api/send",status="422"} 3`)
+	assert.Contains(t, body, `transaction_system_transfers_failed_total{reason="same_wallet"} 1`)
+	assert.Contains(t, body, `transaction_system_transfers_failed_total{reason="invalid_amount"} 2`)
+	assert.Contains(t, body, `transaction_system_transfers_succeeded_total 3`)
+	// Суммы в разных валютах не складываются
+	assert.Contains(t, body, `transaction_system_transferred_amount_total{currency="USD"} 15`)
+	assert.Contains(t, body, `transaction_system_transferred_amount_total{currency="JPY"} 1500`)
+	assert.Contains(t, body, `transaction_system_wallets{status="active"} 7`)
+	assert.Contains(t, body, `transaction_system_wallets{status="frozen"} 2`)
+	assert.Contains(t, body, `transaction_system_db_pool_acquired_conns 1`)
+	assert.Contains(t, body, `transaction_system_db_pool_idle_conns 3`)
+	assert.Contains(t, body, `transaction_system_db_pool_total_conns 4`)
+}
+
+func TestTransferFailureReason(t *testing.T) {
+	assert.Equal(t, "insufficient_funds", service.TransferFailureReason(service.ErrInsufficientFunds))
+	assert.Equal(t, "wallet_frozen", service.TransferFailureReason(&service.WalletStatusError{Address: "a", Status: models.WalletFrozen}))
+	assert.Equal(t, "wallet_not_found", service.TransferFailureReason(service.ErrWalletNotFound))
+	assert.Equal(t, "internal", service.TransferFailureReason(io.ErrUnexpectedEOF))
+}
@@ -0,0 +1,296 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository/sqlite"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := database.LoadMigrations(testSchema)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, "migration %d", m.Version)
+		assert.NotEmpty(t, m.Down, "migration %d", m.Version)
+		assert.Len(t, m.Checksum, 64)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+type MigrationTestSuite struct {
+	suite.Suite
+	container *postgres.PostgresContainer
+	dbPool    *pgxpool.Pool
+	migrator  *database.Migrator
+	ctx       context.Context
+}
+
+func (suite *MigrationTestSuite) SetupSuite() {
+	skipIfNoDocker(suite.T())
+	suite.ctx = context.Background()
+
+	container, err := postgres.RunContainer(
+		suite.ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.container = container
+
+	connStr, err := container.ConnectionString(suite.ctx, "sslmode=disable")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	pool, err := pgxpool.Connect(suite.ctx, connStr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.dbPool = pool
+
+	migrator, err := database.NewMigrator(pool, testSchema)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.migrator = migrator
+}
+
+func (suite *MigrationTestSuite) TearDownSuite() {
+	if suite.container != nil {
+		suite.container.Terminate(suite.ctx)
+	}
+	if suite.dbPool != nil {
+		suite.dbPool.Close()
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	suite.Run(t, new(MigrationTestSuite))
+}
+
+func (suite *MigrationTestSuite) appliedVersions() []int64 {
+	statuses, err := suite.migrator.Status(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	var versions []int64
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func (suite *MigrationTestSuite) TestUpDownRoundTrip() {
+	t := suite.T()
+	migrations, err := database.LoadMigrations(testSchema)
+	require.NoError(t, err)
+	latest := migrations[len(migrations)-1].Version
+
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+	assert.Len(t, suite.appliedVersions(), len(migrations))
+
+	// Повторный запуск ничего не меняет
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+
+	require.NoError(t, suite.migrator.Down(suite.ctx))
+	assert.NotContains(t, suite.appliedVersions(), latest)
+
+	require.NoError(t, suite.migrator.To(suite.ctx, 0))
+	assert.Empty(t, suite.appliedVersions())
+
+	var tables int
+	err = suite.dbPool.QueryRow(suite.ctx, `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = 'TransactionSystem' AND table_name <> 'schema_migrations'
+	`).Scan(&tables)
+	require.NoError(t, err)
+	assert.Zero(t, tables)
+
+	require.NoError(t, suite.migrator.To(suite.ctx, 2))
+	assert.Equal(t, []int64{1, 2}, suite.appliedVersions())
+
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+	assert.Len(t, suite.appliedVersions(), len(migrations))
+}
+
+func (suite *MigrationTestSuite) TestChecksumMismatch() {
+	t := suite.T()
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+
+	migrations, err := database.LoadMigrations(testSchema)
+	require.NoError(t, err)
+
+	_, err = suite.dbPool.Exec(suite.ctx,
+		inSchema(testSchema, `UPDATE {schema}.schema_migrations SET checksum = 'changed' WHERE version = 1`))
+	require.NoError(t, err)
+	defer suite.dbPool.Exec(suite.ctx,
+		inSchema(testSchema, `UPDATE {schema}.schema_migrations SET checksum = $1 WHERE version = 1`), migrations[0].Checksum)
+
+	err = suite.migrator.Up(suite.ctx)
+	assert.ErrorIs(t, err, database.ErrChecksumMismatch)
+
+	statuses, err := suite.migrator.Status(suite.ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Dirty)
+}
+
+func (suite *MigrationTestSuite) TestCurrentVersion() {
+	t := suite.T()
+	require.NoError(t, suite.migrator.To(suite.ctx, 2))
+
+	current, err := suite.migrator.CurrentVersion(suite.ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), current)
+
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+	current, err = suite.migrator.CurrentVersion(suite.ctx)
+	require.NoError(t, err)
+	assert.Equal(t, suite.migrator.LatestVersion(), current)
+}
+
+func TestSQLiteMigrations_MirrorPostgres(t *testing.T) {
+	pg, err := database.LoadMigrations(testSchema)
+	require.NoError(t, err)
+	lite, err := database.LoadSQLiteMigrations()
+	require.NoError(t, err)
+
+	// Номера и имена совпадают, чтобы обе базы сообщали одну версию схемы
+	require.Len(t, lite, len(pg))
+	for i := range pg {
+		assert.Equal(t, pg[i].Version, lite[i].Version)
+		assert.Equal(t, pg[i].Name, lite[i].Name)
+		assert.NotEmpty(t, lite[i].Down, "migration %d", lite[i].Version)
+	}
+}
+
+func TestSQLiteMigrations_UpDownRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	migrator, err := database.NewSQLiteMigrator(db)
+	require.NoError(t, err)
+
+	current, err := migrator.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.LatestVersion(), current)
+
+	require.NoError(t, migrator.Down(ctx))
+	current, err = migrator.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.LatestVersion()-1, current)
+
+	require.NoError(t, migrator.To(ctx, 0))
+
+	var tables int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'
+		AND name NOT IN ('schema_migrations', 'sqlite_sequence')`).Scan(&tables)
+	require.NoError(t, err)
+	assert.Zero(t, tables)
+
+	require.NoError(t, migrator.Up(ctx))
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "migration %d", s.Version)
+		assert.False(t, s.Dirty, "migration %d", s.Version)
+		if assert.NotNil(t, s.AppliedAt, "migration %d", s.Version) {
+			assert.WithinDuration(t, time.Now(), *s.AppliedAt, time.Minute)
+		}
+	}
+}
+
+// 0018 восстанавливает валюту проводок, записанных до её появления, в том числе у счёта @fx
+func TestSQLiteMigrations_LedgerCurrencyBackfill(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	store := sqlite.NewDB(db)
+	wallets, transactions := sqlite.NewWalletRepository(store), sqlite.NewTransactionRepository(store)
+
+	require.NoError(t, wallets.CreateWallet(ctx, "usd", "USD", models.AmountFromUnits(100), nil))
+	require.NoError(t, wallets.CreateWallet(ctx, "eur", "EUR", models.AmountFromUnits(0), nil))
+	conversion := models.Conversion{Rate: models.MustParseRate("0.5"), ToAmount: models.AmountFromUnits(5), ToCurrency: "EUR"}
+	_, err := transactions.ExecuteConversion(ctx, "usd", "eur", models.AmountFromUnits(10), conversion, nil)
+	require.NoError(t, err)
+
+	migrator, err := database.NewSQLiteMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Down(ctx))
+	require.NoError(t, migrator.Up(ctx))
+
+	rows, err := db.Query(`SELECT account, amount, currency FROM ledger_entries WHERE account = '@fx' ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var fx []string
+	for rows.Next() {
+		var account, currency string
+		var amount int64
+		require.NoError(t, rows.Scan(&account, &amount, &currency))
+		fx = append(fx, fmt.Sprintf("%d %s", amount, currency))
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"1000 USD", "-500 EUR"}, fx)
+
+	report, err := sqlite.NewLedgerRepository(store).Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Balanced(), "%+v", report)
+}
+
+func (suite *MigrationTestSuite) TestConstraintsPerSchema() {
+	t := suite.T()
+	require.NoError(t, suite.migrator.Up(suite.ctx))
+
+	// Вторая схема той же базы получает собственные ограничения, хотя их имена уже заняты первой
+	const otherSchema = "tenant_c"
+	require.NoError(t, database.RunMigrations(suite.ctx, suite.dbPool, otherSchema))
+	defer suite.dbPool.Exec(suite.ctx, `DROP SCHEMA tenant_c CASCADE`)
+
+	for _, schema := range []string{testSchema, otherSchema} {
+		var names []string
+		rows, err := suite.dbPool.Query(suite.ctx, `
+			SELECT conname FROM pg_constraint
+			WHERE conrelid = format('%I.wallets', $1::text)::regclass AND contype = 'c'
+			ORDER BY conname
+		`, schema)
+		require.NoError(t, err)
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+		assert.Subset(t, names, []string{"chk_balance_non_negative", "chk_wallet_held", "chk_wallet_status"}, schema)
+	}
+
+	// Ограничение действует во второй схеме: холд не может превысить баланс
+	_, err := suite.dbPool.Exec(suite.ctx, `INSERT INTO tenant_c.wallets (address, balance) VALUES ('w', 10)`)
+	require.NoError(t, err)
+	_, err = suite.dbPool.Exec(suite.ctx, `UPDATE tenant_c.wallets SET held = 20 WHERE address = 'w'`)
+	assert.Error(t, err)
+}
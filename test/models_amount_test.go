@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"100", "100.00", false},
+		{"100.5", "100.50", false},
+		{"0.01", "0.01", false},
+		{"-0.25", "-0.25", false},
+		{"007.10", "7.10", false},
+		{"1.001", "", true},
+		{"1.000", "", true},
+		{"1e2", "", true},
+		{"", "", true},
+		{"1.", "", true},
+		{".5", "", true},
+		{"12345678901234567", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			a, err := models.ParseAmount(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, models.ErrInvalidAmount)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, a.String())
+		})
+	}
+}
+
+func TestAmount_NoFloatDrift(t *testing.T) {
+	sum := models.MustParseAmount("0.1").Add(models.MustParseAmount("0.2"))
+	assert.Equal(t, models.MustParseAmount("0.3"), sum)
+
+	total := models.NewAmount(0)
+	for i := 0; i < 1000; i++ {
+		total = total.Add(models.MustParseAmount("0.01"))
+	}
+	assert.Equal(t, models.AmountFromUnits(10), total)
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var req struct {
+		Amount models.Amount `json:"amount"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 100.50}`), &req))
+	assert.Equal(t, models.NewAmount(10050), req.Amount)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": "0.30"}`), &req))
+	assert.Equal(t, models.NewAmount(30), req.Amount)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 0.001}`), &req))
+
+	out, err := json.Marshal(models.Wallet{Balance: models.NewAmount(7)})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"balance":0.07`)
+}
+
+func TestAmount_Scan(t *testing.T) {
+	var a models.Amount
+
+	require.NoError(t, a.Scan("250.75"))
+	assert.Equal(t, models.NewAmount(25075), a)
+
+	require.NoError(t, a.Scan([]byte("1.2000")))
+	assert.Equal(t, models.NewAmount(120), a)
+
+	assert.Error(t, a.Scan("1.2345"))
+	assert.Error(t, a.Scan(nil))
+}
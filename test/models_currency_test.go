@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"testing"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencies_Scale(t *testing.T) {
+	currencies, err := models.NewCurrencies(map[string]int{"PTS": 0, "GOLD1": 2})
+	require.NoError(t, err)
+
+	for code, want := range map[models.Currency]int{"USD": 2, "EUR": 2, "JPY": 0, "PTS": 0, "GOLD1": 2} {
+		scale, err := currencies.Scale(code)
+		require.NoError(t, err, code)
+		assert.Equal(t, want, scale, code)
+	}
+
+	_, err = currencies.Scale("XYZ")
+	assert.ErrorIs(t, err, models.ErrUnknownCurrency)
+	_, err = models.ISOCurrencies().Scale("PTS")
+	assert.ErrorIs(t, err, models.ErrUnknownCurrency)
+
+	// Валюты с тремя и более знаками не поддерживаются явно, а не считаются неизвестными
+	for _, code := range []models.Currency{"BHD", "KWD", "JOD", "OMR", "TND", "CLF"} {
+		_, err = currencies.Scale(code)
+		assert.ErrorIs(t, err, models.ErrUnsupportedCurrency, code)
+		assert.NotErrorIs(t, err, models.ErrUnknownCurrency, code)
+	}
+}
+
+func TestCurrencies_CheckAmount(t *testing.T) {
+	currencies, err := models.NewCurrencies(map[string]int{"PTS": 0})
+	require.NoError(t, err)
+
+	assert.NoError(t, currencies.CheckAmount("USD", models.MustParseAmount("10.25")))
+	assert.NoError(t, currencies.CheckAmount("JPY", models.MustParseAmount("100")))
+	assert.NoError(t, currencies.CheckAmount("PTS", models.MustParseAmount("0")))
+	assert.ErrorIs(t, currencies.CheckAmount("JPY", models.MustParseAmount("100.50")), models.ErrAmountScale)
+	assert.ErrorIs(t, currencies.CheckAmount("PTS", models.MustParseAmount("0.01")), models.ErrAmountScale)
+	assert.ErrorIs(t, currencies.CheckAmount("XYZ", models.MustParseAmount("1")), models.ErrUnknownCurrency)
+}
+
+func TestNewCurrencies_Invalid(t *testing.T) {
+	for name, custom := range map[string]map[string]int{
+		"lowercase":  {"pts": 0},
+		"too short":  {"PT": 0},
+		"too long":   {"POINTSXYZ": 0},
+		"iso clash":  {"USD": 0},
+		"iso 3 dp":   {"BHD": 2},
+		"scale high": {"PTS": 3},
+		"scale low":  {"PTS": -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := models.NewCurrencies(custom)
+			assert.Error(t, err)
+		})
+	}
+}
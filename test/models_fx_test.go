@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := models.ParseRate("0.92")
+	require.NoError(t, err)
+	assert.Equal(t, "0.92000000", rate.String())
+
+	rate, err = models.ParseRate("151.3")
+	require.NoError(t, err)
+	assert.Equal(t, "151.30000000", rate.String())
+
+	for _, s := range []string{"", "0", "0.000000000", "-1", "1.123456789", "abc", "1."} {
+		_, err := models.ParseRate(s)
+		assert.ErrorIs(t, err, models.ErrInvalidRate, s)
+	}
+}
+
+func TestRate_JSON(t *testing.T) {
+	var body struct {
+		Rate models.Rate `json:"rate"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"rate": 0.92}`), &body))
+	assert.Equal(t, models.MustParseRate("0.92"), body.Rate)
+	require.NoError(t, json.Unmarshal([]byte(`{"rate": "1.5"}`), &body))
+	assert.Equal(t, models.MustParseRate("1.5"), body.Rate)
+	assert.Error(t, json.Unmarshal([]byte(`{"rate": 0}`), &body))
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rate": 1.5}`, string(data))
+}
+
+func TestConvert(t *testing.T) {
+	// 10.01 * 0.9234 = 9.243234: зачисляется 9.24, остаток 0.003234
+	converted, residue, err := models.Convert(models.MustParseAmount("10.01"), models.MustParseRate("0.9234"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("9.24"), converted)
+	assert.Equal(t, "0.0032340000", residue.String())
+
+	// Валюта без дробной части округляется до целых
+	converted, residue, err = models.Convert(models.MustParseAmount("10.00"), models.MustParseRate("151.35"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("1513"), converted)
+	assert.Equal(t, "0.5000000000", residue.String())
+
+	converted, residue, err = models.Convert(models.MustParseAmount("2.00"), models.MustParseRate("1.5"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("3.00"), converted)
+	assert.True(t, residue.IsZero())
+
+	// Слишком маленькая сумма превращается в ноль и целиком уходит в остаток
+	converted, residue, err = models.Convert(models.MustParseAmount("0.01"), models.MustParseRate("0.0066"), 2)
+	require.NoError(t, err)
+	assert.True(t, converted.IsZero())
+	assert.Equal(t, "0.0000660000", residue.String())
+
+	_, _, err = models.Convert(models.MustParseAmount("9999999999999999.99"), models.MustParseRate("1000"), 2)
+	assert.ErrorIs(t, err, models.ErrInvalidAmount)
+}
@@ -0,0 +1,30 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"TransactionSystem/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionCursor_RoundTrip(t *testing.T) {
+	cursor := models.TransactionCursor{
+		CreatedAt: time.Date(2024, 2, 10, 15, 4, 5, 123456000, time.UTC),
+		Id:        42,
+	}
+
+	parsed, err := models.ParseTransactionCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(parsed.CreatedAt))
+	assert.Equal(t, cursor.Id, parsed.Id)
+}
+
+func TestTransactionCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "!!!", "MTIz", "YTpi"} {
+		_, err := models.ParseTransactionCursor(s)
+		assert.ErrorIs(t, err, models.ErrInvalidCursor, s)
+	}
+}
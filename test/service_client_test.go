@@ -0,0 +1,341 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/repository/sqlite"
+	"TransactionSystem/internal/service"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+type ClientServiceTestSuite struct {
+	suite.Suite
+	container    *postgres.PostgresContainer
+	dbPool       *pgxpool.Pool
+	clients      *service.ClientService
+	wallets      *service.WalletService
+	transactions *service.TransactionService
+	ctx          context.Context
+	// sqlite запускает набор на встроенной базе вместо PostgreSQL
+	sqlite   bool
+	sqliteDB *sql.DB
+	// memory запускает набор на хранилище в памяти процесса
+	memory      bool
+	memoryStore *memory.Store
+}
+
+func (suite *ClientServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	if suite.memory {
+		return
+	}
+	if suite.sqlite {
+		suite.sqliteDB = openSQLite(suite.T())
+		db := sqlite.NewDB(suite.sqliteDB)
+		walletRepo := sqlite.NewWalletRepository(db)
+		suite.clients = service.NewClientService(sqlite.NewClientRepository(db))
+		suite.wallets = service.NewWalletService(walletRepo)
+		suite.transactions = service.NewTransactionService(sqlite.NewTransactionRepository(db), walletRepo)
+		return
+	}
+
+	skipIfNoDocker(suite.T())
+
+	container, err := postgres.RunContainer(
+		suite.ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.container = container
+
+	connStr, err := container.ConnectionString(suite.ctx, "sslmode=disable")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	pool, err := pgxpool.Connect(suite.ctx, connStr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.dbPool = pool
+
+	err = database.RunMigrations(suite.ctx, pool, testSchema)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	db := repository.NewDB(pool, testSchema)
+	walletRepo := repository.NewWalletRepository(db)
+	suite.clients = service.NewClientService(repository.NewClientRepository(db))
+	suite.wallets = service.NewWalletService(walletRepo)
+	suite.transactions = service.NewTransactionService(repository.NewTransactionRepository(db), walletRepo)
+}
+
+func (suite *ClientServiceTestSuite) TearDownSuite() {
+	if suite.container != nil {
+		suite.container.Terminate(suite.ctx)
+	}
+	if suite.dbPool != nil {
+		suite.dbPool.Close()
+	}
+}
+
+func (suite *ClientServiceTestSuite) BeforeTest(_, _ string) {
+	if suite.memory {
+		suite.memoryStore = memory.NewStore()
+		suite.clients = service.NewClientService(suite.memoryStore)
+		suite.wallets = service.NewWalletService(suite.memoryStore)
+		suite.transactions = service.NewTransactionService(suite.memoryStore, suite.memoryStore)
+		return
+	}
+	if suite.sqliteDB != nil {
+		_, err := suite.sqliteDB.Exec(`
+			DELETE FROM ledger_entries;
+			DELETE FROM transactions;
+			DELETE FROM wallets;
+			DELETE FROM clients;
+		`)
+		assert.NoError(suite.T(), err)
+		return
+	}
+	_, err := suite.dbPool.Exec(suite.ctx, inSchema(testSchema, `
+		TRUNCATE TABLE {schema}.wallets CASCADE;
+		TRUNCATE TABLE {schema}.transactions CASCADE;
+		TRUNCATE TABLE {schema}.ledger_entries;
+		TRUNCATE TABLE {schema}.clients CASCADE;
+	`))
+	assert.NoError(suite.T(), err)
+}
+
+func TestClientService(t *testing.T) {
+	suite.Run(t, new(ClientServiceTestSuite))
+}
+
+func TestClientService_SQLite(t *testing.T) {
+	suite.Run(t, &ClientServiceTestSuite{sqlite: true})
+}
+
+func TestClientService_Memory(t *testing.T) {
+	suite.Run(t, &ClientServiceTestSuite{memory: true})
+}
+
+// login создаёт клиента и возвращает контекст, аутентифицированный его ключом
+func (suite *ClientServiceTestSuite) login(name string, admin bool) context.Context {
+	_, key, err := suite.clients.CreateClient(suite.ctx, name, admin)
+	require.NoError(suite.T(), err)
+
+	principal, err := suite.clients.Authenticate(suite.ctx, key.Key)
+	require.NoError(suite.T(), err)
+	return auth.WithPrincipal(suite.ctx, principal)
+}
+
+// storedKeyHash читает из хранилища то, что сохранено для ключа
+func (suite *ClientServiceTestSuite) storedKeyHash(key models.APIKey) string {
+	id := key.Id
+	var stored string
+	var err error
+	if suite.memoryStore != nil {
+		var k *models.APIKey
+		k, _, err = suite.memoryStore.GetAPIKeyByPrefix(suite.ctx, key.Prefix)
+		require.NoError(suite.T(), err)
+		return k.Hash
+	}
+	if suite.sqliteDB != nil {
+		err = suite.sqliteDB.QueryRow(`SELECT key_hash FROM api_keys WHERE id = ?`, id).Scan(&stored)
+	} else {
+		err = suite.dbPool.QueryRow(suite.ctx, inSchema(testSchema, `SELECT key_hash FROM {schema}.api_keys WHERE id = $1`), id).Scan(&stored)
+	}
+	require.NoError(suite.T(), err)
+	return stored
+}
+
+func (suite *ClientServiceTestSuite) TestAuthenticate() {
+	t := suite.T()
+
+	client, key, err := suite.clients.CreateClient(suite.ctx, "shop", false)
+	require.NoError(t, err)
+
+	principal, err := suite.clients.Authenticate(suite.ctx, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, client.Id, principal.ClientId)
+	assert.False(t, principal.Admin)
+
+	// В базе хранится только хэш ключа
+	stored := suite.storedKeyHash(key.APIKey)
+	assert.NotContains(t, stored, key.Key)
+	assert.Equal(t, auth.HashKey(key.Key), stored)
+
+	// Ключ с верным префиксом, но другим секретом
+	_, err = suite.clients.Authenticate(suite.ctx, key.Key+"x")
+	assert.ErrorIs(t, err, service.ErrUnauthenticated)
+	_, err = suite.clients.Authenticate(suite.ctx, "not-a-key")
+	assert.ErrorIs(t, err, service.ErrUnauthenticated)
+
+	require.NoError(t, suite.clients.RevokeKey(suite.ctx, key.Id))
+	_, err = suite.clients.Authenticate(suite.ctx, key.Key)
+	assert.ErrorIs(t, err, service.ErrUnauthenticated)
+
+	// Новый ключ работает, старый остаётся отозванным
+	issued, err := suite.clients.IssueKey(suite.ctx, client.Id)
+	require.NoError(t, err)
+	_, err = suite.clients.Authenticate(suite.ctx, issued.Key)
+	assert.NoError(t, err)
+
+	_, err = suite.clients.IssueKey(suite.ctx, client.Id+1000)
+	assert.ErrorIs(t, err, service.ErrClientNotFound)
+	assert.ErrorIs(t, suite.clients.RevokeKey(suite.ctx, issued.Id+1000), service.ErrAPIKeyNotFound)
+}
+
+func (suite *ClientServiceTestSuite) TestCreateClient_Validation() {
+	_, _, err := suite.clients.CreateClient(suite.ctx, "   ", false)
+	assert.ErrorIs(suite.T(), err, service.ErrInvalidClient)
+}
+
+func (suite *ClientServiceTestSuite) TestOnlyAdminManagesClients() {
+	t := suite.T()
+	alice := suite.login("alice", false)
+
+	_, _, err := suite.clients.CreateClient(alice, "mallory", true)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	admin := suite.login("ops", true)
+	_, _, err = suite.clients.CreateClient(admin, "bob", false)
+	assert.NoError(t, err)
+}
+
+func (suite *ClientServiceTestSuite) TestWalletOwnership() {
+	t := suite.T()
+	alice := suite.login("alice", false)
+	bob := suite.login("bob", false)
+	admin := suite.login("ops", true)
+
+	// Выпуск средств при создании кошелька доступен только администратору
+	_, err := suite.wallets.CreateWallet(alice, models.DefaultCurrency, models.AmountFromUnits(100))
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	aliceWallet, err := suite.wallets.CreateWallet(alice, models.DefaultCurrency, models.AmountFromUnits(0))
+	require.NoError(t, err)
+	bobWallet, err := suite.wallets.CreateWallet(bob, models.DefaultCurrency, models.AmountFromUnits(0))
+	require.NoError(t, err)
+	require.NoError(t, suite.wallets.UpdateBalance(admin, aliceWallet, models.AmountFromUnits(50)))
+
+	wallet, err := suite.wallets.GetWallet(alice, aliceWallet)
+	require.NoError(t, err)
+	require.NotNil(t, wallet.Owner)
+
+	// Чужой кошелёк нельзя ни посмотреть, ни списать с него, ни удалить
+	_, err = suite.wallets.GetWallet(bob, aliceWallet)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = suite.wallets.GetBalance(bob, aliceWallet)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = suite.transactions.SendMoney(bob, aliceWallet, bobWallet, models.AmountFromUnits(10))
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	assert.ErrorIs(t, suite.wallets.RemoveWallet(bob, aliceWallet), service.ErrForbidden)
+	_, err = suite.wallets.FreezeWallet(bob, aliceWallet)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = suite.transactions.GetWalletHistory(bob, aliceWallet, models.WalletHistoryFilter{}, "")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// Владелец переводит на чужой кошелёк, обе стороны видят транзакцию
+	transaction, err := suite.transactions.SendMoney(alice, aliceWallet, bobWallet, models.AmountFromUnits(10))
+	require.NoError(t, err)
+	_, err = suite.transactions.GetTransactionById(bob, transaction.Id)
+	assert.NoError(t, err)
+
+	carol := suite.login("carol", false)
+	_, err = suite.transactions.GetTransactionById(carol, transaction.Id)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	page, err := suite.transactions.ListTransactions(carol, models.TransactionFilter{}, "")
+	require.NoError(t, err)
+	assert.Empty(t, page.Transactions)
+	page, err = suite.transactions.ListTransactions(bob, models.TransactionFilter{}, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Transactions, 1)
+
+	// Администратор видит и изменяет любые кошельки
+	_, err = suite.wallets.GetWallet(admin, aliceWallet)
+	assert.NoError(t, err)
+	_, err = suite.transactions.SendMoney(admin, bobWallet, aliceWallet, models.AmountFromUnits(10))
+	assert.NoError(t, err)
+}
+
+func (suite *ClientServiceTestSuite) TestJWTScopes() {
+	t := suite.T()
+	captureLogs(t)
+
+	rsaKey, _, _ := newSigningKeys(t)
+	verifier, err := auth.NewJWTVerifier(jwtConfig(writeJWKS(t, rsaKey)))
+	require.NoError(t, err)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(suite.transactions, suite.wallets, nil, healthService, suite.clients,
+		api.Options{AuthMode: api.AuthModeJWT, JWTVerifier: verifier, AdminMode: true})
+
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	reader := signToken(t, rsaKey, "shop", "wallet:read", nil)
+	writer := signToken(t, rsaKey, "shop", "wallet:read wallet:write", nil)
+	admin := signToken(t, rsaKey, "ops", "admin", nil)
+
+	// Без wallet:write кошелёк не создать; заголовок подсказывает нужный scope
+	rec := call(http.MethodPost, "/api/wallet/create", reader, `{"balance": 0}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope", scope="wallet:write"`)
+
+	rec = call(http.MethodPost, "/api/wallet/create", writer, `{"balance": 0}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	address := created["address"]
+
+	// Кошелёк принадлежит субъекту токена, а не конкретному токену
+	rec = call(http.MethodGet, "/api/wallet/"+address, reader, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	other := signToken(t, rsaKey, "someone-else", "wallet:read", nil)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/wallet/"+address, other, "").Code)
+
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/send", writer,
+		`{"from":"`+address+`","to":"`+address+`","amount":1}`).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, "/api/wallet/"+address, writer, "").Code)
+
+	// admin включает все остальные scope
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/wallet/"+address, admin, "").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodDelete, "/api/wallet/"+address, admin, "").Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/admin/status", writer, "").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/admin/status", admin, "").Code)
+}
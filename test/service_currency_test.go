@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCurrencyServices возвращает сервисы поверх хранилища в памяти с собственной валютой PTS
+func newCurrencyServices(t *testing.T) (*service.WalletService, *service.TransactionService) {
+	currencies, err := models.NewCurrencies(map[string]int{"PTS": 0})
+	require.NoError(t, err)
+
+	store := memory.NewStore()
+	wallets := service.NewWalletService(store)
+	wallets.SetCurrencies(currencies)
+	transactions := service.NewTransactionService(store, store)
+	transactions.SetCurrencies(currencies)
+	return wallets, transactions
+}
+
+func TestWalletService_CreateWalletCurrency(t *testing.T) {
+	ctx := context.Background()
+	wallets, _ := newCurrencyServices(t)
+
+	address, err := wallets.CreateWallet(ctx, "", models.AmountFromUnits(1))
+	require.NoError(t, err)
+	wallet, err := wallets.GetWallet(ctx, address)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultCurrency, wallet.Currency)
+
+	address, err = wallets.CreateWallet(ctx, "PTS", models.AmountFromUnits(500))
+	require.NoError(t, err)
+	wallet, err = wallets.GetWallet(ctx, address)
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("PTS"), wallet.Currency)
+
+	_, err = wallets.CreateWallet(ctx, "XYZ", models.AmountFromUnits(0))
+	assert.ErrorIs(t, err, service.ErrUnknownCurrency)
+	_, err = wallets.CreateWallet(ctx, "KWD", models.AmountFromUnits(0))
+	assert.ErrorIs(t, err, service.ErrUnsupportedCurrency)
+	_, err = wallets.CreateWallet(ctx, "PTS", models.MustParseAmount("1.50"))
+	assert.ErrorIs(t, err, service.ErrAmountScale)
+
+	assert.ErrorIs(t, wallets.UpdateBalance(ctx, address, models.MustParseAmount("0.10")), service.ErrAmountScale)
+	assert.NoError(t, wallets.UpdateBalance(ctx, address, models.AmountFromUnits(10)))
+}
+
+func TestTransactionService_SendMoneyCurrency(t *testing.T) {
+	ctx := context.Background()
+	wallets, transactions := newCurrencyServices(t)
+
+	usd, err := wallets.CreateWallet(ctx, "USD", models.AmountFromUnits(100))
+	require.NoError(t, err)
+	eur, err := wallets.CreateWallet(ctx, "EUR", models.AmountFromUnits(0))
+	require.NoError(t, err)
+	points, err := wallets.CreateWallet(ctx, "PTS", models.AmountFromUnits(100))
+	require.NoError(t, err)
+	morePoints, err := wallets.CreateWallet(ctx, "PTS", models.AmountFromUnits(0))
+	require.NoError(t, err)
+
+	_, err = transactions.SendMoney(ctx, usd, eur, models.AmountFromUnits(10))
+	assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+	var mismatch *service.CurrencyMismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, models.Currency("USD"), mismatch.From)
+		assert.Equal(t, models.Currency("EUR"), mismatch.To)
+	}
+	assert.Equal(t, "currency_mismatch", service.TransferFailureReason(err))
+
+	_, err = transactions.SendMoney(ctx, points, morePoints, models.MustParseAmount("0.50"))
+	assert.ErrorIs(t, err, service.ErrAmountScale)
+
+	transaction, err := transactions.SendMoney(ctx, points, morePoints, models.AmountFromUnits(40))
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("PTS"), transaction.Currency)
+
+	balance, err := wallets.GetBalance(ctx, usd)
+	require.NoError(t, err)
+	assert.Equal(t, models.AmountFromUnits(100), balance)
+}
@@ -0,0 +1,227 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/fx"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fxServices struct {
+	wallets      *service.WalletService
+	transactions *service.TransactionService
+	fx           *service.FXService
+	ledger       *service.LedgerService
+}
+
+// newFXServices возвращает сервисы поверх хранилища в памяти с курсами, которые задаёт администратор
+func newFXServices(t *testing.T, quoteTTL time.Duration) fxServices {
+	currencies := models.ISOCurrencies()
+	rates := fx.NewManualRates(map[fx.Pair]models.Rate{
+		{From: "USD", To: "EUR"}: models.MustParseRate("0.9234"),
+		{From: "USD", To: "JPY"}: models.MustParseRate("151.35"),
+	})
+
+	store := memory.NewStore()
+	s := fxServices{
+		wallets:      service.NewWalletService(store),
+		transactions: service.NewTransactionService(store, store),
+		fx:           service.NewFXService(rates, currencies, quoteTTL),
+		ledger:       service.NewLedgerService(store),
+	}
+	s.wallets.SetCurrencies(currencies)
+	s.transactions.SetCurrencies(currencies)
+	s.transactions.SetFX(s.fx)
+	return s
+}
+
+func (s fxServices) createWallet(t *testing.T, currency models.Currency, balance string) string {
+	address, err := s.wallets.CreateWallet(context.Background(), currency, models.MustParseAmount(balance))
+	require.NoError(t, err)
+	return address
+}
+
+func (s fxServices) balance(t *testing.T, address string) models.Amount {
+	balance, err := s.wallets.GetBalance(context.Background(), address)
+	require.NoError(t, err)
+	return balance
+}
+
+func TestTransactionService_ConvertMoney(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+	usd, eur, jpy := s.createWallet(t, "USD", "100"), s.createWallet(t, "EUR", "0"), s.createWallet(t, "JPY", "0")
+
+	transaction, err := s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("10.01"), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("USD"), transaction.Currency)
+	require.NotNil(t, transaction.Conversion)
+	assert.Equal(t, models.MustParseRate("0.9234"), transaction.Conversion.Rate)
+	assert.Equal(t, models.MustParseAmount("9.24"), transaction.Conversion.ToAmount)
+	assert.Equal(t, models.Currency("EUR"), transaction.Conversion.ToCurrency)
+	assert.Equal(t, "0.0032340000", transaction.Conversion.Residue.String())
+	assert.Empty(t, transaction.Conversion.QuoteId)
+
+	assert.Equal(t, models.MustParseAmount("89.99"), s.balance(t, usd))
+	assert.Equal(t, models.MustParseAmount("9.24"), s.balance(t, eur))
+
+	_, err = s.transactions.ConvertMoney(ctx, usd, jpy, models.MustParseAmount("10"), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("1513"), s.balance(t, jpy))
+
+	// Остатки округления остаются на счёте конвертации, журнал сходится
+	report, err := s.ledger.CheckInvariants(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Balanced(), "%+v", report)
+
+	// Перевод с конвертацией не отменяется
+	_, err = s.transactions.ReverseTransaction(ctx, transaction.Id, "mistake")
+	assert.ErrorIs(t, err, service.ErrConversionNotReversible)
+}
+
+func TestTransactionService_ConvertMoneyErrors(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+	usd, otherUSD, eur := s.createWallet(t, "USD", "100"), s.createWallet(t, "USD", "0"), s.createWallet(t, "EUR", "100")
+
+	_, err := s.transactions.ConvertMoney(ctx, usd, otherUSD, models.MustParseAmount("1"), "")
+	assert.ErrorIs(t, err, service.ErrSameCurrency)
+
+	_, err = s.transactions.ConvertMoney(ctx, eur, usd, models.MustParseAmount("1"), "")
+	assert.ErrorIs(t, err, service.ErrFXRateNotFound)
+	assert.Equal(t, "fx_rate_not_found", service.TransferFailureReason(err))
+
+	_, err = s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("1000"), "")
+	assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+
+	// Сумма, которая после округления превращается в ноль, не переводится
+	require.NoError(t, s.fx.SetRate(ctx, "USD", "EUR", models.MustParseRate("0.0066")))
+	_, err = s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("0.01"), "")
+	assert.ErrorIs(t, err, service.ErrInvalidAmount)
+
+	// Без сервиса курсов конвертация недоступна
+	plain := service.NewTransactionService(memory.NewStore(), memory.NewStore())
+	_, err = plain.ConvertMoney(ctx, usd, eur, models.MustParseAmount("1"), "")
+	assert.ErrorIs(t, err, service.ErrFXRateNotFound)
+
+	assert.Equal(t, models.MustParseAmount("100"), s.balance(t, usd))
+}
+
+func TestTransactionService_ConvertMoneyWithQuote(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+	usd, eur, jpy := s.createWallet(t, "USD", "100"), s.createWallet(t, "EUR", "0"), s.createWallet(t, "JPY", "0")
+
+	quote, err := s.fx.CreateQuote(ctx, "USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseRate("0.9234"), quote.Rate)
+	assert.True(t, quote.ExpiresAt.After(time.Now()))
+
+	// Котировка сохраняет курс, действовавший при её создании
+	require.NoError(t, s.fx.SetRate(ctx, "USD", "EUR", models.MustParseRate("0.5")))
+
+	transaction, err := s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("10"), quote.Id)
+	require.NoError(t, err)
+	assert.Equal(t, quote.Id, transaction.Conversion.QuoteId)
+	assert.Equal(t, models.MustParseAmount("9.23"), transaction.Conversion.ToAmount)
+
+	// Курс без котировки — новый
+	transaction, err = s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("10"), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("5"), transaction.Conversion.ToAmount)
+
+	// Котировка пары USD/EUR не подходит для кошелька в JPY
+	_, err = s.transactions.ConvertMoney(ctx, usd, jpy, models.MustParseAmount("10"), quote.Id)
+	assert.ErrorIs(t, err, service.ErrFXQuoteMismatch)
+
+	_, err = s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("10"), "missing")
+	assert.ErrorIs(t, err, service.ErrFXQuoteNotFound)
+	assert.Equal(t, "fx_quote_invalid", service.TransferFailureReason(err))
+}
+
+func TestFXService_QuoteExpires(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, 10*time.Millisecond)
+	usd, eur := s.createWallet(t, "USD", "100"), s.createWallet(t, "EUR", "0")
+
+	quote, err := s.fx.CreateQuote(ctx, "USD", "EUR")
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	_, err = s.transactions.ConvertMoney(ctx, usd, eur, models.MustParseAmount("10"), quote.Id)
+	assert.ErrorIs(t, err, service.ErrFXQuoteExpired)
+	assert.Equal(t, models.MustParseAmount("100"), s.balance(t, usd))
+}
+
+func TestFXService_SetRate(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+
+	assert.ErrorIs(t, s.fx.SetRate(ctx, "USD", "USD", models.MustParseRate("1")), service.ErrSameCurrency)
+	assert.ErrorIs(t, s.fx.SetRate(ctx, "USD", "XYZ", models.MustParseRate("1")), service.ErrUnknownCurrency)
+	assert.ErrorIs(t, s.fx.SetRate(ctx, "USD", "EUR", models.Rate{}), service.ErrInvalidRate)
+
+	client := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 1})
+	assert.ErrorIs(t, s.fx.SetRate(client, "USD", "EUR", models.MustParseRate("1")), service.ErrForbidden)
+
+	require.NoError(t, s.fx.SetRate(ctx, "EUR", "USD", models.MustParseRate("1.09")))
+	rate, err := s.fx.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseRate("1.09"), rate)
+
+	// Таблица из файла не меняется
+	static := service.NewFXService(fx.NewStaticRates(nil), models.ISOCurrencies(), time.Minute)
+	assert.ErrorIs(t, static.SetRate(ctx, "USD", "EUR", models.MustParseRate("1")), service.ErrFXRatesReadOnly)
+}
+
+func TestFXService_QuoteBelongsToClient(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+	owner := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 1})
+	other := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 2})
+	admin := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 3, Admin: true})
+
+	quote, err := s.fx.CreateQuote(owner, "USD", "EUR")
+	require.NoError(t, err)
+	require.NotNil(t, quote.Owner)
+	assert.Equal(t, int64(1), *quote.Owner)
+
+	_, err = s.fx.Quote(owner, quote.Id)
+	assert.NoError(t, err)
+	_, err = s.fx.Quote(admin, quote.Id)
+	assert.NoError(t, err)
+
+	// Котировку другого клиента нельзя использовать для перевода
+	_, err = s.fx.Quote(other, quote.Id)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	usd, err := s.wallets.CreateWallet(other, "USD", models.Amount{})
+	require.NoError(t, err)
+	eur := s.createWallet(t, "EUR", "0")
+	_, err = s.transactions.ConvertMoney(other, usd, eur, models.MustParseAmount("10"), quote.Id)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+}
+
+func TestTransactionService_ConvertMoneyAuthorizesSenderFirst(t *testing.T) {
+	ctx := context.Background()
+	s := newFXServices(t, time.Minute)
+	usd, eur := s.createWallet(t, "USD", "100"), s.createWallet(t, "EUR", "0")
+	client := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 1})
+
+	// Чужой кошелёк-отправитель отклоняется раньше, чем проверяются получатель и котировка
+	_, err := s.transactions.ConvertMoney(client, usd, eur, models.MustParseAmount("10"), "")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.transactions.ConvertMoney(client, usd, "missing", models.MustParseAmount("10"), "")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.transactions.ConvertMoney(client, usd, eur, models.MustParseAmount("10"), "missing")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	assert.Equal(t, models.MustParseAmount("100"), s.balance(t, usd))
+}
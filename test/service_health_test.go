@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDatabase struct {
+	pingErr error
+}
+
+func (db *fakeDatabase) Ping(ctx context.Context) error {
+	return db.pingErr
+}
+
+func (db *fakeDatabase) Stats() models.PoolStats {
+	return models.PoolStats{TotalConns: 4, AcquiredConns: 1, IdleConns: 3, MaxConns: 10}
+}
+
+type fakeMigrations struct {
+	current, latest int64
+	err             error
+}
+
+func (m *fakeMigrations) CurrentVersion(ctx context.Context) (int64, error) {
+	return m.current, m.err
+}
+
+func (m *fakeMigrations) LatestVersion() int64 {
+	return m.latest
+}
+
+func TestHealthService_Ready(t *testing.T) {
+	ctx := context.Background()
+	hs := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 11, latest: 11}, "1.2.3")
+
+	readiness := hs.Ready(ctx)
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, models.CheckOK, readiness.Checks[models.CheckDatabase])
+	assert.Equal(t, models.CheckOK, readiness.Checks[models.CheckMigrations])
+	assert.Equal(t, models.CheckOK, readiness.Checks[models.CheckShutdown])
+
+	hs.BeginShutdown()
+	readiness = hs.Ready(ctx)
+	assert.False(t, readiness.Ready)
+	assert.NotEqual(t, models.CheckOK, readiness.Checks[models.CheckShutdown])
+}
+
+func TestHealthService_NotReady(t *testing.T) {
+	ctx := context.Background()
+
+	readiness := service.NewHealthService(&fakeDatabase{pingErr: errors.New("connection refused")},
+		&fakeMigrations{current: 11, latest: 11}, "dev").Ready(ctx)
+	assert.False(t, readiness.Ready)
+	assert.Contains(t, readiness.Checks[models.CheckDatabase], "connection refused")
+
+	readiness = service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 9, latest: 11}, "dev").Ready(ctx)
+	assert.False(t, readiness.Ready)
+	assert.Contains(t, readiness.Checks[models.CheckMigrations], "expected 11")
+	assert.Equal(t, models.CheckOK, readiness.Checks[models.CheckDatabase])
+}
+
+func TestHealthService_Status(t *testing.T) {
+	hs := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{err: errors.New("no schema_migrations"), latest: 11}, "1.2.3")
+
+	status := hs.Status(context.Background())
+	assert.Equal(t, "1.2.3", status.Version)
+	assert.GreaterOrEqual(t, status.UptimeSeconds, 0.0)
+	assert.Equal(t, int64(11), status.Migrations.Expected)
+	assert.Equal(t, "no schema_migrations", status.Migrations.Error)
+	assert.Equal(t, int32(10), status.Pool.MaxConns)
+	assert.False(t, status.Readiness.Ready)
+	assert.False(t, status.ShuttingDown)
+}
+
+func TestAdminStatus_WithoutAdminMode(t *testing.T) {
+	captureLogs(t)
+
+	hs := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(nil, nil, nil, hs, nil, api.Options{})
+
+	// Статус доступен и без административного режима, удаление — нет
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/status", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/transaction/1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,264 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"TransactionSystem/internal/auth"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type holdServices struct {
+	store        *memory.Store
+	wallets      *service.WalletService
+	transactions *service.TransactionService
+	holds        *service.HoldService
+	ledger       *service.LedgerService
+}
+
+// newHoldServices возвращает сервисы поверх хранилища в памяти; холды живут от 1 до 60 минут
+func newHoldServices() holdServices {
+	store := memory.NewStore()
+	return holdServices{
+		store:        store,
+		wallets:      service.NewWalletService(store),
+		transactions: service.NewTransactionService(store, store),
+		holds:        service.NewHoldService(store, store, 10*time.Minute, time.Hour),
+		ledger:       service.NewLedgerService(store),
+	}
+}
+
+func (s holdServices) createWallet(t *testing.T, currency models.Currency, balance int64) string {
+	address, err := s.wallets.CreateWallet(context.Background(), currency, models.AmountFromUnits(balance))
+	require.NoError(t, err)
+	return address
+}
+
+func (s holdServices) wallet(t *testing.T, address string) *models.Wallet {
+	wallet, err := s.wallets.GetWallet(context.Background(), address)
+	require.NoError(t, err)
+	return wallet
+}
+
+func TestHoldService_Capture(t *testing.T) {
+	ctx := context.Background()
+	s := newHoldServices()
+	from, to := s.createWallet(t, "USD", 100), s.createWallet(t, "USD", 0)
+
+	start := time.Now()
+	hold, err := s.holds.CreateHold(ctx, from, to, models.AmountFromUnits(30), 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldActive, hold.Status)
+	assert.WithinDuration(t, start.Add(10*time.Minute), hold.ExpiresAt, time.Second)
+
+	// Кошелёк отдаёт и полный баланс, и доступный остаток
+	body, err := json.Marshal(s.wallet(t, from))
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	balance, _ := json.Marshal(models.AmountFromUnits(100))
+	available, _ := json.Marshal(models.AmountFromUnits(70))
+	assert.JSONEq(t, string(balance), string(fields["balance"]))
+	assert.JSONEq(t, string(available), string(fields["available_balance"]))
+
+	// Захолдированные средства нельзя перевести или захолдировать повторно
+	_, err = s.transactions.SendMoney(ctx, from, to, models.AmountFromUnits(71))
+	assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+	_, err = s.holds.CreateHold(ctx, from, to, models.AmountFromUnits(71), 0)
+	assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+
+	_, _, err = s.holds.CaptureHold(ctx, hold.Id, models.AmountFromUnits(31))
+	assert.ErrorIs(t, err, service.ErrCaptureExceedsHold)
+
+	captured, transaction, err := s.holds.CaptureHold(ctx, hold.Id, models.AmountFromUnits(20))
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldCaptured, captured.Status)
+	assert.Equal(t, models.AmountFromUnits(20), transaction.Amount)
+	assert.Equal(t, from, transaction.From)
+	assert.Equal(t, to, transaction.To)
+
+	wallet := s.wallet(t, from)
+	assert.Equal(t, models.AmountFromUnits(80), wallet.Balance)
+	assert.Equal(t, models.AmountFromUnits(80), wallet.Available())
+	assert.Equal(t, models.AmountFromUnits(20), s.wallet(t, to).Balance)
+
+	_, _, err = s.holds.CaptureHold(ctx, hold.Id, models.Amount{})
+	var se *service.HoldStatusError
+	require.True(t, errors.As(err, &se), "expected HoldStatusError, got %v", err)
+	assert.Equal(t, models.HoldCaptured, se.Status)
+	assert.ErrorIs(t, err, service.ErrHoldNotActive)
+
+	// Без суммы списывается весь холд
+	hold, err = s.holds.CreateHold(ctx, from, to, models.AmountFromUnits(15), time.Minute)
+	require.NoError(t, err)
+	_, transaction, err = s.holds.CaptureHold(ctx, hold.Id, models.Amount{})
+	require.NoError(t, err)
+	assert.Equal(t, models.AmountFromUnits(15), transaction.Amount)
+	assert.Equal(t, models.AmountFromUnits(65), s.wallet(t, from).Balance)
+
+	report, err := s.ledger.CheckInvariants(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Balanced())
+}
+
+func TestHoldService_Void(t *testing.T) {
+	ctx := context.Background()
+	s := newHoldServices()
+	from, to := s.createWallet(t, "USD", 100), s.createWallet(t, "USD", 0)
+
+	hold, err := s.holds.CreateHold(ctx, from, to, models.AmountFromUnits(40), 0)
+	require.NoError(t, err)
+
+	// Баланс нельзя опустить ниже захолдированной суммы
+	err = s.wallets.UpdateBalance(ctx, from, models.AmountFromUnits(39))
+	assert.ErrorIs(t, err, service.ErrNegativeBalance)
+
+	voided, err := s.holds.VoidHold(ctx, hold.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldVoided, voided.Status)
+	assert.Nil(t, voided.TransactionId)
+
+	wallet := s.wallet(t, from)
+	assert.Equal(t, models.AmountFromUnits(100), wallet.Balance)
+	assert.Equal(t, models.AmountFromUnits(100), wallet.Available())
+	assert.True(t, s.wallet(t, to).Balance.IsZero())
+
+	_, err = s.holds.VoidHold(ctx, hold.Id)
+	assert.ErrorIs(t, err, service.ErrHoldNotActive)
+	_, _, err = s.holds.CaptureHold(ctx, hold.Id, models.Amount{})
+	assert.ErrorIs(t, err, service.ErrHoldNotActive)
+
+	_, err = s.holds.VoidHold(ctx, hold.Id+100)
+	assert.ErrorIs(t, err, service.ErrHoldNotFound)
+}
+
+func TestHoldService_CreateValidation(t *testing.T) {
+	ctx := context.Background()
+	s := newHoldServices()
+	from, to, eur := s.createWallet(t, "USD", 100), s.createWallet(t, "USD", 0), s.createWallet(t, "EUR", 0)
+	amount := models.AmountFromUnits(10)
+
+	_, err := s.holds.CreateHold(ctx, from, from, amount, 0)
+	assert.ErrorIs(t, err, service.ErrSameWallet)
+	_, err = s.holds.CreateHold(ctx, from, to, models.Amount{}, 0)
+	assert.ErrorIs(t, err, service.ErrInvalidAmount)
+	_, err = s.holds.CreateHold(ctx, from, to, amount, 2*time.Hour)
+	assert.ErrorIs(t, err, service.ErrInvalidHoldTTL)
+	_, err = s.holds.CreateHold(ctx, from, to, amount, -time.Second)
+	assert.ErrorIs(t, err, service.ErrInvalidHoldTTL)
+	_, err = s.holds.CreateHold(ctx, from, eur, amount, 0)
+	assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+	_, err = s.holds.CreateHold(ctx, from, uuid.New().String(), amount, 0)
+	assert.ErrorIs(t, err, service.ErrWalletNotFound)
+
+	_, err = s.wallets.FreezeWallet(ctx, to)
+	require.NoError(t, err)
+	_, err = s.holds.CreateHold(ctx, from, to, amount, 0)
+	assert.ErrorIs(t, err, service.ErrWalletFrozen)
+
+	assert.True(t, s.wallet(t, from).Held.IsZero())
+}
+
+func TestHoldService_TTLFromSeconds(t *testing.T) {
+	s := newHoldServices()
+
+	ttl, err := s.holds.TTLFromSeconds(0)
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+	ttl, err = s.holds.TTLFromSeconds(3600)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	// math.MaxInt64 секунд после умножения переполнил бы time.Duration
+	for _, seconds := range []int64{-1, 3601, math.MaxInt64, math.MaxInt64/int64(time.Second) + 1} {
+		_, err := s.holds.TTLFromSeconds(seconds)
+		assert.ErrorIs(t, err, service.ErrInvalidHoldTTL, seconds)
+	}
+}
+
+func TestHoldService_Authorization(t *testing.T) {
+	ctx := context.Background()
+	s := newHoldServices()
+	owner := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 1})
+	other := auth.WithPrincipal(ctx, &auth.Principal{ClientId: 2})
+
+	from, err := s.wallets.CreateWallet(owner, "USD", models.Amount{})
+	require.NoError(t, err)
+	require.NoError(t, s.wallets.UpdateBalance(ctx, from, models.AmountFromUnits(50)))
+	to := s.createWallet(t, "USD", 0)
+
+	_, err = s.holds.CreateHold(other, from, to, models.AmountFromUnits(10), 0)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	hold, err := s.holds.CreateHold(owner, from, to, models.AmountFromUnits(10), 0)
+	require.NoError(t, err)
+
+	_, err = s.holds.GetHold(other, hold.Id)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, _, err = s.holds.CaptureHold(other, hold.Id, models.Amount{})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.holds.VoidHold(other, hold.Id)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	got, err := s.holds.GetHold(owner, hold.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldActive, got.Status)
+}
+
+func TestHoldService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	s := newHoldServices()
+	from, to := s.createWallet(t, "USD", 100), s.createWallet(t, "USD", 0)
+
+	// Срок холда в прошлом задаётся через хранилище: сервис не принимает такой ttl
+	expired, err := s.store.CreateHold(ctx, from, to, models.AmountFromUnits(30), time.Now().Add(-time.Second), nil)
+	require.NoError(t, err)
+	active, err := s.holds.CreateHold(ctx, from, to, models.AmountFromUnits(20), 0)
+	require.NoError(t, err)
+
+	// Истёкший холд нельзя списать, даже если фоновая задача ещё не сняла его
+	_, _, err = s.holds.CaptureHold(ctx, expired.Id, models.Amount{})
+	assert.ErrorIs(t, err, service.ErrHoldExpired)
+
+	n, err := s.holds.ExpireHolds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	hold, err := s.holds.GetHold(ctx, expired.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldExpired, hold.Status)
+	assert.NotNil(t, hold.ClosedAt)
+	hold, err = s.holds.GetHold(ctx, active.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldActive, hold.Status)
+	assert.Equal(t, models.AmountFromUnits(20), s.wallet(t, from).Held)
+
+	// Фоновая задача снимает холды, пока не отменён контекст
+	expired, err = s.store.CreateHold(ctx, from, to, models.AmountFromUnits(30), time.Now().Add(-time.Second), nil)
+	require.NoError(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.holds.RunExpiry(runCtx, 10*time.Millisecond)
+	}()
+	assert.Eventually(t, func() bool {
+		hold, err := s.holds.GetHold(ctx, expired.Id)
+		return err == nil && hold.Status == models.HoldExpired
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, models.AmountFromUnits(20), s.wallet(t, from).Held)
+}
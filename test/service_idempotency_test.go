@@ -0,0 +1,323 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"TransactionSystem/api"
+	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/repository/sqlite"
+	"TransactionSystem/internal/service"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+type IdempotencyServiceTestSuite struct {
+	suite.Suite
+	container *postgres.PostgresContainer
+	dbPool    *pgxpool.Pool
+	repo      repository.IdempotencyStore
+	service   *service.IdempotencyService
+	ctx       context.Context
+	// sqlite запускает набор на встроенной базе вместо PostgreSQL
+	sqlite   bool
+	sqliteDB *sql.DB
+	// memory запускает набор на хранилище в памяти процесса
+	memory bool
+}
+
+func (suite *IdempotencyServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	if suite.memory {
+		return
+	}
+	if suite.sqlite {
+		suite.sqliteDB = openSQLite(suite.T())
+		suite.repo = sqlite.NewIdempotencyRepository(sqlite.NewDB(suite.sqliteDB))
+		suite.service = service.NewIdempotencyService(suite.repo, time.Hour)
+		return
+	}
+
+	skipIfNoDocker(suite.T())
+
+	container, err := postgres.RunContainer(
+		suite.ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.container = container
+
+	connStr, err := container.ConnectionString(suite.ctx, "sslmode=disable")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	pool, err := pgxpool.Connect(suite.ctx, connStr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.dbPool = pool
+
+	err = database.RunMigrations(suite.ctx, pool, testSchema)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	suite.repo = repository.NewIdempotencyRepository(repository.NewDB(pool, testSchema))
+	suite.service = service.NewIdempotencyService(suite.repo, time.Hour)
+}
+
+func (suite *IdempotencyServiceTestSuite) TearDownSuite() {
+	if suite.container != nil {
+		suite.container.Terminate(suite.ctx)
+	}
+	if suite.dbPool != nil {
+		suite.dbPool.Close()
+	}
+}
+
+func (suite *IdempotencyServiceTestSuite) BeforeTest(_, _ string) {
+	if suite.memory {
+		suite.repo = memory.NewStore()
+		suite.service = service.NewIdempotencyService(suite.repo, time.Hour)
+		return
+	}
+	if suite.sqliteDB != nil {
+		_, err := suite.sqliteDB.Exec(`DELETE FROM idempotency_keys`)
+		assert.NoError(suite.T(), err)
+		return
+	}
+	_, err := suite.dbPool.Exec(suite.ctx, inSchema(testSchema, `TRUNCATE TABLE {schema}.idempotency_keys`))
+	assert.NoError(suite.T(), err)
+}
+
+func TestIdempotencyService(t *testing.T) {
+	suite.Run(t, new(IdempotencyServiceTestSuite))
+}
+
+func TestIdempotencyService_SQLite(t *testing.T) {
+	suite.Run(t, &IdempotencyServiceTestSuite{sqlite: true})
+}
+
+func TestIdempotencyService_Memory(t *testing.T) {
+	suite.Run(t, &IdempotencyServiceTestSuite{memory: true})
+}
+
+func (suite *IdempotencyServiceTestSuite) TestReplayReturnsStoredResponse() {
+	t := suite.T()
+	ctx := context.Background()
+
+	record, err := suite.service.Begin(ctx, "send", "key-1", "fp-1")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	err = suite.service.Complete(ctx, "send", "key-1", http.StatusCreated, headers, []byte(`{"id":42}`))
+	assert.NoError(t, err)
+
+	record, err = suite.service.Begin(ctx, "send", "key-1", "fp-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, record) {
+		assert.Equal(t, http.StatusCreated, record.StatusCode)
+		assert.Equal(t, headers, record.Headers)
+		assert.JSONEq(t, `{"id":42}`, string(record.Body))
+	}
+}
+
+func (suite *IdempotencyServiceTestSuite) TestConflictingReuse() {
+	t := suite.T()
+	ctx := context.Background()
+
+	_, err := suite.service.Begin(ctx, "send", "key-2", "fp-1")
+	assert.NoError(t, err)
+
+	// Пока исходный запрос выполняется, повтор получает отказ
+	_, err = suite.service.Begin(ctx, "send", "key-2", "fp-1")
+	assert.ErrorIs(t, err, service.ErrIdempotencyKeyInProgress)
+
+	_, err = suite.service.Begin(ctx, "send", "key-2", "fp-2")
+	assert.ErrorIs(t, err, service.ErrIdempotencyKeyReused)
+
+	// Один и тот же ключ в разных областях независим
+	record, err := suite.service.Begin(ctx, "wallet_create", "key-2", "fp-2")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestReleaseAllowsRetry() {
+	t := suite.T()
+	ctx := context.Background()
+
+	_, err := suite.service.Begin(ctx, "send", "key-3", "fp-1")
+	assert.NoError(t, err)
+	assert.NoError(t, suite.service.Release(ctx, "send", "key-3"))
+
+	record, err := suite.service.Begin(ctx, "send", "key-3", "fp-1")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestExpiredKeyIsReusable() {
+	t := suite.T()
+	ctx := context.Background()
+
+	shortLived := service.NewIdempotencyService(suite.repo, 10*time.Millisecond)
+
+	_, err := shortLived.Begin(ctx, "send", "key-4", "fp-1")
+	assert.NoError(t, err)
+	assert.NoError(t, shortLived.Complete(ctx, "send", "key-4", http.StatusOK, nil, nil))
+
+	time.Sleep(50 * time.Millisecond)
+
+	record, err := shortLived.Begin(ctx, "send", "key-4", "fp-2")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestInvalidKey() {
+	_, err := suite.service.Begin(context.Background(), "send", "", "fp")
+	assert.ErrorIs(suite.T(), err, service.ErrIdempotencyKeyInvalid)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestPurgeExpired() {
+	t := suite.T()
+	ctx := context.Background()
+
+	shortLived := service.NewIdempotencyService(suite.repo, 10*time.Millisecond)
+	_, err := shortLived.Begin(ctx, "send", "key-5", "fp-1")
+	assert.NoError(t, err)
+	assert.NoError(t, shortLived.Complete(ctx, "send", "key-5", http.StatusOK, nil, nil))
+	_, err = suite.service.Begin(ctx, "send", "key-6", "fp-1")
+	assert.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Удаляются только ключи с истёкшим сроком
+	deleted, err := suite.service.PurgeExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = suite.repo.Get(ctx, "send", "key-5")
+	var nf *repository.NotFoundError
+	assert.ErrorAs(t, err, &nf)
+	_, err = suite.repo.Get(ctx, "send", "key-6")
+	assert.NoError(t, err)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestPanicReleasesKey() {
+	t := suite.T()
+
+	// Без хранилища перевод паникует внутри обработчика
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(service.NewTransactionService(nil, nil), nil, suite.service, healthService, nil, api.Options{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(`{"from":"wallet-a","to":"wallet-b","amount":1}`))
+	req.Header.Set("Idempotency-Key", "key-7")
+	assert.Panics(t, func() { router.ServeHTTP(httptest.NewRecorder(), req) })
+
+	// Ключ освобождён, запрос можно повторить
+	_, err := suite.repo.Get(context.Background(), "send", "key-7")
+	var nf *repository.NotFoundError
+	assert.ErrorAs(t, err, &nf)
+}
+
+// failingCompleteStore не может сохранить ответ
+type failingCompleteStore struct {
+	repository.IdempotencyStore
+}
+
+func (failingCompleteStore) Complete(context.Context, string, string, int, map[string]string, []byte) error {
+	return errors.New("storage unavailable")
+}
+
+func (suite *IdempotencyServiceTestSuite) TestCompleteFailureReleasesKey() {
+	t := suite.T()
+
+	idempotency := service.NewIdempotencyService(failingCompleteStore{suite.repo}, time.Hour)
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(nil, service.NewWalletService(memory.NewStore()), idempotency, healthService, nil, api.Options{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/wallet/create", strings.NewReader(`{"balance":1}`))
+	req.Header.Set("Idempotency-Key", "key-8")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Ответ не сохранён, поэтому ключ не остаётся занятым до истечения срока
+	_, err := suite.repo.Get(context.Background(), "wallet_create", "key-8")
+	var nf *repository.NotFoundError
+	assert.ErrorAs(t, err, &nf)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestBodyTooLarge() {
+	t := suite.T()
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(nil, service.NewWalletService(memory.NewStore()), suite.service, healthService, nil,
+		api.Options{MaxIdempotentBodyBytes: 16})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/wallet/create", strings.NewReader(`{"balance":1,"currency":"USD"}`))
+	req.Header.Set("Idempotency-Key", "key-9")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request_too_large")
+
+	// Ключ не занимается, пока тело не прочитано целиком
+	_, err := suite.repo.Get(context.Background(), "wallet_create", "key-9")
+	var nf *repository.NotFoundError
+	assert.ErrorAs(t, err, &nf)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/wallet/create", strings.NewReader(`{"balance":1}`))
+	req.Header.Set("Idempotency-Key", "key-9")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func (suite *IdempotencyServiceTestSuite) TestFingerprintIgnoresJSONFormatting() {
+	t := suite.T()
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(nil, service.NewWalletService(memory.NewStore()), suite.service, healthService, nil, api.Options{})
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/wallet/create", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-10")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := create(`{"balance":1,"currency":"USD"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// Тот же запрос с другим порядком полей и пробелами — повтор
+	replay := create("{\n  \"currency\": \"USD\",\n  \"balance\": 1\n}")
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+
+	// Другое содержимое — повторное использование ключа
+	assert.Equal(t, http.StatusUnprocessableEntity, create(`{"balance":1.01,"currency":"USD"}`).Code)
+}
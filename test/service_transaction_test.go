@@ -2,10 +2,12 @@ package service_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/metrics"
 	"TransactionSystem/internal/models"
 	"TransactionSystem/internal/repository"
 	"TransactionSystem/internal/service"
@@ -13,6 +15,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -23,11 +26,14 @@ type TransactionServiceTestSuite struct {
 	suite.Suite
 	container  *postgres.PostgresContainer
 	dbPool     *pgxpool.Pool
+	walletRepo *repository.WalletRepository
 	service    *service.TransactionService
+	ledger     *service.LedgerService
 	ctx        context.Context
 }
 
 func (suite *TransactionServiceTestSuite) SetupSuite() {
+	skipIfNoDocker(suite.T())
 	suite.ctx = context.Background()
 	
 	container, err := postgres.RunContainer(
@@ -57,14 +63,16 @@ func (suite *TransactionServiceTestSuite) SetupSuite() {
 	}
 	suite.dbPool = pool
 
-	err = database.RunMigrations(suite.ctx, pool)
+	err = database.RunMigrations(suite.ctx, pool, testSchema)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
 
-	walletRepo := repository.NewWalletRepository(pool)
-	transactionRepo := repository.NewTransactionRepository(pool)
-	suite.service = service.NewTransactionService(transactionRepo, walletRepo)
+	db := repository.NewDB(pool, testSchema)
+	suite.walletRepo = repository.NewWalletRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	suite.service = service.NewTransactionService(transactionRepo, suite.walletRepo)
+	suite.ledger = service.NewLedgerService(repository.NewLedgerRepository(db))
 }
 
 func (suite *TransactionServiceTestSuite) TearDownSuite() {
@@ -77,10 +85,11 @@ func (suite *TransactionServiceTestSuite) TearDownSuite() {
 }
 
 func (suite *TransactionServiceTestSuite) BeforeTest(_, _ string) {
-	_, err := suite.dbPool.Exec(suite.ctx, `
-		TRUNCATE TABLE "TransactionSystem".wallets CASCADE;
-		TRUNCATE TABLE "TransactionSystem".transactions CASCADE;
-	`)
+	_, err := suite.dbPool.Exec(suite.ctx, inSchema(testSchema, `
+		TRUNCATE TABLE {schema}.wallets CASCADE;
+		TRUNCATE TABLE {schema}.transactions CASCADE;
+		TRUNCATE TABLE {schema}.ledger_entries;
+	`))
 	assert.NoError(suite.T(), err)
 }
 
@@ -88,12 +97,10 @@ func TestTransactionService(t *testing.T) {
 	suite.Run(t, new(TransactionServiceTestSuite))
 }
 
-func (suite *TransactionServiceTestSuite) createTestWallet(balance float64) string {
+// createTestWallet создаёт кошелёк через репозиторий, чтобы начальный баланс попал в журнал проводок
+func (suite *TransactionServiceTestSuite) createTestWallet(balance models.Amount) string {
 	address := uuid.New().String()
-	_, err := suite.dbPool.Exec(suite.ctx, `
-		INSERT INTO "TransactionSystem".wallets (address, balance)
-		VALUES ($1, $2)
-	`, address, balance)
+	err := suite.walletRepo.CreateWallet(suite.ctx, address, models.DefaultCurrency, balance, nil)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -103,56 +110,65 @@ func (suite *TransactionServiceTestSuite) createTestWallet(balance float64) stri
 func (suite *TransactionServiceTestSuite) TestSendMoney_Success() {
 	ctx := context.Background()
 	
-	from := suite.createTestWallet(100.0)
-	to := suite.createTestWallet(50.0)
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(50))
 
-	err := suite.service.SendMoney(ctx, from, to, 30.0)
+	created, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(30))
 	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), created) {
+		assert.NotZero(suite.T(), created.Id)
+		assert.Equal(suite.T(), from, created.From)
+		assert.Equal(suite.T(), to, created.To)
+		assert.Equal(suite.T(), models.AmountFromUnits(30), created.Amount)
+		assert.Equal(suite.T(), models.AmountFromUnits(70), *created.FromBalance)
+		assert.Equal(suite.T(), models.AmountFromUnits(80), *created.ToBalance)
+		assert.WithinDuration(suite.T(), time.Now(), created.CreatedAt, 2*time.Second)
+	}
 
-	var fromBalance, toBalance float64
+	var fromBalance, toBalance models.Amount
 	err = suite.dbPool.QueryRow(ctx, 
-		`SELECT balance FROM "TransactionSystem".wallets WHERE address = $1`, from).Scan(&fromBalance)
+		inSchema(testSchema, `SELECT balance FROM {schema}.wallets WHERE address = $1`), from).Scan(&fromBalance)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), 70.0, fromBalance)
+	assert.Equal(suite.T(), models.AmountFromUnits(70), fromBalance)
 
 	err = suite.dbPool.QueryRow(ctx, 
-		`SELECT balance FROM "TransactionSystem".wallets WHERE address = $1`, to).Scan(&toBalance)
+		inSchema(testSchema, `SELECT balance FROM {schema}.wallets WHERE address = $1`), to).Scan(&toBalance)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), 80.0, toBalance)
+	assert.Equal(suite.T(), models.AmountFromUnits(80), toBalance)
 
 	var transaction models.Transaction
-	err = suite.dbPool.QueryRow(ctx, `
+	err = suite.dbPool.QueryRow(ctx, inSchema(testSchema, `
 		SELECT from_wallet, to_wallet, amount 
-		FROM "TransactionSystem".transactions
+		FROM {schema}.transactions
 		WHERE from_wallet = $1 AND to_wallet = $2
-	`, from, to).Scan(
+	`), from, to).Scan(
 		&transaction.From,
 		&transaction.To,
 		&transaction.Amount,
 	)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), 30.0, transaction.Amount)
+	assert.Equal(suite.T(), models.AmountFromUnits(30), transaction.Amount)
 }
 
 func (suite *TransactionServiceTestSuite) TestSendMoney_InvalidParameters() {
 	ctx := context.Background()
-	from := suite.createTestWallet(100.0)
+	from := suite.createTestWallet(models.AmountFromUnits(100))
 
 	tests := []struct {
 		name    string
 		from    string
 		to      string
-		amount  float64
+		amount  models.Amount
 		wantErr string
 	}{
-		{"Same sender and receiver", from, from, 10.0, "cannot be the same"},
-		{"Negative amount", from, "any", -5.0, "greater than zero"},
-		{"Zero amount", from, "any", 0.0, "greater than zero"},
+		{"Same sender and receiver", from, from, models.AmountFromUnits(10), "cannot be the same"},
+		{"Negative amount", from, "any", models.AmountFromUnits(-5), "greater than zero"},
+		{"Zero amount", from, "any", models.AmountFromUnits(0), "greater than zero"},
 	}
 
 	for _, tc := range tests {
 		suite.T().Run(tc.name, func(t *testing.T) {
-			err := suite.service.SendMoney(ctx, tc.from, tc.to, tc.amount)
+			_, err := suite.service.SendMoney(ctx, tc.from, tc.to, tc.amount)
 			assert.ErrorContains(t, err, tc.wantErr)
 		})
 	}
@@ -160,20 +176,116 @@ func (suite *TransactionServiceTestSuite) TestSendMoney_InvalidParameters() {
 
 func (suite *TransactionServiceTestSuite) TestSendMoney_InsufficientFunds() {
 	ctx := context.Background()
-	from := suite.createTestWallet(50.0)
-	to := suite.createTestWallet(0.0)
+	from := suite.createTestWallet(models.AmountFromUnits(50))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
 
-	err := suite.service.SendMoney(ctx, from, to, 100.0)
+	_, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(100))
 	assert.ErrorContains(suite.T(), err, "insufficient funds")
+	assert.ErrorIs(suite.T(), err, service.ErrInsufficientFunds)
+}
+
+func (suite *TransactionServiceTestSuite) TestSendMoney_WalletNotFound() {
+	ctx := context.Background()
+	from := suite.createTestWallet(models.AmountFromUnits(50))
+
+	_, err := suite.service.SendMoney(ctx, from, "missing_wallet", models.AmountFromUnits(10))
+	assert.ErrorIs(suite.T(), err, service.ErrWalletNotFound)
+
+	var nf *repository.NotFoundError
+	if assert.ErrorAs(suite.T(), err, &nf) {
+		assert.Equal(suite.T(), "missing_wallet", nf.Key)
+	}
+}
+
+func (suite *TransactionServiceTestSuite) TestSendMoney_InactiveWallets() {
+	ctx := context.Background()
+	active := suite.createTestWallet(models.AmountFromUnits(100))
+	frozen := suite.createTestWallet(models.AmountFromUnits(100))
+	closed := suite.createTestWallet(models.AmountFromUnits(0))
+
+	_, err := suite.dbPool.Exec(ctx, inSchema(testSchema, `UPDATE {schema}.wallets SET status = 'frozen' WHERE address = $1`), frozen)
+	assert.NoError(suite.T(), err)
+	_, err = suite.dbPool.Exec(ctx, inSchema(testSchema, `UPDATE {schema}.wallets SET status = 'closed' WHERE address = $1`), closed)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.SendMoney(ctx, frozen, active, models.AmountFromUnits(10))
+	assert.ErrorIs(suite.T(), err, service.ErrWalletFrozen)
+
+	_, err = suite.service.SendMoney(ctx, active, frozen, models.AmountFromUnits(10))
+	assert.ErrorIs(suite.T(), err, service.ErrWalletFrozen)
+
+	_, err = suite.service.SendMoney(ctx, active, closed, models.AmountFromUnits(10))
+	assert.ErrorIs(suite.T(), err, service.ErrWalletClosed)
+	var statusErr *service.WalletStatusError
+	if assert.ErrorAs(suite.T(), err, &statusErr) {
+		assert.Equal(suite.T(), closed, statusErr.Address)
+	}
+
+	balance, err := suite.walletRepo.GetWalletBalance(ctx, active)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.AmountFromUnits(100), balance)
+}
+
+func (suite *TransactionServiceTestSuite) TestSendMoney_Metrics() {
+	t := suite.T()
+	m := metrics.New(nil, nil)
+	suite.service.SetMetrics(m)
+	defer suite.service.SetMetrics(nil)
+
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+
+	_, err := suite.service.SendMoney(suite.ctx, from, to, models.MustParseAmount("12.50"))
+	require.NoError(t, err)
+	_, err = suite.service.SendMoney(suite.ctx, from, to, models.AmountFromUnits(1000))
+	require.ErrorIs(t, err, service.ErrInsufficientFunds)
+	_, err = suite.service.SendMoney(suite.ctx, from, uuid.New().String(), models.AmountFromUnits(1))
+	require.ErrorIs(t, err, service.ErrWalletNotFound)
+
+	body := scrape(t, m.Handler())
+	assert.Contains(t, body, `transaction_system_transfers_succeeded_total 1`)
+	assert.Contains(t, body, `transaction_system_transferred_amount_total{currency="USD"} 12.5`)
+	assert.Contains(t, body, `transaction_system_transfers_failed_total{reason="insufficient_funds"} 1`)
+	assert.Contains(t, body, `transaction_system_transfers_failed_total{reason="wallet_not_found"} 1`)
+}
+
+func (suite *TransactionServiceTestSuite) TestSendMoney_TracesSQL() {
+	t := suite.T()
+	from := suite.createTestWallet(models.AmountFromUnits(10))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+
+	recorder := recordSpans(t)
+	_, err := suite.service.SendMoney(suite.ctx, from, to, models.AmountFromUnits(1))
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	send := findSpan(spans, "TransactionService.SendMoney")
+	require.NotNil(t, send)
+
+	var statements []string
+	for _, s := range spans {
+		if s.Parent().SpanID() == send.SpanContext().SpanID() {
+			statements = append(statements, s.Name())
+			assert.Equal(t, "postgresql", spanAttribute(s, "db.system"))
+			assert.Contains(t, spanAttribute(s, "db.query.text"), inSchema(testSchema, "{schema}."))
+		}
+	}
+	// Две блокировки кошельков, два обновления балансов, запись транзакции и проводок
+	assert.Equal(t, []string{"SELECT", "SELECT", "UPDATE", "UPDATE", "INSERT", "INSERT"}, statements)
+}
+
+func (suite *TransactionServiceTestSuite) TestGetTransactionById_NotFound() {
+	_, err := suite.service.GetTransactionById(context.Background(), 999999)
+	assert.ErrorIs(suite.T(), err, service.ErrTransactionNotFound)
 }
 
 func (suite *TransactionServiceTestSuite) TestGetLastTransactions() {
 	ctx := context.Background()
-	from := suite.createTestWallet(200.0)
-	to := suite.createTestWallet(0.0)
+	from := suite.createTestWallet(models.AmountFromUnits(200))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
 
 	for i := 0; i < 5; i++ {
-		err := suite.service.SendMoney(ctx, from, to, 10.0)
+		_, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(10))
 		assert.NoError(suite.T(), err)
 	}
 
@@ -184,44 +296,38 @@ func (suite *TransactionServiceTestSuite) TestGetLastTransactions() {
 	for _, t := range transactions {
 		assert.Equal(suite.T(), from, t.From)
 		assert.Equal(suite.T(), to, t.To)
-		assert.Equal(suite.T(), 10.0, t.Amount)
+		assert.Equal(suite.T(), models.AmountFromUnits(10), t.Amount)
 	}
 }
 
 func (suite *TransactionServiceTestSuite) TestGetTransactionById() {
 	ctx := context.Background()
-	from := suite.createTestWallet(100.0)
-	to := suite.createTestWallet(0.0)
-
-	err := suite.service.SendMoney(ctx, from, to, 50.0)
-	assert.NoError(suite.T(), err)
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
 
-	var transactionID int64
-	err = suite.dbPool.QueryRow(ctx, `
-		SELECT id FROM "TransactionSystem".transactions 
-		WHERE from_wallet = $1 AND to_wallet = $2
-	`, from, to).Scan(&transactionID)
+	created, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(50))
 	assert.NoError(suite.T(), err)
+	transactionID := created.Id
 
 	transaction, err := suite.service.GetTransactionById(ctx, transactionID)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), transactionID, transaction.Id)
-	assert.Equal(suite.T(), 50.0, transaction.Amount)
+	assert.Equal(suite.T(), models.AmountFromUnits(50), transaction.Amount)
 }
 
 func (suite *TransactionServiceTestSuite) TestRemoveTransaction() {
 	ctx := context.Background()
-	from := suite.createTestWallet(100.0)
-	to := suite.createTestWallet(0.0)
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
 
-	err := suite.service.SendMoney(ctx, from, to, 30.0)
+	_, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(30))
 	assert.NoError(suite.T(), err)
 
 	var transactionID int64
-	err = suite.dbPool.QueryRow(ctx, `
-		SELECT id FROM "TransactionSystem".transactions 
+	err = suite.dbPool.QueryRow(ctx, inSchema(testSchema, `
+		SELECT id FROM {schema}.transactions 
 		WHERE from_wallet = $1 AND to_wallet = $2
-	`, from, to).Scan(&transactionID)
+	`), from, to).Scan(&transactionID)
 	assert.NoError(suite.T(), err)
 
 	err = suite.service.RemoveTransaction(ctx, transactionID)
@@ -229,4 +335,270 @@ func (suite *TransactionServiceTestSuite) TestRemoveTransaction() {
 
 	_, err = suite.service.GetTransactionById(ctx, transactionID)
 	assert.ErrorContains(suite.T(), err, "not found")
-}
\ No newline at end of file
+}
+func (suite *TransactionServiceTestSuite) TestReverseTransaction() {
+	ctx := context.Background()
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+
+	original, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(30))
+	assert.NoError(suite.T(), err)
+
+	reversal, err := suite.service.ReverseTransaction(ctx, original.Id, "sent by mistake")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), reversal) {
+		assert.Equal(suite.T(), to, reversal.From)
+		assert.Equal(suite.T(), from, reversal.To)
+		assert.Equal(suite.T(), models.AmountFromUnits(30), reversal.Amount)
+		assert.Equal(suite.T(), &original.Id, reversal.ReversalOf)
+		assert.Equal(suite.T(), "sent by mistake", reversal.Reason)
+		assert.Equal(suite.T(), models.AmountFromUnits(0), *reversal.FromBalance)
+		assert.Equal(suite.T(), models.AmountFromUnits(100), *reversal.ToBalance)
+	}
+
+	reversed, err := suite.service.GetTransactionById(ctx, original.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &reversal.Id, reversed.ReversedBy)
+
+	_, err = suite.service.ReverseTransaction(ctx, original.Id, "again")
+	assert.ErrorIs(suite.T(), err, service.ErrTransactionAlreadyReversed)
+
+	_, err = suite.service.ReverseTransaction(ctx, reversal.Id, "undo reversal")
+	assert.ErrorIs(suite.T(), err, service.ErrTransactionNotReversible)
+
+	report, err := suite.ledger.CheckInvariants(ctx)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced())
+}
+
+func (suite *TransactionServiceTestSuite) TestReverseTransaction_Refused() {
+	ctx := context.Background()
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+	other := suite.createTestWallet(models.AmountFromUnits(0))
+
+	original, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(30))
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.ReverseTransaction(ctx, original.Id, " ")
+	assert.ErrorIs(suite.T(), err, service.ErrReversalReasonRequired)
+
+	// Получатель уже потратил часть суммы, сторнирование увело бы его баланс в минус
+	_, err = suite.service.SendMoney(ctx, to, other, models.AmountFromUnits(10))
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.ReverseTransaction(ctx, original.Id, "sent by mistake")
+	assert.ErrorIs(suite.T(), err, service.ErrInsufficientFunds)
+
+	reloaded, err := suite.service.GetTransactionById(ctx, original.Id)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), reloaded.ReversedBy)
+
+	_, err = suite.service.ReverseTransaction(ctx, 999999, "missing")
+	assert.ErrorIs(suite.T(), err, service.ErrTransactionNotFound)
+}
+
+func (suite *TransactionServiceTestSuite) TestSendMoney_ConcurrentTransfersPreserveTotal() {
+	ctx := context.Background()
+
+	const (
+		walletsCount   = 5
+		transfersCount = 300
+	)
+
+	wallets := make([]string, walletsCount)
+	for i := range wallets {
+		wallets[i] = suite.createTestWallet(models.AmountFromUnits(100))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < transfersCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from := wallets[i%walletsCount]
+			to := wallets[(i*7+1)%walletsCount]
+			if from == to {
+				to = wallets[(i+1)%walletsCount]
+			}
+			// Часть переводов закончится ошибкой "insufficient funds", это ожидаемо
+			_, _ = suite.service.SendMoney(ctx, from, to, models.MustParseAmount("33.33"))
+		}(i)
+	}
+	wg.Wait()
+
+	var total models.Amount
+	err := suite.dbPool.QueryRow(ctx,
+		inSchema(testSchema, `SELECT SUM(balance) FROM {schema}.wallets`)).Scan(&total)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.AmountFromUnits(100*walletsCount), total)
+
+	var negative int
+	err = suite.dbPool.QueryRow(ctx,
+		inSchema(testSchema, `SELECT COUNT(*) FROM {schema}.wallets WHERE balance < 0`)).Scan(&negative)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, negative)
+
+	// Сумма по журналу переводов должна сходиться с балансами
+	for _, address := range wallets {
+		var balance, expected models.Amount
+		err = suite.dbPool.QueryRow(ctx, inSchema(testSchema, `
+			SELECT w.balance,
+			       100 + COALESCE((SELECT SUM(amount) FROM {schema}.transactions WHERE to_wallet = w.address), 0)
+			           - COALESCE((SELECT SUM(amount) FROM {schema}.transactions WHERE from_wallet = w.address), 0)
+			FROM {schema}.wallets w WHERE w.address = $1
+		`), address).Scan(&balance, &expected)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), expected, balance)
+	}
+
+	report, err := suite.ledger.CheckInvariants(ctx)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced())
+}
+
+func (suite *TransactionServiceTestSuite) TestListTransactions_Pagination() {
+	ctx := context.Background()
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+
+	var created []int64
+	for i := 1; i <= 5; i++ {
+		transaction, err := suite.service.SendMoney(ctx, from, to, models.AmountFromUnits(int64(i)))
+		assert.NoError(suite.T(), err)
+		created = append(created, transaction.Id)
+	}
+
+	var seen []int64
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := suite.service.ListTransactions(ctx, models.TransactionFilter{Limit: 2}, cursor)
+		assert.NoError(suite.T(), err)
+		for _, t := range page.Transactions {
+			seen = append(seen, t.Id)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	// Все транзакции получены ровно один раз, от новых к старым
+	assert.Equal(suite.T(), []int64{created[4], created[3], created[2], created[1], created[0]}, seen)
+}
+
+func (suite *TransactionServiceTestSuite) TestListTransactions_Filters() {
+	ctx := context.Background()
+	a := suite.createTestWallet(models.AmountFromUnits(100))
+	b := suite.createTestWallet(models.AmountFromUnits(100))
+	c := suite.createTestWallet(models.AmountFromUnits(100))
+
+	_, err := suite.service.SendMoney(ctx, a, b, models.AmountFromUnits(10))
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.SendMoney(ctx, b, a, models.AmountFromUnits(20))
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.SendMoney(ctx, b, c, models.AmountFromUnits(30))
+	assert.NoError(suite.T(), err)
+
+	count := func(filter models.TransactionFilter) int {
+		page, err := suite.service.ListTransactions(ctx, filter, "")
+		assert.NoError(suite.T(), err)
+		return len(page.Transactions)
+	}
+
+	assert.Equal(suite.T(), 2, count(models.TransactionFilter{Wallet: a}))
+	assert.Equal(suite.T(), 1, count(models.TransactionFilter{Wallet: a, WalletRole: models.WalletRoleSender}))
+	assert.Equal(suite.T(), 1, count(models.TransactionFilter{Wallet: a, WalletRole: models.WalletRoleReceiver}))
+	assert.Equal(suite.T(), 2, count(models.TransactionFilter{Wallet: b, WalletRole: models.WalletRoleSender}))
+
+	minAmount := models.AmountFromUnits(15)
+	maxAmount := models.AmountFromUnits(25)
+	assert.Equal(suite.T(), 1, count(models.TransactionFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}))
+
+	future := time.Now().Add(time.Hour)
+	assert.Equal(suite.T(), 0, count(models.TransactionFilter{Since: &future}))
+
+	_, err = suite.service.ListTransactions(ctx, models.TransactionFilter{MinAmount: &maxAmount, MaxAmount: &minAmount}, "")
+	assert.ErrorIs(suite.T(), err, service.ErrInvalidFilter)
+
+	_, err = suite.service.ListTransactions(ctx, models.TransactionFilter{}, "not-a-cursor")
+	assert.ErrorIs(suite.T(), err, service.ErrInvalidCursor)
+}
+
+func (suite *TransactionServiceTestSuite) TestGetWalletHistory_RunningBalance() {
+	ctx := context.Background()
+	a := suite.createTestWallet(models.AmountFromUnits(100))
+	b := suite.createTestWallet(models.AmountFromUnits(50))
+
+	_, err := suite.service.SendMoney(ctx, a, b, models.AmountFromUnits(30))
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.SendMoney(ctx, b, a, models.AmountFromUnits(5))
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.SendMoney(ctx, a, b, models.MustParseAmount("0.50"))
+	assert.NoError(suite.T(), err)
+
+	page, err := suite.service.GetWalletHistory(ctx, a, models.WalletHistoryFilter{Limit: 2}, "")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), page.Entries, 2) {
+		return
+	}
+	assert.NotEmpty(suite.T(), page.NextCursor)
+
+	assert.Equal(suite.T(), models.DirectionDebit, page.Entries[0].Direction)
+	assert.Equal(suite.T(), b, page.Entries[0].Counterparty)
+	assert.Equal(suite.T(), models.MustParseAmount("74.50"), page.Entries[0].BalanceAfter)
+
+	assert.Equal(suite.T(), models.DirectionCredit, page.Entries[1].Direction)
+	assert.Equal(suite.T(), models.AmountFromUnits(5), page.Entries[1].Amount)
+	assert.Equal(suite.T(), models.AmountFromUnits(75), page.Entries[1].BalanceAfter)
+
+	page, err = suite.service.GetWalletHistory(ctx, a, models.WalletHistoryFilter{Limit: 2}, page.NextCursor)
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), page.Entries, 1) {
+		assert.Equal(suite.T(), models.AmountFromUnits(70), page.Entries[0].BalanceAfter)
+	}
+	assert.Empty(suite.T(), page.NextCursor)
+
+	_, err = suite.service.GetWalletHistory(ctx, "missing_wallet", models.WalletHistoryFilter{}, "")
+	assert.ErrorIs(suite.T(), err, service.ErrWalletNotFound)
+}
+
+func (suite *TransactionServiceTestSuite) TestLedger_TransferWritesBalancedEntries() {
+	ctx := context.Background()
+	from := suite.createTestWallet(models.AmountFromUnits(100))
+	to := suite.createTestWallet(models.AmountFromUnits(0))
+
+	created, err := suite.service.SendMoney(ctx, from, to, models.MustParseAmount("12.34"))
+	assert.NoError(suite.T(), err)
+
+	var entries int
+	var sum models.Amount
+	err = suite.dbPool.QueryRow(ctx, inSchema(testSchema, `
+		SELECT COUNT(*), SUM(amount) FROM {schema}.ledger_entries WHERE transaction_id = $1
+	`), created.Id).Scan(&entries, &sum)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, entries)
+	assert.True(suite.T(), sum.IsZero())
+
+	report, err := suite.ledger.CheckInvariants(ctx)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced())
+}
+
+func (suite *TransactionServiceTestSuite) TestLedger_DetectsBalanceDrift() {
+	ctx := context.Background()
+	address := suite.createTestWallet(models.AmountFromUnits(100))
+
+	// Баланс, изменённый в обход журнала, должен обнаруживаться сверкой
+	_, err := suite.dbPool.Exec(ctx,
+		inSchema(testSchema, `UPDATE {schema}.wallets SET balance = 90 WHERE address = $1`), address)
+	assert.NoError(suite.T(), err)
+
+	report, err := suite.ledger.CheckInvariants(ctx)
+	assert.ErrorIs(suite.T(), err, service.ErrLedgerImbalanced)
+	if assert.Len(suite.T(), report.Mismatches, 1) {
+		assert.Equal(suite.T(), address, report.Mismatches[0].Address)
+		assert.Equal(suite.T(), models.AmountFromUnits(90), report.Mismatches[0].Balance)
+		assert.Equal(suite.T(), models.AmountFromUnits(100), report.Mismatches[0].LedgerBalance)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/models"
 	"TransactionSystem/internal/repository"
 	"TransactionSystem/internal/service"
 
@@ -27,6 +28,7 @@ type WalletServiceTestSuite struct {
 }
 
 func (suite *WalletServiceTestSuite) SetupSuite() {
+	skipIfNoDocker(suite.T())
 	suite.ctx = context.Background()
 	
 	container, err := postgres.RunContainer(
@@ -56,12 +58,12 @@ func (suite *WalletServiceTestSuite) SetupSuite() {
 	}
 	suite.dbPool = pool
 
-	err = database.RunMigrations(suite.ctx, pool)
+	err = database.RunMigrations(suite.ctx, pool, testSchema)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
 
-	repo := repository.NewWalletRepository(pool)
+	repo := repository.NewWalletRepository(repository.NewDB(pool, testSchema))
 	suite.service = service.NewWalletService(repo)
 }
 
@@ -75,7 +77,7 @@ func (suite *WalletServiceTestSuite) TearDownSuite() {
 }
 
 func (suite *WalletServiceTestSuite) BeforeTest(_, _ string) {
-	_, err := suite.dbPool.Exec(suite.ctx, `DELETE FROM "TransactionSystem".wallets`)
+	_, err := suite.dbPool.Exec(suite.ctx, inSchema(testSchema, `DELETE FROM {schema}.wallets`))
 	assert.NoError(suite.T(), err)
 }
 
@@ -83,12 +85,12 @@ func TestWalletService(t *testing.T) {
 	suite.Run(t, new(WalletServiceTestSuite))
 }
 
-func (suite *WalletServiceTestSuite) createTestWallet(balance float64) string {
+func (suite *WalletServiceTestSuite) createTestWallet(balance models.Amount) string {
 	address := uuid.New().String()
-	_, err := suite.dbPool.Exec(suite.ctx, `
-		INSERT INTO "TransactionSystem".wallets (address, balance)
+	_, err := suite.dbPool.Exec(suite.ctx, inSchema(testSchema, `
+		INSERT INTO {schema}.wallets (address, balance)
 		VALUES ($1, $2)
-	`, address, balance)
+	`), address, balance)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -99,14 +101,14 @@ func (suite *WalletServiceTestSuite) TestCreateWallet() {
 	t := suite.T()
 	ctx := context.Background()
 
-	address, err := suite.service.CreateWallet(ctx)
+	address, err := suite.service.CreateWallet(ctx, models.DefaultCurrency, models.AmountFromUnits(0))
 	assert.NoError(t, err)
 	assert.NotEmpty(t, address)
 
 	// Проверяем существование в БД
 	var count int
 	err = suite.dbPool.QueryRow(ctx, 
-		`SELECT COUNT(*) FROM "TransactionSystem".wallets WHERE address = $1`, address).Scan(&count)
+		inSchema(testSchema, `SELECT COUNT(*) FROM {schema}.wallets WHERE address = $1`), address).Scan(&count)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 }
@@ -115,11 +117,11 @@ func (suite *WalletServiceTestSuite) TestGetBalance() {
 	t := suite.T()
 	ctx := context.Background()
 
-	address := suite.createTestWallet(100.0)
+	address := suite.createTestWallet(models.AmountFromUnits(100))
 
 	balance, err := suite.service.GetBalance(ctx, address)
 	assert.NoError(t, err)
-	assert.Equal(t, 100.0, balance)
+	assert.Equal(t, models.AmountFromUnits(100), balance)
 
 	// Несуществующий кошелек
 	_, err = suite.service.GetBalance(ctx, "invalid_address")
@@ -130,13 +132,13 @@ func (suite *WalletServiceTestSuite) TestGetWallet() {
 	t := suite.T()
 	ctx := context.Background()
 
-	address := suite.createTestWallet(200.0)
+	address := suite.createTestWallet(models.AmountFromUnits(200))
 
 	wallet, err := suite.service.GetWallet(ctx, address)
 	assert.NoError(t, err)
 
 	assert.Equal(t, address, wallet.Address)
-	assert.Equal(t, 200.0, wallet.Balance)
+	assert.Equal(t, models.AmountFromUnits(200), wallet.Balance)
 	assert.WithinDuration(t, time.Now(), wallet.CreatedAt, 2*time.Second)
 }
 
@@ -144,16 +146,16 @@ func (suite *WalletServiceTestSuite) TestUpdateBalance() {
 	t := suite.T()
 	ctx := context.Background()
 
-	address := suite.createTestWallet(50.0)
+	address := suite.createTestWallet(models.AmountFromUnits(50))
 
 	tests := []struct {
 		name        string
-		newBalance  float64
+		newBalance  models.Amount
 		expectError bool
 	}{
-		{"Positive value", 100.0, false},
-		{"Negative value", -10.0, true},
-		{"Zero value", 0.0, false},
+		{"Positive value", models.AmountFromUnits(100), false},
+		{"Negative value", models.AmountFromUnits(-10), true},
+		{"Zero value", models.AmountFromUnits(0), false},
 	}
 
 	for _, tc := range tests {
@@ -174,7 +176,7 @@ func (suite *WalletServiceTestSuite) TestRemoveWallet() {
 	t := suite.T()
 	ctx := context.Background()
 
-	address := suite.createTestWallet(0.0)
+	address := suite.createTestWallet(models.AmountFromUnits(0))
 
 	err := suite.service.RemoveWallet(ctx, address)
 	assert.NoError(t, err)
@@ -182,7 +184,7 @@ func (suite *WalletServiceTestSuite) TestRemoveWallet() {
 	// Проверяем удаление
 	var count int
 	err = suite.dbPool.QueryRow(ctx, 
-		`SELECT COUNT(*) FROM "TransactionSystem".wallets WHERE address = $1`, address).Scan(&count)
+		inSchema(testSchema, `SELECT COUNT(*) FROM {schema}.wallets WHERE address = $1`), address).Scan(&count)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
 }
@@ -193,4 +195,83 @@ func (suite *WalletServiceTestSuite) TestRemoveWallet_NonExistent() {
 
 	err := suite.service.RemoveWallet(ctx, "non_existent_address")
 	assert.ErrorContains(t, err, "wallet with address non_existent_address not found")
+	assert.ErrorIs(t, err, service.ErrWalletNotFound)
+}
+
+func (suite *WalletServiceTestSuite) TestWalletStatusTransitions() {
+	t := suite.T()
+	ctx := context.Background()
+
+	address := suite.createTestWallet(models.AmountFromUnits(0))
+
+	wallet, err := suite.service.GetWallet(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletActive, wallet.Status)
+
+	wallet, err = suite.service.FreezeWallet(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletFrozen, wallet.Status)
+
+	_, err = suite.service.FreezeWallet(ctx, address)
+	assert.ErrorIs(t, err, service.ErrWalletStatusTransition)
+
+	// Замороженный кошелёк нельзя закрыть, сначала его нужно разморозить
+	_, err = suite.service.CloseWallet(ctx, address)
+	assert.ErrorIs(t, err, service.ErrWalletStatusTransition)
+
+	wallet, err = suite.service.UnfreezeWallet(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletActive, wallet.Status)
+
+	wallet, err = suite.service.CloseWallet(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletClosed, wallet.Status)
+
+	_, err = suite.service.UnfreezeWallet(ctx, address)
+	assert.ErrorIs(t, err, service.ErrWalletStatusTransition)
+
+	// Закрытый кошелёк остаётся в БД
+	wallet, err = suite.service.GetWallet(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletClosed, wallet.Status)
+}
+
+func (suite *WalletServiceTestSuite) TestCloseWallet_NonZeroBalance() {
+	t := suite.T()
+	ctx := context.Background()
+
+	address := suite.createTestWallet(models.AmountFromUnits(10))
+
+	_, err := suite.service.CloseWallet(ctx, address)
+	assert.ErrorIs(t, err, service.ErrWalletBalanceNotZero)
+
+	_, err = suite.service.CloseWallet(ctx, "non_existent_address")
+	assert.ErrorIs(t, err, service.ErrWalletNotFound)
+}
+
+func (suite *WalletServiceTestSuite) TestSchemasAreIsolated() {
+	t := suite.T()
+	ctx := context.Background()
+
+	// Второй экземпляр сервиса в той же базе, но в другой схеме
+	const otherSchema = "tenant_b"
+	err := database.RunMigrations(ctx, suite.dbPool, otherSchema)
+	assert.NoError(t, err)
+	defer suite.dbPool.Exec(ctx, `DROP SCHEMA tenant_b CASCADE`)
+
+	other := service.NewWalletService(repository.NewWalletRepository(repository.NewDB(suite.dbPool, otherSchema)))
+
+	address, err := suite.service.CreateWallet(ctx, models.DefaultCurrency, models.AmountFromUnits(10))
+	assert.NoError(t, err)
+	otherAddress, err := other.CreateWallet(ctx, models.DefaultCurrency, models.AmountFromUnits(20))
+	assert.NoError(t, err)
+
+	_, err = other.GetWallet(ctx, address)
+	assert.ErrorIs(t, err, service.ErrWalletNotFound)
+	_, err = suite.service.GetWallet(ctx, otherAddress)
+	assert.ErrorIs(t, err, service.ErrWalletNotFound)
+
+	wallet, err := other.GetWallet(ctx, otherAddress)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AmountFromUnits(20), wallet.Balance)
 }
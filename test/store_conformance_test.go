@@ -0,0 +1,762 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"TransactionSystem/config"
+	"TransactionSystem/internal/database"
+	"TransactionSystem/internal/models"
+	"TransactionSystem/internal/repository"
+	"TransactionSystem/internal/repository/memory"
+	"TransactionSystem/internal/repository/sqlite"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// stores — набор хранилищ одной реализации
+type stores struct {
+	wallets      repository.WalletStore
+	transactions repository.TransactionStore
+	ledger       repository.LedgerStore
+	holds        repository.HoldStore
+}
+
+// StoreConformanceSuite проверяет, что реализации хранилищ ведут себя одинаково.
+// open возвращает пустые хранилища и вызывается перед каждым тестом.
+type StoreConformanceSuite struct {
+	suite.Suite
+	open func(t *testing.T) stores
+	stores
+	ctx context.Context
+}
+
+func (suite *StoreConformanceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.stores = suite.open(suite.T())
+}
+
+func TestStoreConformance_Memory(t *testing.T) {
+	suite.Run(t, &StoreConformanceSuite{open: func(*testing.T) stores {
+		store := memory.NewStore()
+		return stores{store, store, store, store}
+	}})
+}
+
+func TestStoreConformance_SQLite(t *testing.T) {
+	suite.Run(t, &StoreConformanceSuite{open: func(t *testing.T) stores {
+		db := sqlite.NewDB(openSQLite(t))
+		return stores{sqlite.NewWalletRepository(db), sqlite.NewTransactionRepository(db), sqlite.NewLedgerRepository(db), sqlite.NewHoldRepository(db)}
+	}})
+}
+
+// openSQLite создаёт базу SQLite во временном каталоге теста и применяет к ней миграции
+func openSQLite(t *testing.T) *sql.DB {
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "test.db")
+
+	db, err := database.InitSQLite(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := database.NewSQLiteMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.Background()))
+
+	return db
+}
+
+func TestStoreConformance_Postgres(t *testing.T) {
+	skipIfNoDocker(t)
+	ctx := context.Background()
+
+	container, err := postgres.RunContainer(
+		ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { container.Terminate(ctx) })
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.Connect(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	// Репозитории не должны зависеть от имени схемы: набор проходит и в схеме по умолчанию,
+	// и в отдельной схеме в той же базе
+	for _, schema := range []string{testSchema, "ts_conformance"} {
+		schema := schema
+		t.Run(schema, func(t *testing.T) {
+			require.NoError(t, database.RunMigrations(ctx, pool, schema))
+
+			db := repository.NewDB(pool, schema)
+			suite.Run(t, &StoreConformanceSuite{open: func(t *testing.T) stores {
+				_, err := db.Exec(ctx, `
+					TRUNCATE TABLE {schema}.holds;
+					TRUNCATE TABLE {schema}.wallets CASCADE;
+					TRUNCATE TABLE {schema}.transactions CASCADE;
+					TRUNCATE TABLE {schema}.ledger_entries;
+				`)
+				require.NoError(t, err)
+				return stores{repository.NewWalletRepository(db), repository.NewTransactionRepository(db), repository.NewLedgerRepository(db), repository.NewHoldRepository(db)}
+			}})
+		})
+	}
+}
+
+func (suite *StoreConformanceSuite) createWallet(balance int64) string {
+	address := uuid.New().String()
+	require.NoError(suite.T(), suite.wallets.CreateWallet(suite.ctx, address, models.DefaultCurrency, models.AmountFromUnits(balance), nil))
+	return address
+}
+
+func (suite *StoreConformanceSuite) balance(address string) models.Amount {
+	wallet, err := suite.wallets.GetWallet(suite.ctx, address)
+	require.NoError(suite.T(), err)
+	return wallet.Balance
+}
+
+func (suite *StoreConformanceSuite) transfer(from, to string, amount int64) *models.Transaction {
+	t, err := suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.AmountFromUnits(amount), nil)
+	require.NoError(suite.T(), err)
+	return t
+}
+
+func (suite *StoreConformanceSuite) assertLedgerBalanced() {
+	report, err := suite.ledger.Verify(suite.ctx)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced(), "%+v", report)
+}
+
+func assertNotFound(t *testing.T, err error, entity string) {
+	t.Helper()
+	var nf *repository.NotFoundError
+	if assert.True(t, errors.As(err, &nf), "expected NotFoundError, got %v", err) {
+		assert.Equal(t, entity, nf.Entity)
+	}
+}
+
+func (suite *StoreConformanceSuite) TestWallets() {
+	t := suite.T()
+
+	empty, err := suite.wallets.IsEmpty(suite.ctx)
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	owner := int64(7)
+	address := uuid.New().String()
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, address, models.DefaultCurrency, models.AmountFromUnits(10), &owner))
+	assert.Error(t, suite.wallets.CreateWallet(suite.ctx, address, models.DefaultCurrency, models.AmountFromUnits(0), nil))
+
+	wallet, err := suite.wallets.GetWallet(suite.ctx, address)
+	require.NoError(t, err)
+	assert.Equal(t, address, wallet.Address)
+	assert.Equal(t, models.AmountFromUnits(10), wallet.Balance)
+	assert.Equal(t, models.WalletActive, wallet.Status)
+	assert.Equal(t, &owner, wallet.Owner)
+	assert.False(t, wallet.CreatedAt.IsZero())
+
+	// Изменение полученной копии не затрагивает хранилище
+	wallet.Balance = models.AmountFromUnits(1000)
+	assert.Equal(t, models.AmountFromUnits(10), suite.balance(address))
+
+	_, err = suite.wallets.GetWallet(suite.ctx, "missing")
+	assertNotFound(t, err, "wallet")
+	assertNotFound(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, "missing", models.AmountFromUnits(1)), "wallet")
+
+	require.NoError(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, address, models.AmountFromUnits(25)))
+	assert.Equal(t, models.AmountFromUnits(25), suite.balance(address))
+	assert.Error(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, address, models.AmountFromUnits(-1)))
+
+	refuse := errors.New("refused")
+	_, err = suite.wallets.UpdateWalletStatus(suite.ctx, address, models.WalletFrozen, func(*models.Wallet) error { return refuse })
+	assert.ErrorIs(t, err, refuse)
+	frozen, err := suite.wallets.UpdateWalletStatus(suite.ctx, address, models.WalletFrozen, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.WalletFrozen, frozen.Status)
+	_, err = suite.wallets.UpdateWalletStatus(suite.ctx, "missing", models.WalletFrozen, nil)
+	assertNotFound(t, err, "wallet")
+
+	suite.createWallet(0)
+	counts, err := suite.wallets.CountWalletsByStatus(suite.ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[models.WalletStatus]int64{models.WalletActive: 1, models.WalletFrozen: 1}, counts)
+
+	assert.ErrorIs(t, suite.wallets.RemoveWallet(suite.ctx, address, func(*models.Wallet) error { return refuse }), refuse)
+	require.NoError(t, suite.wallets.RemoveWallet(suite.ctx, address, nil))
+	_, err = suite.wallets.GetWallet(suite.ctx, address)
+	assertNotFound(t, err, "wallet")
+	assertNotFound(t, suite.wallets.RemoveWallet(suite.ctx, address, nil), "wallet")
+
+	suite.assertLedgerBalanced()
+}
+
+func (suite *StoreConformanceSuite) TestExecuteTransfer() {
+	t := suite.T()
+	from, to := suite.createWallet(100), suite.createWallet(5)
+
+	var checked []string
+	transaction, err := suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.MustParseAmount("30.50"), func(f, t *models.Wallet) error {
+		checked = append(checked, f.Address, t.Address)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{from, to}, checked)
+	assert.NotZero(t, transaction.Id)
+	assert.Equal(t, from, transaction.From)
+	assert.Equal(t, to, transaction.To)
+	assert.False(t, transaction.CreatedAt.IsZero())
+	require.NotNil(t, transaction.FromBalance)
+	require.NotNil(t, transaction.ToBalance)
+	assert.Equal(t, models.MustParseAmount("69.50"), *transaction.FromBalance)
+	assert.Equal(t, models.MustParseAmount("35.50"), *transaction.ToBalance)
+
+	stored, err := suite.transactions.GetTransactionById(suite.ctx, transaction.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("30.50"), stored.Amount)
+	assert.Nil(t, stored.FromBalance)
+
+	found, err := suite.transactions.GetTransactionByInfo(suite.ctx, from, to, stored.CreatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, transaction.Id, found.Id)
+	_, err = suite.transactions.GetTransactionByInfo(suite.ctx, to, from, stored.CreatedAt)
+	assertNotFound(t, err, "transaction")
+
+	// Отклонённый проверкой или невозможный перевод ничего не меняет
+	refuse := errors.New("refused")
+	_, err = suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.AmountFromUnits(1), func(_, _ *models.Wallet) error { return refuse })
+	assert.ErrorIs(t, err, refuse)
+	_, err = suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.AmountFromUnits(1000), nil)
+	assert.Error(t, err)
+	_, err = suite.transactions.ExecuteTransfer(suite.ctx, from, "missing", models.AmountFromUnits(1), nil)
+	assertNotFound(t, err, "wallet")
+
+	assert.Equal(t, models.MustParseAmount("69.50"), suite.balance(from))
+	assert.Equal(t, models.MustParseAmount("35.50"), suite.balance(to))
+	last, err := suite.transactions.GetLastTransactions(suite.ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, last, 1)
+
+	_, err = suite.transactions.GetTransactionById(suite.ctx, transaction.Id+1000)
+	assertNotFound(t, err, "transaction")
+
+	suite.assertLedgerBalanced()
+}
+
+func (suite *StoreConformanceSuite) TestCurrency() {
+	t := suite.T()
+	from, to := uuid.New().String(), uuid.New().String()
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, from, "EUR", models.AmountFromUnits(10), nil))
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, to, "EUR", models.AmountFromUnits(0), nil))
+
+	wallet, err := suite.wallets.GetWallet(suite.ctx, from)
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("EUR"), wallet.Currency)
+
+	// Проверка перевода видит валюты обоих кошельков
+	transaction, err := suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.AmountFromUnits(4), func(f, t *models.Wallet) error {
+		if f.Currency != "EUR" || t.Currency != "EUR" {
+			return errors.New("currency not loaded")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("EUR"), transaction.Currency)
+
+	stored, err := suite.transactions.GetTransactionById(suite.ctx, transaction.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("EUR"), stored.Currency)
+
+	listed, err := suite.transactions.ListTransactions(suite.ctx, models.TransactionFilter{WalletRole: models.WalletRoleAny, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, models.Currency("EUR"), listed[0].Currency)
+
+	history, err := suite.transactions.GetWalletHistory(suite.ctx, to, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.Currency("EUR"), history[0].Currency)
+
+	// Журнал сходится по каждой валюте отдельно, хотя счёт @issuance у валют общий
+	usd := suite.createWallet(5)
+	require.NoError(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, usd, models.AmountFromUnits(7)))
+	report, err := suite.ledger.Verify(suite.ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Balanced(), "%+v", report)
+	assert.ElementsMatch(t, []models.Currency{"EUR", "USD"}, mapKeys(report.Totals))
+}
+
+func mapKeys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Ошибки в разных валютах не компенсируют друг друга
+func TestLedgerTotalsPerCurrency_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	ledger := sqlite.NewLedgerRepository(sqlite.NewDB(db))
+
+	_, err := db.Exec(`INSERT INTO ledger_entries (account, amount, kind, created_at, currency)
+		VALUES ('@issuance', 100, 'adjustment', '2024-01-01T00:00:00Z', 'USD'),
+		       ('@issuance', -100, 'adjustment', '2024-01-01T00:00:00Z', 'EUR')`)
+	require.NoError(t, err)
+
+	report, err := ledger.Verify(ctx)
+	require.NoError(t, err)
+	assert.False(t, report.Balanced())
+	assert.Equal(t, []models.Currency{"EUR", "USD"}, report.UnbalancedCurrencies())
+	assert.Equal(t, models.MustParseAmount("1"), report.Totals["USD"])
+	assert.Equal(t, models.MustParseAmount("-1"), report.Totals["EUR"])
+}
+
+func (suite *StoreConformanceSuite) TestConcurrentTransfers() {
+	t := suite.T()
+
+	const (
+		walletsCount   = 5
+		transfersCount = 300
+	)
+
+	wallets := make([]string, walletsCount)
+	for i := range wallets {
+		wallets[i] = suite.createWallet(100)
+	}
+
+	amount := models.MustParseAmount("33.33")
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < transfersCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := wallets[i%walletsCount], wallets[(i*7+1)%walletsCount]
+			if from == to {
+				to = wallets[(i+1)%walletsCount]
+			}
+			_, err := suite.transactions.ExecuteTransfer(suite.ctx, from, to, amount, func(f, _ *models.Wallet) error {
+				if f.Balance.Cmp(amount) < 0 {
+					return errors.New("insufficient funds")
+				}
+				return nil
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var total models.Amount
+	for _, address := range wallets {
+		balance := suite.balance(address)
+		assert.GreaterOrEqual(t, balance.Sign(), 0)
+		total = total.Add(balance)
+	}
+	assert.Equal(t, models.AmountFromUnits(100*walletsCount), total)
+
+	transactions, err := suite.transactions.GetLastTransactions(suite.ctx, transfersCount)
+	require.NoError(t, err)
+	assert.Len(t, transactions, succeeded)
+
+	suite.assertLedgerBalanced()
+}
+
+func (suite *StoreConformanceSuite) TestReverseTransfer() {
+	t := suite.T()
+	a, b := suite.createWallet(100), suite.createWallet(0)
+	original := suite.transfer(a, b, 40)
+
+	reversal, err := suite.transactions.ReverseTransfer(suite.ctx, original.Id, "refund", func(o *models.Transaction) error {
+		assert.Equal(t, original.Id, o.Id)
+		return nil
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, b, reversal.From)
+	assert.Equal(t, a, reversal.To)
+	assert.Equal(t, models.AmountFromUnits(40), reversal.Amount)
+	assert.Equal(t, &original.Id, reversal.ReversalOf)
+	assert.Equal(t, "refund", reversal.Reason)
+	assert.Equal(t, models.AmountFromUnits(100), *reversal.ToBalance)
+
+	stored, err := suite.transactions.GetTransactionById(suite.ctx, original.Id)
+	require.NoError(t, err)
+	assert.Equal(t, &reversal.Id, stored.ReversedBy)
+
+	// Второе сторнирование невозможно даже без проверки исходной транзакции
+	_, err = suite.transactions.ReverseTransfer(suite.ctx, original.Id, "again", nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, models.AmountFromUnits(100), suite.balance(a))
+
+	refuse := errors.New("refused")
+	other := suite.transfer(a, b, 10)
+	_, err = suite.transactions.ReverseTransfer(suite.ctx, other.Id, "refund", func(*models.Transaction) error { return refuse }, nil)
+	assert.ErrorIs(t, err, refuse)
+	_, err = suite.transactions.ReverseTransfer(suite.ctx, other.Id+1000, "refund", nil, nil)
+	assertNotFound(t, err, "transaction")
+
+	suite.assertLedgerBalanced()
+}
+
+func (suite *StoreConformanceSuite) TestListTransactions_OrderAndFilters() {
+	t := suite.T()
+	owner := int64(42)
+	a, b := suite.createWallet(1000), suite.createWallet(1000)
+	owned := uuid.New().String()
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, owned, models.DefaultCurrency, models.AmountFromUnits(0), &owner))
+
+	// Паузы разводят время создания, чтобы проверить фильтр по интервалу
+	var created []*models.Transaction
+	for i := 1; i <= 6; i++ {
+		created = append(created, suite.transfer(a, b, int64(i)))
+		time.Sleep(time.Millisecond)
+	}
+	created = append(created, suite.transfer(b, owned, 7))
+
+	all, err := suite.transactions.ListTransactions(suite.ctx, models.TransactionFilter{WalletRole: models.WalletRoleAny, Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, all, len(created))
+	for i := range all {
+		assert.Equal(t, created[len(created)-1-i].Id, all[i].Id)
+	}
+
+	last, err := suite.transactions.GetLastTransactions(suite.ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, all[:3], last)
+
+	// Постраничный вывод по курсору проходит весь список без пропусков и повторов
+	var paged []models.Transaction
+	filter := models.TransactionFilter{WalletRole: models.WalletRoleAny, Limit: 3}
+	for {
+		page, err := suite.transactions.ListTransactions(suite.ctx, filter)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		tail := page[len(page)-1]
+		filter.After = &models.TransactionCursor{CreatedAt: tail.CreatedAt, Id: tail.Id}
+	}
+	assert.Equal(t, all, paged)
+
+	count := func(filter models.TransactionFilter) int {
+		filter.Limit = 100
+		transactions, err := suite.transactions.ListTransactions(suite.ctx, filter)
+		require.NoError(t, err)
+		return len(transactions)
+	}
+	min, max := models.AmountFromUnits(3), models.AmountFromUnits(5)
+	since, until := created[1].CreatedAt, created[3].CreatedAt
+	assert.Equal(t, 6, count(models.TransactionFilter{Wallet: a, WalletRole: models.WalletRoleAny}))
+	assert.Equal(t, 1, count(models.TransactionFilter{Wallet: b, WalletRole: models.WalletRoleSender}))
+	assert.Equal(t, 0, count(models.TransactionFilter{Wallet: a, WalletRole: models.WalletRoleReceiver}))
+	assert.Equal(t, 1, count(models.TransactionFilter{Owner: &owner}))
+	assert.Equal(t, 3, count(models.TransactionFilter{MinAmount: &min, MaxAmount: &max}))
+	assert.Equal(t, 2, count(models.TransactionFilter{Since: &since, Until: &until}))
+
+	empty, err := suite.transactions.ListTransactions(suite.ctx, models.TransactionFilter{Wallet: "missing", Limit: 10})
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+}
+
+func (suite *StoreConformanceSuite) TestGetWalletHistory() {
+	t := suite.T()
+	a, b := suite.createWallet(100), suite.createWallet(0)
+	first := suite.transfer(a, b, 30)
+	suite.transfer(b, a, 10)
+	suite.transfer(a, b, 5)
+
+	history, err := suite.transactions.GetWalletHistory(suite.ctx, a, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.DirectionDebit, history[0].Direction)
+	assert.Equal(t, b, history[0].Counterparty)
+	assert.Equal(t, models.AmountFromUnits(75), history[0].BalanceAfter)
+	assert.Equal(t, models.DirectionCredit, history[1].Direction)
+	assert.Equal(t, models.AmountFromUnits(80), history[1].BalanceAfter)
+	assert.Equal(t, models.AmountFromUnits(70), history[2].BalanceAfter)
+	assert.Equal(t, first.Id, history[2].TransactionId)
+
+	// Баланс после транзакции не зависит от окна выборки
+	after := &models.TransactionCursor{CreatedAt: history[0].CreatedAt, Id: history[0].TransactionId}
+	page, err := suite.transactions.GetWalletHistory(suite.ctx, a, models.WalletHistoryFilter{After: after, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, history[1], page[0])
+
+	missing, err := suite.transactions.GetWalletHistory(suite.ctx, "missing", models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func (suite *StoreConformanceSuite) TestGetWalletHistory_AdjustmentsAndRemovals() {
+	t := suite.T()
+	a, b := suite.createWallet(100), suite.createWallet(0)
+	first := suite.transfer(a, b, 30)
+	removed := suite.transfer(a, b, 20)
+
+	// Корректировка и удаление транзакции не меняют баланс после уже проведённых операций
+	require.NoError(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, a, models.AmountFromUnits(500)))
+	require.NoError(t, suite.transactions.RemoveTransaction(suite.ctx, removed.Id))
+	last := suite.transfer(a, b, 5)
+
+	history, err := suite.transactions.GetWalletHistory(suite.ctx, a, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, last.Id, history[0].TransactionId)
+	assert.Equal(t, models.AmountFromUnits(495), history[0].BalanceAfter)
+	assert.Equal(t, first.Id, history[1].TransactionId)
+	assert.Equal(t, models.AmountFromUnits(70), history[1].BalanceAfter)
+
+	history, err = suite.transactions.GetWalletHistory(suite.ctx, b, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.AmountFromUnits(55), history[0].BalanceAfter)
+	assert.Equal(t, models.AmountFromUnits(30), history[1].BalanceAfter)
+}
+
+func (suite *StoreConformanceSuite) TestRemoveTransaction() {
+	t := suite.T()
+	a, b := suite.createWallet(100), suite.createWallet(0)
+	original := suite.transfer(a, b, 10)
+	reversal, err := suite.transactions.ReverseTransfer(suite.ctx, original.Id, "refund", nil, nil)
+	require.NoError(t, err)
+
+	// Кошелёк, на который ссылаются транзакции, удалить нельзя
+	assert.Error(t, suite.wallets.RemoveWallet(suite.ctx, a, nil))
+	_, err = suite.wallets.GetWallet(suite.ctx, a)
+	assert.NoError(t, err)
+
+	require.NoError(t, suite.transactions.RemoveTransaction(suite.ctx, reversal.Id))
+	_, err = suite.transactions.GetTransactionById(suite.ctx, reversal.Id)
+	assertNotFound(t, err, "transaction")
+	assertNotFound(t, suite.transactions.RemoveTransaction(suite.ctx, reversal.Id), "transaction")
+
+	// Ссылка на удалённую транзакцию обнуляется, балансы не меняются
+	stored, err := suite.transactions.GetTransactionById(suite.ctx, original.Id)
+	require.NoError(t, err)
+	assert.Nil(t, stored.ReversedBy)
+	assert.Equal(t, models.AmountFromUnits(100), suite.balance(a))
+
+	require.NoError(t, suite.transactions.RemoveTransaction(suite.ctx, original.Id))
+	require.NoError(t, suite.wallets.RemoveWallet(suite.ctx, b, nil))
+}
+
+func (suite *StoreConformanceSuite) TestConversion() {
+	t := suite.T()
+	from, to := uuid.New().String(), uuid.New().String()
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, from, "USD", models.AmountFromUnits(100), nil))
+	require.NoError(t, suite.wallets.CreateWallet(suite.ctx, to, "EUR", models.AmountFromUnits(0), nil))
+
+	rate := models.MustParseRate("0.9234")
+	amount := models.MustParseAmount("10.01")
+	converted, residue, err := models.Convert(amount, rate, 2)
+	require.NoError(t, err)
+	conversion := models.Conversion{Rate: rate, ToAmount: converted, ToCurrency: "EUR", Residue: residue, QuoteId: "quote"}
+
+	before, err := suite.ledger.Verify(suite.ctx)
+	require.NoError(t, err)
+
+	transaction, err := suite.transactions.ExecuteConversion(suite.ctx, from, to, amount, conversion, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.Currency("USD"), transaction.Currency)
+	require.NotNil(t, transaction.Conversion)
+	assert.Equal(t, conversion, *transaction.Conversion)
+
+	assert.Equal(t, models.MustParseAmount("89.99"), suite.balance(from))
+	assert.Equal(t, models.MustParseAmount("9.24"), suite.balance(to))
+	suite.assertLedgerBalanced()
+
+	// Каждая валюта сходится отдельно: счёт @fx получил доллары отправителя
+	// и выдал евро получателю, а не одну разность в смешанных единицах
+	after, err := suite.ledger.Verify(suite.ctx)
+	require.NoError(t, err)
+	for _, currency := range []models.Currency{"USD", "EUR"} {
+		assert.True(t, after.Totals[currency].IsZero(), "%s total %s", currency, after.Totals[currency])
+	}
+	fxDelta := func(currency models.Currency) models.Amount {
+		return after.SystemBalances[currency][models.FXAccount].Sub(before.SystemBalances[currency][models.FXAccount])
+	}
+	assert.Equal(t, amount, fxDelta("USD"))
+	assert.Equal(t, converted.Neg(), fxDelta("EUR"))
+
+	stored, err := suite.transactions.GetTransactionById(suite.ctx, transaction.Id)
+	require.NoError(t, err)
+	require.NotNil(t, stored.Conversion)
+	assert.Equal(t, conversion, *stored.Conversion)
+
+	// Получатель видит зачисление в своей валюте
+	history, err := suite.transactions.GetWalletHistory(suite.ctx, to, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.DirectionCredit, history[0].Direction)
+	assert.Equal(t, models.Currency("EUR"), history[0].Currency)
+	assert.Equal(t, models.MustParseAmount("9.24"), history[0].Amount)
+	assert.Equal(t, models.MustParseAmount("9.24"), history[0].BalanceAfter)
+
+	history, err = suite.transactions.GetWalletHistory(suite.ctx, from, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.Currency("USD"), history[0].Currency)
+	assert.Equal(t, amount, history[0].Amount)
+
+	// Перевод без конвертации не получает сведений о ней
+	plain := suite.transfer(from, suite.createWallet(0), 1)
+	stored, err = suite.transactions.GetTransactionById(suite.ctx, plain.Id)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Conversion)
+}
+
+func (suite *StoreConformanceSuite) wallet(address string) *models.Wallet {
+	wallet, err := suite.wallets.GetWallet(suite.ctx, address)
+	require.NoError(suite.T(), err)
+	return wallet
+}
+
+func (suite *StoreConformanceSuite) TestHolds() {
+	t := suite.T()
+	from, to := suite.createWallet(100), suite.createWallet(0)
+	expiresAt := time.Now().Add(time.Hour)
+
+	hold, err := suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(30), expiresAt, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldActive, hold.Status)
+	assert.Equal(t, models.DefaultCurrency, hold.Currency)
+	assert.WithinDuration(t, expiresAt, hold.ExpiresAt, time.Millisecond)
+	assert.Nil(t, hold.ClosedAt)
+
+	// Холд уменьшает доступный остаток, но не баланс
+	wallet := suite.wallet(from)
+	assert.Equal(t, models.AmountFromUnits(100), wallet.Balance)
+	assert.Equal(t, models.AmountFromUnits(30), wallet.Held)
+	assert.Equal(t, models.AmountFromUnits(70), wallet.Available())
+
+	stored, err := suite.holds.GetHold(suite.ctx, hold.Id)
+	require.NoError(t, err)
+	assert.Equal(t, hold.Amount, stored.Amount)
+	assert.Equal(t, hold.From, stored.From)
+
+	// Частичное списание переводит сумму получателю и освобождает весь холд
+	captured, transaction, err := suite.holds.CaptureHold(suite.ctx, hold.Id, models.AmountFromUnits(20), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldCaptured, captured.Status)
+	require.NotNil(t, captured.CapturedAmount)
+	assert.Equal(t, models.AmountFromUnits(20), *captured.CapturedAmount)
+	require.NotNil(t, captured.TransactionId)
+	assert.Equal(t, transaction.Id, *captured.TransactionId)
+	assert.NotNil(t, captured.ClosedAt)
+	assert.Equal(t, models.AmountFromUnits(20), transaction.Amount)
+
+	wallet = suite.wallet(from)
+	assert.Equal(t, models.AmountFromUnits(80), wallet.Balance)
+	assert.True(t, wallet.Held.IsZero())
+	assert.Equal(t, models.AmountFromUnits(20), suite.balance(to))
+	suite.assertLedgerBalanced()
+
+	history, err := suite.transactions.GetWalletHistory(suite.ctx, to, models.WalletHistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, transaction.Id, history[0].TransactionId)
+
+	// Закрытый холд нельзя закрыть повторно
+	_, _, err = suite.holds.CaptureHold(suite.ctx, hold.Id, models.AmountFromUnits(10), nil, nil)
+	assert.Error(t, err)
+	_, err = suite.holds.ReleaseHold(suite.ctx, hold.Id, models.HoldVoided, nil)
+	assert.Error(t, err)
+	assert.Equal(t, models.AmountFromUnits(80), suite.balance(from))
+
+	// Снятие холда возвращает средства в доступный остаток
+	voided, err := suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(50), expiresAt, nil)
+	require.NoError(t, err)
+	voided, err = suite.holds.ReleaseHold(suite.ctx, voided.Id, models.HoldVoided, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldVoided, voided.Status)
+	assert.Nil(t, voided.TransactionId)
+	wallet = suite.wallet(from)
+	assert.Equal(t, models.AmountFromUnits(80), wallet.Balance)
+	assert.True(t, wallet.Held.IsZero())
+
+	_, err = suite.holds.GetHold(suite.ctx, 1<<40)
+	assertNotFound(t, err, "hold")
+	_, err = suite.holds.CreateHold(suite.ctx, from, uuid.New().String(), models.AmountFromUnits(1), expiresAt, nil)
+	assertNotFound(t, err, "wallet")
+}
+
+func (suite *StoreConformanceSuite) TestHolds_BalanceConstraint() {
+	t := suite.T()
+	from, to := suite.createWallet(100), suite.createWallet(0)
+	_, err := suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(80), time.Now().Add(time.Hour), nil)
+	require.NoError(t, err)
+
+	// Без проверок сервиса хранилище само не даёт потратить или захолдировать зарезервированные средства
+	_, err = suite.transactions.ExecuteTransfer(suite.ctx, from, to, models.AmountFromUnits(30), nil)
+	assert.Error(t, err)
+	_, err = suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(30), time.Now().Add(time.Hour), nil)
+	assert.Error(t, err)
+	assert.Error(t, suite.wallets.UpdateWalletBalabnce(suite.ctx, from, models.AmountFromUnits(50)))
+
+	wallet := suite.wallet(from)
+	assert.Equal(t, models.AmountFromUnits(100), wallet.Balance)
+	assert.Equal(t, models.AmountFromUnits(80), wallet.Held)
+	suite.transfer(from, to, 20)
+}
+
+func (suite *StoreConformanceSuite) TestListExpiredHolds() {
+	t := suite.T()
+	from, to := suite.createWallet(100), suite.createWallet(0)
+	now := time.Now()
+
+	var ids []int64
+	for _, offset := range []time.Duration{-time.Minute, -time.Hour, time.Hour} {
+		hold, err := suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(10), now.Add(offset), nil)
+		require.NoError(t, err)
+		ids = append(ids, hold.Id)
+	}
+	_, err := suite.holds.ReleaseHold(suite.ctx, ids[1], models.HoldVoided, nil)
+	require.NoError(t, err)
+	expired, err := suite.holds.CreateHold(suite.ctx, from, to, models.AmountFromUnits(10), now.Add(-2*time.Hour), nil)
+	require.NoError(t, err)
+
+	// Только активные холды с истёкшим сроком, раньше истёкшие первыми
+	got, err := suite.holds.ListExpiredHolds(suite.ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{expired.Id, ids[0]}, got)
+
+	got, err = suite.holds.ListExpiredHolds(suite.ctx, now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{expired.Id}, got)
+
+	hold, err := suite.holds.ReleaseHold(suite.ctx, expired.Id, models.HoldExpired, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldExpired, hold.Status)
+	assert.Equal(t, models.AmountFromUnits(20), suite.wallet(from).Held)
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TransactionSystem/api"
+	"TransactionSystem/config"
+	"TransactionSystem/internal/service"
+	"TransactionSystem/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans подменяет глобальный TracerProvider провайдером, запоминающим спаны, до конца теста
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func findSpan(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, s := range spans {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracing_RouteAndServiceSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	healthService := service.NewHealthService(&fakeDatabase{}, &fakeMigrations{current: 1, latest: 1}, "dev")
+	router := api.NewRouter(service.NewTransactionService(nil, nil), nil, nil, healthService, nil, api.Options{})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	req := httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(`{"from":"wallet-a","to":"wallet-a","amount":5}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	req.Header.Set("X-Request-ID", "req-trace")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Пробы не трассируются
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	route := findSpan(spans, "/api/send")
+	require.NotNil(t, route)
+	assert.Equal(t, traceID, route.SpanContext().TraceID().String())
+	assert.Equal(t, parentID, route.Parent().SpanID().String())
+	assert.True(t, route.Parent().IsRemote())
+	assert.Equal(t, "req-trace", spanAttribute(route, "request.id"))
+
+	send := findSpan(spans, "TransactionService.SendMoney")
+	require.NotNil(t, send)
+	assert.Equal(t, route.SpanContext().SpanID(), send.Parent().SpanID())
+	assert.Equal(t, codes.Error, send.Status().Code)
+	assert.Equal(t, "wallet-a", spanAttribute(send, "wallet.from"))
+	assert.Equal(t, "5.00", spanAttribute(send, "amount"))
+}
+
+func TestTracing_StdoutExporter(t *testing.T) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	cfg := config.Default().Tracing
+	cfg.Exporter = tracing.ExporterStdout
+
+	var out bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), cfg, "1.2.3", &out)
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "test", "stdout-span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"stdout-span"`)
+	assert.Contains(t, out.String(), "transaction-system")
+	assert.Contains(t, out.String(), "1.2.3")
+
+	cfg.Exporter = "zipkin"
+	_, err = tracing.Setup(context.Background(), cfg, "dev", &out)
+	assert.Error(t, err)
+}